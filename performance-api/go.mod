@@ -1,10 +1,13 @@
 module performance-api
 
-go 1.21
+go 1.26.0
 
 require (
+	github.com/google/pprof v0.0.0-20230207041349-798e818bf904
 	github.com/gorilla/mux v1.8.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/shirou/gopsutil/v3 v3.23.11
+	golang.org/x/exp v0.0.0-20260908205506-85c1c2202aba
 )
 
 require (
@@ -0,0 +1,334 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"performance-api/internal/metrics"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Estados de una regla de alerta
+const (
+	StateInactive = "inactive" // La condición no se cumple
+	StatePending  = "pending"  // La condición se cumple pero aún no durante el tiempo de for
+	StateFiring   = "firing"   // La condición se cumple desde hace al menos el tiempo de for
+	StateResolved = "resolved" // Una alerta disparada dejó de cumplirse (solo en el historial y las notificaciones)
+)
+
+// defaultHistorySize es la cantidad de transiciones conservadas por defecto
+const defaultHistorySize = 1000
+
+// Alert es una alerta activa (pendiente o disparada)
+type Alert struct {
+	Rule        string     `json:"rule"`
+	Expr        string     `json:"expr"`
+	Severity    string     `json:"severity,omitempty"`
+	Description string     `json:"description,omitempty"`
+	State       string     `json:"state"`
+	Value       float64    `json:"value"`        // Valor en la última evaluación
+	ActiveSince time.Time  `json:"active_since"` // Primera muestra en que se cumplió la condición
+	FiredAt     *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"` // Solo en las notificaciones de alertas resueltas
+	UpdatedAt   time.Time  `json:"updated_at"`            // Última evaluación
+}
+
+// Transition es un cambio de estado de una regla
+type Transition struct {
+	Rule      string    `json:"rule"`
+	Severity  string    `json:"severity,omitempty"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Value     float64   `json:"value"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RuleStatus describe una regla con su estado y su último valor
+type RuleStatus struct {
+	*Rule
+	State     string     `json:"state"`
+	Value     *float64   `json:"value,omitempty"` // Nulo si aún no se evaluó
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Options configura el motor de alertas
+type Options struct {
+	Rules       []RuleConfig
+	HistorySize int           // Transiciones conservadas (0 = 1000)
+	Notify      NotifyOptions // Destinos de las notificaciones (sin destinos = no se notifica)
+}
+
+// Engine evalúa las reglas de alerta con cada muestra del recolector
+type Engine struct {
+	collector   *metrics.Collector
+	rules       []*Rule
+	window      time.Duration // Ventana más larga de las reglas
+	historySize int
+
+	mu      sync.RWMutex
+	samples []metrics.SystemMetrics // Muestras dentro de la ventana más larga
+	alerts  map[string]*Alert       // Alertas activas por regla
+	values  map[string]float64      // Último valor por regla
+	updated time.Time               // Momento de la última evaluación
+	history []Transition
+
+	notifier *dispatcher // Nulo si no hay destinos configurados
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewEngine crea el motor de alertas con las reglas indicadas
+func NewEngine(c *metrics.Collector, opts Options) (*Engine, error) {
+	if opts.HistorySize <= 0 {
+		opts.HistorySize = defaultHistorySize
+	}
+
+	e := &Engine{
+		collector:   c,
+		historySize: opts.HistorySize,
+		alerts:      make(map[string]*Alert),
+		values:      make(map[string]float64),
+	}
+	names := make(map[string]bool)
+	for _, cfg := range opts.Rules {
+		rule, err := ParseRule(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("regla de alerta duplicada %q", rule.Name)
+		}
+		names[rule.Name] = true
+		e.rules = append(e.rules, rule)
+		e.window = max(e.window, rule.window)
+	}
+	if len(opts.Notify.Sinks) > 0 {
+		notifier, err := newDispatcher(opts.Notify)
+		if err != nil {
+			return nil, err
+		}
+		e.notifier = notifier
+	}
+
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	if e.notifier != nil {
+		// Se inicia aquí para que Stop pueda esperarlo aunque Start no haya corrido
+		go e.notifier.run(e.ctx)
+	}
+	return e, nil
+}
+
+// Start evalúa las reglas con cada muestra del recolector hasta que se
+// llame a Stop. Las ventanas se completan con el historial existente.
+func (e *Engine) Start() {
+	if len(e.rules) == 0 {
+		return
+	}
+
+	// Suscribirse antes de leer el historial para no perder muestras intermedias
+	samples, unsubscribe := e.collector.Subscribe(4)
+	defer unsubscribe()
+
+	var last time.Time
+	if e.window > 0 {
+		backfill := e.collector.GetMetricsRange(time.Now().Add(-e.window), time.Time{})
+		e.mu.Lock()
+		e.samples = backfill
+		e.mu.Unlock()
+		if n := len(backfill); n > 0 {
+			last = backfill[n-1].Timestamp
+		}
+	}
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case m, ok := <-samples:
+			if !ok {
+				return
+			}
+			if !m.Timestamp.After(last) {
+				// Ya incluida en el historial leído al iniciar
+				continue
+			}
+			e.evaluate(m)
+		}
+	}
+}
+
+// Stop detiene la evaluación de las reglas y espera el envío de las
+// notificaciones pendientes
+func (e *Engine) Stop() {
+	e.cancel()
+	if e.notifier != nil {
+		<-e.notifier.done
+	}
+}
+
+// evaluate agrega la muestra a la ventana y actualiza el estado de cada regla
+func (e *Engine) evaluate(m metrics.SystemMetrics) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.samples = append(e.samples, m)
+	start := m.Timestamp.Add(-e.window)
+	drop := 0
+	for drop < len(e.samples)-1 && !e.samples[drop].Timestamp.After(start) {
+		drop++
+	}
+	e.samples = e.samples[drop:]
+	e.updated = m.Timestamp
+
+	for _, rule := range e.rules {
+		value, met := rule.evaluate(e.samples)
+		e.values[rule.Name] = value
+		alert, active := e.alerts[rule.Name]
+
+		switch {
+		case met && !active:
+			alert = &Alert{
+				Rule:        rule.Name,
+				Expr:        rule.Expr,
+				Severity:    rule.Severity,
+				Description: rule.Description,
+				State:       StatePending,
+				ActiveSince: m.Timestamp,
+			}
+			e.alerts[rule.Name] = alert
+			if rule.hold > 0 {
+				e.transition(rule, StateInactive, StatePending, value, m.Timestamp)
+			} else {
+				e.fire(rule, alert, StateInactive, value, m.Timestamp)
+			}
+		case met && alert.State == StatePending && m.Timestamp.Sub(alert.ActiveSince) >= rule.hold:
+			e.fire(rule, alert, StatePending, value, m.Timestamp)
+		case !met && active:
+			delete(e.alerts, rule.Name)
+			if alert.State == StateFiring {
+				e.transition(rule, StateFiring, StateResolved, value, m.Timestamp)
+				log.Printf("✅ Alerta %s resuelta: %s = %g", rule.Name, rule.Field, value)
+				resolved := *alert
+				resolvedAt := m.Timestamp
+				resolved.State = StateResolved
+				resolved.Value = value
+				resolved.UpdatedAt = m.Timestamp
+				resolved.ResolvedAt = &resolvedAt
+				e.notify(resolved)
+			} else {
+				e.transition(rule, StatePending, StateInactive, value, m.Timestamp)
+			}
+		}
+		if alert != nil {
+			alert.Value = value
+			alert.UpdatedAt = m.Timestamp
+		}
+	}
+}
+
+// fire pasa una alerta al estado disparado
+func (e *Engine) fire(rule *Rule, alert *Alert, from string, value float64, at time.Time) {
+	alert.State = StateFiring
+	firedAt := at
+	alert.FiredAt = &firedAt
+	alert.Value = value
+	alert.UpdatedAt = at
+	e.transition(rule, from, StateFiring, value, at)
+	log.Printf("🚨 Alerta %s disparada: %s (valor %g)", rule.Name, rule.Expr, value)
+	e.notify(*alert)
+}
+
+// notify envía una copia de la alerta a los destinos configurados
+func (e *Engine) notify(alert Alert) {
+	if e.notifier != nil {
+		e.notifier.enqueue(alert)
+	}
+}
+
+// transition registra un cambio de estado en el historial
+func (e *Engine) transition(rule *Rule, from, to string, value float64, at time.Time) {
+	e.history = append(e.history, Transition{
+		Rule:      rule.Name,
+		Severity:  rule.Severity,
+		From:      from,
+		To:        to,
+		Value:     value,
+		Timestamp: at,
+	})
+	if len(e.history) > e.historySize {
+		e.history = e.history[len(e.history)-e.historySize:]
+	}
+}
+
+// Alerts retorna las alertas activas, primero las disparadas y luego por nombre
+func (e *Engine) Alerts() []Alert {
+	e.mu.RLock()
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		alerts = append(alerts, *alert)
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].State != alerts[j].State {
+			return alerts[i].State == StateFiring
+		}
+		return alerts[i].Rule < alerts[j].Rule
+	})
+	return alerts
+}
+
+// Rules retorna las reglas configuradas con su estado actual
+func (e *Engine) Rules() []RuleStatus {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rules := make([]RuleStatus, 0, len(e.rules))
+	for _, rule := range e.rules {
+		status := RuleStatus{Rule: rule, State: StateInactive}
+		if alert, ok := e.alerts[rule.Name]; ok {
+			status.State = alert.State
+		}
+		if value, ok := e.values[rule.Name]; ok {
+			updated := e.updated
+			status.Value = &value
+			status.UpdatedAt = &updated
+		}
+		rules = append(rules, status)
+	}
+	return rules
+}
+
+// History retorna las transiciones de estado dentro de [from, to] (cero =
+// sin límite), opcionalmente de una sola regla, de la más antigua a la más
+// reciente y conservando las limit más recientes (0 = sin límite)
+func (e *Engine) History(rule string, from, to time.Time, limit int) []Transition {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	history := make([]Transition, 0)
+	for _, t := range e.history {
+		if rule != "" && t.Rule != rule {
+			continue
+		}
+		if (!from.IsZero() && t.Timestamp.Before(from)) || (!to.IsZero() && t.Timestamp.After(to)) {
+			continue
+		}
+		history = append(history, t)
+	}
+	if limit > 0 && len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return history
+}
+
+// Sinks retorna el estado de entrega de los destinos de notificaciones
+func (e *Engine) Sinks() []SinkStatus {
+	if e.notifier == nil {
+		return []SinkStatus{}
+	}
+	return e.notifier.statuses()
+}
@@ -0,0 +1,31 @@
+package alerts
+
+import (
+	"path/filepath"
+	"performance-api/internal/metrics"
+	"testing"
+	"time"
+)
+
+func TestEngineStopBeforeStart(t *testing.T) {
+	engine, err := NewEngine(metrics.NewCollector(), Options{
+		Rules: []RuleConfig{{Name: "cpu", Expr: "cpu.percent > 90"}},
+		Notify: NotifyOptions{
+			Sinks: []SinkConfig{{Name: "archivo", Type: "file", Path: filepath.Join(t.TempDir(), "alerts.jsonl")}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		engine.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(flushTimeout + time.Second):
+		t.Fatal("Stop no terminó sin haber llamado a Start")
+	}
+}
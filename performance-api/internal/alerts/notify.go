@@ -0,0 +1,383 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NotifyOptions configura la entrega de notificaciones de alertas
+type NotifyOptions struct {
+	Sinks          []SinkConfig
+	GroupWait      time.Duration // Espera para reunir alertas en una sola notificación (0 = enviar de inmediato)
+	GroupBy        []string      // Campos que separan los grupos: rule y/o severity (vacío = un solo grupo)
+	MaxRetries     int           // Reintentos tras el primer intento fallido
+	InitialBackoff time.Duration // Espera antes del primer reintento; se duplica en cada uno
+	MaxBackoff     time.Duration // Espera máxima entre reintentos
+	Timeout        time.Duration // Tiempo máximo de cada intento
+	ResendInterval time.Duration // Reenvío de las alertas disparadas a Alertmanager (0 = 1m)
+}
+
+// SinkStatus describe el estado de entrega de un destino
+type SinkStatus struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Sent        int        `json:"sent"`    // Notificaciones entregadas
+	Failed      int        `json:"failed"`  // Notificaciones descartadas tras agotar los reintentos
+	Retries     int        `json:"retries"` // Reintentos realizados
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// dedupTTL es el tiempo que se recuerda una alerta entregada para no repetirla
+const dedupTTL = 24 * time.Hour
+
+// alertmanagerLeaseFactor es cuántos intervalos de reenvío se adelanta el
+// endsAt de una alerta disparada: si la API deja de reenviarla, Alertmanager
+// la da por resuelta tras ese plazo
+const alertmanagerLeaseFactor = 4
+
+// flushTimeout limita el envío de los grupos pendientes al detener el motor
+const flushTimeout = 5 * time.Second
+
+// notifySink es un destino con su estado de entrega
+type notifySink struct {
+	sink
+	mu        sync.Mutex
+	status    SinkStatus
+	delivered map[string]time.Time // Claves de alertas ya entregadas
+}
+
+// group reúne las alertas de un mismo grupo hasta su envío
+type group struct {
+	labels map[string]string
+	alerts map[string]Alert // Último estado por regla
+	flush  time.Time
+}
+
+// dispatcher agrupa las alertas notificadas por el motor y las entrega a
+// cada destino con reintentos y deduplicación
+type dispatcher struct {
+	opts   NotifyOptions
+	sinks  []*notifySink
+	events chan Alert
+	firing map[string]Alert // Alertas disparadas por regla, solo usado por run
+	wg     sync.WaitGroup   // Entregas en curso
+	done   chan struct{}    // Se cierra cuando run termina
+}
+
+// newDispatcher crea el despachador con los destinos configurados
+func newDispatcher(opts NotifyOptions) (*dispatcher, error) {
+	for _, field := range opts.GroupBy {
+		if field != "rule" && field != "severity" {
+			return nil, fmt.Errorf("campo de agrupación desconocido %q (usa rule o severity)", field)
+		}
+	}
+	if opts.GroupWait < 0 || opts.MaxRetries < 0 || opts.InitialBackoff < 0 || opts.MaxBackoff < 0 || opts.ResendInterval < 0 {
+		return nil, fmt.Errorf("la espera de agrupación, los reintentos, el backoff y el reenvío no pueden ser negativos")
+	}
+	if opts.ResendInterval == 0 {
+		opts.ResendInterval = time.Minute
+	}
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = time.Second
+	}
+	if opts.MaxBackoff < opts.InitialBackoff {
+		opts.MaxBackoff = max(opts.InitialBackoff, time.Minute)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+
+	d := &dispatcher{opts: opts, events: make(chan Alert, 256), firing: make(map[string]Alert), done: make(chan struct{})}
+	names := make(map[string]bool)
+	for _, cfg := range opts.Sinks {
+		if cfg.Name == "" || names[cfg.Name] {
+			return nil, fmt.Errorf("cada destino de notificaciones necesita un nombre único (%q)", cfg.Name)
+		}
+		names[cfg.Name] = true
+		s, err := newSink(cfg, opts.Timeout, opts.ResendInterval*alertmanagerLeaseFactor)
+		if err != nil {
+			return nil, err
+		}
+		d.sinks = append(d.sinks, &notifySink{
+			sink:      s,
+			status:    SinkStatus{Name: cfg.Name, Type: cfg.Type},
+			delivered: make(map[string]time.Time),
+		})
+	}
+	return d, nil
+}
+
+// enqueue recibe una alerta disparada o resuelta sin bloquear al motor
+func (d *dispatcher) enqueue(a Alert) {
+	select {
+	case d.events <- a:
+	default:
+		log.Printf("Cola de notificaciones llena: se descartó la alerta %s (%s)", a.Rule, a.State)
+	}
+}
+
+// run agrupa las alertas y envía cada grupo al cumplirse su espera. Al
+// cancelarse ctx envía los grupos pendientes y espera las entregas en
+// curso, que pueden seguir reintentando hasta flushTimeout.
+func (d *dispatcher) run(ctx context.Context) {
+	defer close(d.done)
+	groups := make(map[string]*group)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	// Alertmanager espera que los clientes reenvíen las alertas activas
+	var resend <-chan time.Time
+	if len(d.resendSinks()) > 0 {
+		ticker := time.NewTicker(d.opts.ResendInterval)
+		defer ticker.Stop()
+		resend = ticker.C
+	}
+
+	// Las entregas no usan ctx para que detener el motor no cancele los
+	// reintentos en curso; se cancelan al vencer flushTimeout
+	sendCtx, cancelSends := context.WithCancel(context.Background())
+	defer cancelSends()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(sendCtx, flushTimeout)
+			defer cancel()
+			stop := context.AfterFunc(flushCtx, cancelSends)
+			defer stop()
+		drain:
+			for {
+				select {
+				case a := <-d.events:
+					d.collect(groups, a)
+				default:
+					break drain
+				}
+			}
+			for _, g := range groups {
+				d.send(flushCtx, g)
+			}
+			d.wg.Wait()
+			return
+		case a := <-d.events:
+			d.collect(groups, a)
+		case <-timer.C:
+		case <-resend:
+			d.resendFiring(sendCtx)
+		}
+
+		// Enviar los grupos cuya espera terminó y programar el siguiente
+		now := time.Now()
+		var next time.Time
+		for key, g := range groups {
+			if !g.flush.After(now) {
+				d.send(sendCtx, g)
+				delete(groups, key)
+			} else if next.IsZero() || g.flush.Before(next) {
+				next = g.flush
+			}
+		}
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+		}
+	}
+}
+
+// collect agrega la alerta a su grupo, reemplazando el estado anterior de
+// la misma regla
+func (d *dispatcher) collect(groups map[string]*group, a Alert) {
+	key, labels := d.groupKey(a)
+	g, ok := groups[key]
+	if !ok {
+		g = &group{labels: labels, alerts: make(map[string]Alert), flush: time.Now().Add(d.opts.GroupWait)}
+		groups[key] = g
+	}
+	g.alerts[a.Rule] = a
+
+	if a.State == StateFiring {
+		d.firing[a.Rule] = a
+	} else {
+		delete(d.firing, a.Rule)
+	}
+}
+
+// resendSinks retorna los destinos que necesitan el reenvío periódico de
+// las alertas disparadas
+func (d *dispatcher) resendSinks() []*notifySink {
+	var sinks []*notifySink
+	for _, s := range d.sinks {
+		if s.status.Type == SinkAlertmanager {
+			sinks = append(sinks, s)
+		}
+	}
+	return sinks
+}
+
+// resendFiring reenvía las alertas disparadas a Alertmanager sin pasar por
+// la deduplicación, para renovar su endsAt. Se hace un solo intento: si
+// falla, el próximo reenvío lo repite antes de que venza el plazo.
+func (d *dispatcher) resendFiring(ctx context.Context) {
+	if len(d.firing) == 0 {
+		return
+	}
+	alerts := make([]Alert, 0, len(d.firing))
+	for _, a := range d.firing {
+		alerts = append(alerts, a)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Rule < alerts[j].Rule })
+	n := Notification{Status: StateFiring, Alerts: alerts, Timestamp: time.Now()}
+
+	for _, s := range d.resendSinks() {
+		d.wg.Add(1)
+		go func(s *notifySink) {
+			defer d.wg.Done()
+			if err := s.send(ctx, n); err != nil {
+				s.markFailed(err)
+				log.Printf("Error al reenviar las alertas disparadas a %s: %v", s.status.Name, err)
+				return
+			}
+			s.markDelivered(alerts)
+		}(s)
+	}
+}
+
+// groupKey calcula la clave y las etiquetas del grupo de una alerta
+func (d *dispatcher) groupKey(a Alert) (string, map[string]string) {
+	if len(d.opts.GroupBy) == 0 {
+		return "", nil
+	}
+	labels := make(map[string]string, len(d.opts.GroupBy))
+	parts := make([]string, 0, len(d.opts.GroupBy))
+	for _, field := range d.opts.GroupBy {
+		value := a.Rule
+		if field == "severity" {
+			value = a.Severity
+		}
+		labels[field] = value
+		parts = append(parts, field+"="+value)
+	}
+	return strings.Join(parts, ","), labels
+}
+
+// send entrega un grupo a cada destino en paralelo
+func (d *dispatcher) send(ctx context.Context, g *group) {
+	alerts := make([]Alert, 0, len(g.alerts))
+	for _, a := range g.alerts {
+		alerts = append(alerts, a)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Rule < alerts[j].Rule })
+
+	for _, s := range d.sinks {
+		d.wg.Add(1)
+		go func(s *notifySink) {
+			defer d.wg.Done()
+			d.deliver(ctx, s, g.labels, alerts)
+		}(s)
+	}
+}
+
+// deliver envía al destino las alertas que aún no recibió, reintentando
+// con backoff exponencial los errores transitorios
+func (d *dispatcher) deliver(ctx context.Context, s *notifySink, labels map[string]string, alerts []Alert) {
+	pending := s.undelivered(alerts)
+	if len(pending) == 0 {
+		return
+	}
+	n := Notification{Status: StateResolved, Group: labels, Alerts: pending, Timestamp: time.Now()}
+	for _, a := range pending {
+		if a.State == StateFiring {
+			n.Status = StateFiring
+		}
+	}
+
+	backoff := d.opts.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.send(ctx, n)
+		if err == nil {
+			s.markDelivered(pending)
+			return
+		}
+		if isPermanent(err) || attempt >= d.opts.MaxRetries || ctx.Err() != nil {
+			s.markFailed(err)
+			log.Printf("Error al notificar a %s (%d intentos): %v", s.status.Name, attempt+1, err)
+			return
+		}
+
+		s.mu.Lock()
+		s.status.Retries++
+		s.status.LastError = err.Error()
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			s.markFailed(err)
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, d.opts.MaxBackoff)
+	}
+}
+
+// dedupKey identifica una alerta en un estado: la misma alerta disparada
+// o resuelta no se entrega dos veces al mismo destino
+func dedupKey(a Alert) string {
+	return a.Rule + "|" + a.State + "|" + strconv.FormatInt(a.ActiveSince.UnixNano(), 10)
+}
+
+// undelivered retorna las alertas que el destino aún no recibió
+func (s *notifySink) undelivered(alerts []Alert) []Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := make([]Alert, 0, len(alerts))
+	for _, a := range alerts {
+		if _, ok := s.delivered[dedupKey(a)]; !ok {
+			pending = append(pending, a)
+		}
+	}
+	return pending
+}
+
+// markDelivered registra una entrega exitosa y olvida las claves antiguas
+func (s *notifySink) markDelivered(alerts []Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, at := range s.delivered {
+		if now.Sub(at) > dedupTTL {
+			delete(s.delivered, key)
+		}
+	}
+	for _, a := range alerts {
+		s.delivered[dedupKey(a)] = now
+	}
+	s.status.Sent++
+	s.status.LastSuccess = &now
+	s.status.LastError = ""
+}
+
+// markFailed registra una notificación descartada
+func (s *notifySink) markFailed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Failed++
+	s.status.LastError = err.Error()
+}
+
+// statuses retorna el estado de entrega de cada destino
+func (d *dispatcher) statuses() []SinkStatus {
+	statuses := make([]SinkStatus, len(d.sinks))
+	for i, s := range d.sinks {
+		s.mu.Lock()
+		statuses[i] = s.status
+		s.mu.Unlock()
+	}
+	return statuses
+}
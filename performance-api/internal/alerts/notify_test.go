@@ -0,0 +1,324 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookRecorder es un webhook de prueba que responde con los códigos
+// indicados (200 cuando se agotan) y registra cada notificación recibida
+type webhookRecorder struct {
+	mu            sync.Mutex
+	codes         []int
+	notifications []Notification
+	times         []time.Time
+}
+
+func (w *webhookRecorder) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	var n Notification
+	if err := json.NewDecoder(req.Body).Decode(&n); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.mu.Lock()
+	code := http.StatusOK
+	if len(w.times) < len(w.codes) {
+		code = w.codes[len(w.times)]
+	}
+	w.notifications = append(w.notifications, n)
+	w.times = append(w.times, time.Now())
+	w.mu.Unlock()
+	rw.WriteHeader(code)
+}
+
+func (w *webhookRecorder) requests() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.times)
+}
+
+// startDispatcher crea un despachador con un webhook hacia srv y lo
+// ejecuta hasta que termina la prueba o se llama a la función retornada
+func startDispatcher(t *testing.T, srv *httptest.Server, opts NotifyOptions) (*dispatcher, func()) {
+	t.Helper()
+	opts.Sinks = []SinkConfig{{Name: "hook", Type: SinkWebhook, URL: srv.URL}}
+	d, err := newDispatcher(opts)
+	if err != nil {
+		t.Fatalf("newDispatcher: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go d.run(ctx)
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			<-d.done
+		})
+	}
+	t.Cleanup(stop)
+	return d, stop
+}
+
+// waitFor espera hasta que cond se cumpla o falla la prueba
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tiempo agotado esperando %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func firingAlert(rule, severity string, since time.Time) Alert {
+	return Alert{Rule: rule, Severity: severity, State: StateFiring, ActiveSince: since, Value: 95}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		codes       []int
+		maxRetries  int
+		wantReqs    int
+		wantSent    int
+		wantFailed  int
+		wantRetries int
+	}{
+		{"éxito al primer intento", nil, 3, 1, 1, 0, 0},
+		{"errores transitorios", []int{503, 500}, 3, 3, 1, 0, 2},
+		{"429 se reintenta", []int{429}, 3, 2, 1, 0, 1},
+		{"reintentos agotados", []int{503, 503, 503}, 2, 3, 0, 1, 2},
+		{"error permanente", []int{400}, 3, 1, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &webhookRecorder{codes: tt.codes}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+
+			const backoff = 20 * time.Millisecond
+			d, _ := startDispatcher(t, srv, NotifyOptions{MaxRetries: tt.maxRetries, InitialBackoff: backoff, MaxBackoff: time.Second})
+			d.enqueue(firingAlert("cpu", "critical", time.Now()))
+
+			waitFor(t, "el resultado de la entrega", func() bool {
+				s := d.statuses()[0]
+				return s.Sent+s.Failed > 0
+			})
+			status := d.statuses()[0]
+			if rec.requests() != tt.wantReqs || status.Sent != tt.wantSent || status.Failed != tt.wantFailed || status.Retries != tt.wantRetries {
+				t.Errorf("solicitudes = %d, sent = %d, failed = %d, retries = %d; se esperaban %d, %d, %d, %d",
+					rec.requests(), status.Sent, status.Failed, status.Retries, tt.wantReqs, tt.wantSent, tt.wantFailed, tt.wantRetries)
+			}
+
+			// El backoff se duplica en cada reintento
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			wait := backoff
+			for i := 1; i < len(rec.times); i++ {
+				if gap := rec.times[i].Sub(rec.times[i-1]); gap < wait {
+					t.Errorf("reintento %d tras %v, se esperaba al menos %v", i, gap, wait)
+				}
+				wait *= 2
+			}
+		})
+	}
+}
+
+func TestDispatcherDeduplicates(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	d, _ := startDispatcher(t, srv, NotifyOptions{})
+
+	since := time.Now()
+	firing := firingAlert("cpu", "critical", since)
+	d.enqueue(firing)
+	waitFor(t, "la primera entrega", func() bool { return d.statuses()[0].Sent == 1 })
+
+	// La misma alerta disparada no se vuelve a entregar
+	d.enqueue(firing)
+	resolved := firing
+	resolved.State = StateResolved
+	d.enqueue(resolved)
+	waitFor(t, "la entrega de la resolución", func() bool { return d.statuses()[0].Sent == 2 })
+
+	// Una nueva activación de la misma regla sí se entrega
+	d.enqueue(firingAlert("cpu", "critical", since.Add(time.Minute)))
+	waitFor(t, "la nueva activación", func() bool { return d.statuses()[0].Sent == 3 })
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	want := []string{StateFiring, StateResolved, StateFiring}
+	if len(rec.notifications) != len(want) {
+		t.Fatalf("se recibieron %d notificaciones, se esperaban %d", len(rec.notifications), len(want))
+	}
+	for i, n := range rec.notifications {
+		if n.Status != want[i] || len(n.Alerts) != 1 {
+			t.Errorf("notificación %d: status %q con %d alertas, se esperaba %q con 1", i, n.Status, len(n.Alerts), want[i])
+		}
+	}
+}
+
+func TestDispatcherGroups(t *testing.T) {
+	tests := []struct {
+		name       string
+		groupBy    []string
+		wantGroups map[string]int // Alertas por valor de la etiqueta agrupada ("" = sin etiquetas)
+	}{
+		{"un solo grupo", nil, map[string]int{"": 3}},
+		{"por regla", []string{"rule"}, map[string]int{"cpu": 1, "memory": 1, "disk": 1}},
+		{"por severidad", []string{"severity"}, map[string]int{"critical": 2, "warning": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &webhookRecorder{}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+			d, _ := startDispatcher(t, srv, NotifyOptions{GroupWait: 50 * time.Millisecond, GroupBy: tt.groupBy})
+
+			now := time.Now()
+			d.enqueue(firingAlert("cpu", "critical", now))
+			d.enqueue(firingAlert("memory", "warning", now))
+			d.enqueue(firingAlert("disk", "critical", now))
+			// Un estado posterior de la misma regla reemplaza al anterior en el grupo
+			d.enqueue(firingAlert("cpu", "critical", now))
+
+			waitFor(t, "las entregas", func() bool { return d.statuses()[0].Sent == len(tt.wantGroups) })
+
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			got := make(map[string]int)
+			for _, n := range rec.notifications {
+				key := ""
+				for _, value := range n.Group {
+					key = value
+				}
+				got[key] += len(n.Alerts)
+			}
+			if len(got) != len(tt.wantGroups) {
+				t.Fatalf("grupos = %v, se esperaban %v", got, tt.wantGroups)
+			}
+			for key, count := range tt.wantGroups {
+				if got[key] != count {
+					t.Errorf("grupos = %v, se esperaban %v", got, tt.wantGroups)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcherStopKeepsRetrying(t *testing.T) {
+	rec := &webhookRecorder{codes: []int{503}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	d, stop := startDispatcher(t, srv, NotifyOptions{MaxRetries: 3, InitialBackoff: 200 * time.Millisecond})
+
+	d.enqueue(firingAlert("cpu", "critical", time.Now()))
+	waitFor(t, "el primer intento", func() bool { return rec.requests() == 1 })
+
+	// Detener durante el backoff no debe descartar la entrega en curso
+	stop()
+	status := d.statuses()[0]
+	if status.Sent != 1 || status.Failed != 0 || rec.requests() != 2 {
+		t.Errorf("sent = %d, failed = %d, solicitudes = %d; se esperaban 1, 0 y 2", status.Sent, status.Failed, rec.requests())
+	}
+}
+
+func TestDispatcherStopFlushesPendingGroups(t *testing.T) {
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	d, stop := startDispatcher(t, srv, NotifyOptions{GroupWait: time.Hour})
+
+	d.enqueue(firingAlert("cpu", "critical", time.Now()))
+	time.Sleep(20 * time.Millisecond)
+	stop()
+	if status := d.statuses()[0]; status.Sent != 1 || rec.requests() != 1 {
+		t.Errorf("sent = %d, solicitudes = %d; se esperaba el envío del grupo pendiente", status.Sent, rec.requests())
+	}
+}
+
+func TestAlertmanagerResendsFiringAlerts(t *testing.T) {
+	var mu sync.Mutex
+	var payloads [][]alertmanagerAlert
+	var received []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var alerts []alertmanagerAlert
+		if err := json.NewDecoder(req.Body).Decode(&alerts); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		payloads = append(payloads, alerts)
+		received = append(received, time.Now())
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(payloads)
+	}
+
+	const resend = 50 * time.Millisecond
+	d, err := newDispatcher(NotifyOptions{
+		Sinks:          []SinkConfig{{Name: "am", Type: SinkAlertmanager, URL: srv.URL}},
+		ResendInterval: resend,
+	})
+	if err != nil {
+		t.Fatalf("newDispatcher: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		<-d.done
+	}()
+	go d.run(ctx)
+
+	since := time.Now()
+	firing := firingAlert("cpu", "critical", since)
+	d.enqueue(firing)
+	waitFor(t, "los reenvíos", func() bool { return count() >= 3 })
+
+	// Cada envío de la alerta disparada renueva su endsAt
+	mu.Lock()
+	for i, alerts := range payloads[:3] {
+		if len(alerts) != 1 {
+			t.Fatalf("envío %d con %d alertas, se esperaba 1", i, len(alerts))
+		}
+		a := alerts[0]
+		if a.Labels["alertname"] != "cpu" || a.Labels["severity"] != "critical" || !a.StartsAt.Equal(since) {
+			t.Errorf("envío %d = %+v, se esperaba la alerta cpu desde %v", i, a, since)
+		}
+		lease := resend * alertmanagerLeaseFactor
+		if a.EndsAt == nil || a.EndsAt.Before(received[i].Add(lease/2)) || a.EndsAt.After(received[i].Add(lease)) {
+			t.Errorf("envío %d: endsAt = %v, se esperaba unos %v después de %v", i, a.EndsAt, lease, received[i])
+		}
+	}
+	mu.Unlock()
+
+	// Al resolverse se envía endsAt con el momento de la resolución y se
+	// dejan de reenviar
+	resolvedAt := time.Now()
+	resolved := firing
+	resolved.State = StateResolved
+	resolved.ResolvedAt = &resolvedAt
+	d.enqueue(resolved)
+	waitFor(t, "la resolución", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		last := payloads[len(payloads)-1]
+		return len(last) == 1 && last[0].EndsAt != nil && last[0].EndsAt.Equal(resolvedAt)
+	})
+	sent := count()
+	time.Sleep(4 * resend)
+	if got := count(); got != sent {
+		t.Errorf("se hicieron %d envíos tras la resolución, se esperaba ninguno", got-sent)
+	}
+}
@@ -0,0 +1,130 @@
+package alerts
+
+import (
+	"fmt"
+	"performance-api/internal/metrics"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RuleConfig describe una regla de alerta tal como se escribe en la configuración
+type RuleConfig struct {
+	Name        string `json:"name"`
+	Expr        string `json:"expr"`                  // Por ejemplo "cpu.percent > 90 for 2m"
+	Severity    string `json:"severity,omitempty"`    // Por ejemplo "warning" o "critical"
+	Description string `json:"description,omitempty"` // Texto libre incluido en las alertas
+}
+
+// Rule es una regla de alerta interpretada
+type Rule struct {
+	RuleConfig
+	Field         string  `json:"field"`                    // Campo de la muestra, por ejemplo cpu.percent
+	Function      string  `json:"function,omitempty"`       // avg_over_time, min_over_time o max_over_time
+	WindowSeconds float64 `json:"window_seconds,omitempty"` // Ventana de la función
+	Op            string  `json:"op"`                       // >, >=, <, <=, == o !=
+	Threshold     float64 `json:"threshold"`
+	ForSeconds    float64 `json:"for_seconds,omitempty"` // Tiempo que la condición debe cumplirse antes de disparar
+
+	window time.Duration
+	hold   time.Duration
+	value  metrics.FieldFunc
+}
+
+// exprPattern reconoce "<campo> [<función>(<ventana>)] <operador> <umbral> [for <duración>]"
+var exprPattern = regexp.MustCompile(`^([a-z_][a-z0-9_.]*)(?:\s+([a-z_]+)\(\s*([^)\s]+)\s*\))?\s*(>=|<=|==|!=|>|<)\s*([-+0-9.eE]+)(?:\s+for\s+(\S+))?$`)
+
+// ParseRule interpreta una regla de la configuración
+func ParseRule(cfg RuleConfig) (*Rule, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("la regla %q no tiene nombre", cfg.Expr)
+	}
+	match := exprPattern.FindStringSubmatch(strings.TrimSpace(cfg.Expr))
+	if match == nil {
+		return nil, fmt.Errorf("regla %s: expresión inválida %q (usa \"campo [avg_over_time(5m)] > umbral [for 2m]\")", cfg.Name, cfg.Expr)
+	}
+
+	rule := &Rule{RuleConfig: cfg, Field: match[1], Function: match[2], Op: match[4]}
+	var ok bool
+	if rule.value, ok = metrics.LookupField(rule.Field); !ok {
+		return nil, fmt.Errorf("regla %s: campo desconocido %q (disponibles: %s)", cfg.Name, rule.Field, strings.Join(metrics.FieldNames(), ", "))
+	}
+
+	if rule.Function != "" {
+		switch rule.Function {
+		case "avg_over_time", "min_over_time", "max_over_time":
+		default:
+			return nil, fmt.Errorf("regla %s: función desconocida %q (usa avg_over_time, min_over_time o max_over_time)", cfg.Name, rule.Function)
+		}
+		window, err := time.ParseDuration(match[3])
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("regla %s: ventana inválida %q", cfg.Name, match[3])
+		}
+		rule.window = window
+		rule.WindowSeconds = window.Seconds()
+	}
+
+	threshold, err := strconv.ParseFloat(match[5], 64)
+	if err != nil {
+		return nil, fmt.Errorf("regla %s: umbral inválido %q", cfg.Name, match[5])
+	}
+	rule.Threshold = threshold
+
+	if match[6] != "" {
+		forDuration, err := time.ParseDuration(match[6])
+		if err != nil || forDuration < 0 {
+			return nil, fmt.Errorf("regla %s: duración de for inválida %q", cfg.Name, match[6])
+		}
+		rule.hold = forDuration
+		rule.ForSeconds = forDuration.Seconds()
+	}
+	return rule, nil
+}
+
+// evaluate calcula el valor de la regla sobre las muestras recientes (la
+// última es la actual) e indica si se cumple la condición
+func (r *Rule) evaluate(window []metrics.SystemMetrics) (float64, bool) {
+	current := window[len(window)-1]
+	value := r.value(current)
+
+	if r.Function != "" {
+		start := current.Timestamp.Add(-r.window)
+		var sum float64
+		count := 0
+		for _, m := range window {
+			if !m.Timestamp.After(start) {
+				continue
+			}
+			v := r.value(m)
+			switch {
+			case count == 0:
+				value = v
+			case r.Function == "min_over_time" && v < value:
+				value = v
+			case r.Function == "max_over_time" && v > value:
+				value = v
+			}
+			sum += v
+			count++
+		}
+		if r.Function == "avg_over_time" && count > 0 {
+			value = sum / float64(count)
+		}
+	}
+
+	switch r.Op {
+	case ">":
+		return value, value > r.Threshold
+	case ">=":
+		return value, value >= r.Threshold
+	case "<":
+		return value, value < r.Threshold
+	case "<=":
+		return value, value <= r.Threshold
+	case "==":
+		return value, value == r.Threshold
+	default:
+		return value, value != r.Threshold
+	}
+}
@@ -0,0 +1,107 @@
+package alerts
+
+import (
+	"performance-api/internal/metrics"
+	"testing"
+	"time"
+)
+
+func TestParseRuleAccepts(t *testing.T) {
+	tests := []struct {
+		expr          string
+		field         string
+		function      string
+		windowSeconds float64
+		op            string
+		threshold     float64
+		forSeconds    float64
+	}{
+		{"cpu.percent > 90", "cpu.percent", "", 0, ">", 90, 0},
+		{"cpu.percent>90", "cpu.percent", "", 0, ">", 90, 0},
+		{"  memory.used_percent >= 80.5  ", "memory.used_percent", "", 0, ">=", 80.5, 0},
+		{"goroutines < 10 for 30s", "goroutines", "", 0, "<", 10, 30},
+		{"cpu.percent <= -1e2", "cpu.percent", "", 0, "<=", -100, 0},
+		{"cpu.percent == 0", "cpu.percent", "", 0, "==", 0, 0},
+		{"cpu.percent != 1", "cpu.percent", "", 0, "!=", 1, 0},
+		{"cpu.percent > 90 for 2m", "cpu.percent", "", 0, ">", 90, 120},
+		{"memory.used_percent avg_over_time(5m) > 80", "memory.used_percent", "avg_over_time", 300, ">", 80, 0},
+		{"runtime.gc_pauses.p99 max_over_time( 1m ) > 0.01 for 1m30s", "runtime.gc_pauses.p99", "max_over_time", 60, ">", 0.01, 90},
+		{"disk.io_time_ms_per_sec min_over_time(10s) >= 900", "disk.io_time_ms_per_sec", "min_over_time", 10, ">=", 900, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			rule, err := ParseRule(RuleConfig{Name: "regla", Expr: tt.expr})
+			if err != nil {
+				t.Fatalf("ParseRule: %v", err)
+			}
+			if rule.Field != tt.field || rule.Function != tt.function || rule.WindowSeconds != tt.windowSeconds ||
+				rule.Op != tt.op || rule.Threshold != tt.threshold || rule.ForSeconds != tt.forSeconds {
+				t.Errorf("ParseRule = {%s %s %g %s %g %g}, se esperaba {%s %s %g %s %g %g}",
+					rule.Field, rule.Function, rule.WindowSeconds, rule.Op, rule.Threshold, rule.ForSeconds,
+					tt.field, tt.function, tt.windowSeconds, tt.op, tt.threshold, tt.forSeconds)
+			}
+		})
+	}
+}
+
+func TestParseRuleRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RuleConfig
+	}{
+		{"sin nombre", RuleConfig{Expr: "cpu.percent > 90"}},
+		{"vacía", RuleConfig{Name: "r", Expr: ""}},
+		{"sin operador", RuleConfig{Name: "r", Expr: "cpu.percent 90"}},
+		{"sin umbral", RuleConfig{Name: "r", Expr: "cpu.percent >"}},
+		{"operador desconocido", RuleConfig{Name: "r", Expr: "cpu.percent => 90"}},
+		{"campo desconocido", RuleConfig{Name: "r", Expr: "cpu.temperature > 90"}},
+		{"umbral inválido", RuleConfig{Name: "r", Expr: "cpu.percent > 9.0.1"}},
+		{"función desconocida", RuleConfig{Name: "r", Expr: "cpu.percent rate(5m) > 90"}},
+		{"ventana inválida", RuleConfig{Name: "r", Expr: "cpu.percent avg_over_time(5) > 90"}},
+		{"ventana cero", RuleConfig{Name: "r", Expr: "cpu.percent avg_over_time(0s) > 90"}},
+		{"for inválido", RuleConfig{Name: "r", Expr: "cpu.percent > 90 for pronto"}},
+		{"for negativo", RuleConfig{Name: "r", Expr: "cpu.percent > 90 for -1m"}},
+		{"texto sobrante", RuleConfig{Name: "r", Expr: "cpu.percent > 90 and goroutines > 10"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseRule(tt.cfg); err == nil {
+				t.Errorf("ParseRule(%q): se esperaba un error", tt.cfg.Expr)
+			}
+		})
+	}
+}
+
+func TestRuleEvaluate(t *testing.T) {
+	base := time.Now()
+	window := make([]metrics.SystemMetrics, 0)
+	for i, cpu := range []float64{10, 50, 90, 70} {
+		window = append(window, metrics.SystemMetrics{Timestamp: base.Add(time.Duration(i) * time.Minute), CPU: metrics.CPUInfo{Percent: cpu}})
+	}
+
+	tests := []struct {
+		expr  string
+		value float64
+		met   bool
+	}{
+		{"cpu.percent > 60", 70, true},
+		{"cpu.percent > 70", 70, false},
+		{"cpu.percent >= 70", 70, true},
+		{"cpu.percent avg_over_time(3m) > 60", 70, true}, // 50, 90 y 70: la muestra en el borde queda fuera
+		{"cpu.percent max_over_time(10m) >= 90", 90, true},
+		{"cpu.percent min_over_time(10m) < 20", 10, true},
+		{"cpu.percent min_over_time(90s) < 20", 70, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			rule, err := ParseRule(RuleConfig{Name: "regla", Expr: tt.expr})
+			if err != nil {
+				t.Fatalf("ParseRule: %v", err)
+			}
+			value, met := rule.evaluate(window)
+			if value != tt.value || met != tt.met {
+				t.Errorf("evaluate = (%g, %v), se esperaba (%g, %v)", value, met, tt.value, tt.met)
+			}
+		})
+	}
+}
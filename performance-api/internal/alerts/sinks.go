@@ -0,0 +1,234 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tipos de destino de las notificaciones
+const (
+	SinkWebhook      = "webhook"      // JSON genérico
+	SinkSlack        = "slack"        // Webhook entrante de Slack (o compatible)
+	SinkAlertmanager = "alertmanager" // API v2 de Alertmanager (/api/v2/alerts)
+	SinkFile         = "file"         // Archivo JSONL de solo escritura al final
+)
+
+// SinkConfig describe un destino de notificaciones
+type SinkConfig struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`              // webhook, slack, alertmanager o file
+	URL     string            `json:"url,omitempty"`     // Para los webhooks
+	Path    string            `json:"path,omitempty"`    // Para el archivo
+	Headers map[string]string `json:"headers,omitempty"` // Cabeceras adicionales de los webhooks
+}
+
+// Notification es un grupo de alertas que se envía en una sola entrega
+type Notification struct {
+	Status    string            `json:"status"`          // firing si alguna alerta está disparada, si no resolved
+	Group     map[string]string `json:"group,omitempty"` // Valores de los campos de agrupación
+	Alerts    []Alert           `json:"alerts"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// sink entrega notificaciones a un destino
+type sink interface {
+	send(ctx context.Context, n Notification) error
+}
+
+// permanentError indica un error de entrega que no se debe reintentar
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// newSink crea el destino indicado en la configuración. lease es el plazo
+// que se adelanta el endsAt de las alertas disparadas enviadas a Alertmanager.
+func newSink(cfg SinkConfig, timeout, lease time.Duration) (sink, error) {
+	switch cfg.Type {
+	case SinkWebhook, SinkSlack, SinkAlertmanager:
+		if !strings.HasPrefix(cfg.URL, "http://") && !strings.HasPrefix(cfg.URL, "https://") {
+			return nil, fmt.Errorf("destino %s: URL inválida %q", cfg.Name, cfg.URL)
+		}
+		return &webhookSink{cfg: cfg, client: &http.Client{Timeout: timeout}, lease: lease}, nil
+	case SinkFile:
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("destino %s: falta la ruta del archivo", cfg.Name)
+		}
+		if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+			return nil, fmt.Errorf("destino %s: error al crear el directorio: %w", cfg.Name, err)
+		}
+		return &fileSink{path: cfg.Path}, nil
+	default:
+		return nil, fmt.Errorf("destino %s: tipo desconocido %q (usa webhook, slack, alertmanager o file)", cfg.Name, cfg.Type)
+	}
+}
+
+// webhookSink envía las notificaciones por HTTP POST
+type webhookSink struct {
+	cfg    SinkConfig
+	client *http.Client
+	lease  time.Duration // Vigencia de las alertas disparadas en Alertmanager
+}
+
+func (s *webhookSink) send(ctx context.Context, n Notification) error {
+	var payload interface{}
+	switch s.cfg.Type {
+	case SinkSlack:
+		payload = slackPayload(n)
+	case SinkAlertmanager:
+		payload = alertmanagerPayload(n, s.lease)
+	default:
+		payload = n
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return &permanentError{fmt.Errorf("error al codificar la notificación: %w", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("respuesta %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		// Error del cliente: reintentar no cambiaría el resultado
+		return &permanentError{err}
+	}
+	return err
+}
+
+// slackPayload arma un mensaje de texto compatible con los webhooks entrantes de Slack
+func slackPayload(n Notification) map[string]interface{} {
+	var b strings.Builder
+	firing := 0
+	for _, a := range n.Alerts {
+		if a.State == StateFiring {
+			firing++
+		}
+	}
+	if firing > 0 {
+		fmt.Fprintf(&b, "🚨 *[FIRING:%d]*", firing)
+	} else {
+		b.WriteString("✅ *[RESOLVED]*")
+	}
+	for key, value := range n.Group {
+		fmt.Fprintf(&b, " %s=%s", key, value)
+	}
+	for _, a := range n.Alerts {
+		icon := "🔥"
+		if a.State == StateResolved {
+			icon = "✅"
+		}
+		fmt.Fprintf(&b, "\n%s *%s*", icon, a.Rule)
+		if a.Severity != "" {
+			fmt.Fprintf(&b, " (%s)", a.Severity)
+		}
+		fmt.Fprintf(&b, ": `%s` — valor %g", a.Expr, a.Value)
+		if a.Description != "" {
+			fmt.Fprintf(&b, "\n    %s", a.Description)
+		}
+	}
+	return map[string]interface{}{"text": b.String()}
+}
+
+// alertmanagerAlert es una alerta en el formato de la API v2 de Alertmanager
+type alertmanagerAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      *time.Time        `json:"endsAt,omitempty"`
+}
+
+// alertmanagerPayload convierte las alertas al formato de POST /api/v2/alerts.
+// Las disparadas vencen lease después del envío, de modo que Alertmanager las
+// mantiene activas mientras se reenvíen y no las resuelve por su cuenta.
+func alertmanagerPayload(n Notification, lease time.Duration) []alertmanagerAlert {
+	alerts := make([]alertmanagerAlert, len(n.Alerts))
+	for i, a := range n.Alerts {
+		labels := map[string]string{"alertname": a.Rule}
+		if a.Severity != "" {
+			labels["severity"] = a.Severity
+		}
+		annotations := map[string]string{
+			"expr":  a.Expr,
+			"value": fmt.Sprintf("%g", a.Value),
+		}
+		if a.Description != "" {
+			annotations["description"] = a.Description
+		}
+		endsAt := a.ResolvedAt
+		if a.State == StateFiring {
+			lapse := n.Timestamp.Add(lease)
+			endsAt = &lapse
+		}
+		alerts[i] = alertmanagerAlert{
+			Labels:      labels,
+			Annotations: annotations,
+			StartsAt:    a.ActiveSince,
+			EndsAt:      endsAt,
+		}
+	}
+	return alerts
+}
+
+// fileSink agrega cada alerta notificada como una línea JSON al archivo
+type fileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *fileSink) send(ctx context.Context, n Notification) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, a := range n.Alerts {
+		if err := enc.Encode(a); err != nil {
+			return &permanentError{fmt.Errorf("error al codificar la alerta: %w", err)}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error al abrir el archivo de alertas: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("error al escribir el archivo de alertas: %w", err)
+	}
+	return f.Close()
+}
+
+// isPermanent indica si un error de entrega no se debe reintentar
+func isPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
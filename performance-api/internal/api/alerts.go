@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"performance-api/internal/alerts"
+	"time"
+)
+
+// handleListAlerts lista las alertas activas, opcionalmente filtradas por
+// estado (state=pending|firing)
+func (r *Router) handleListAlerts(w http.ResponseWriter, req *http.Request) {
+	state := req.URL.Query().Get("state")
+	if state != "" && state != alerts.StatePending && state != alerts.StateFiring {
+		r.respondError(w, http.StatusBadRequest, fmt.Sprintf("Estado inválido %q (usa pending o firing)", state))
+		return
+	}
+
+	active := make([]alerts.Alert, 0)
+	for _, alert := range r.alerts.Alerts() {
+		if state == "" || alert.State == state {
+			active = append(active, alert)
+		}
+	}
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"count":  len(active),
+		"alerts": active,
+	})
+}
+
+// handleListAlertRules lista las reglas de alerta con su estado y último valor
+func (r *Router) handleListAlertRules(w http.ResponseWriter, req *http.Request) {
+	rules := r.alerts.Rules()
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"count": len(rules),
+		"rules": rules,
+	})
+}
+
+// handleListAlertSinks lista los destinos de notificaciones con su estado de entrega
+func (r *Router) handleListAlertSinks(w http.ResponseWriter, req *http.Request) {
+	sinks := r.alerts.Sinks()
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"count": len(sinks),
+		"sinks": sinks,
+	})
+}
+
+// handleAlertHistory retorna las transiciones de estado de las alertas,
+// filtradas por regla (rule), rango (from, to) y cantidad (limit)
+func (r *Router) handleAlertHistory(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	from, to, err := parseTimeRange(query, time.Now())
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePositiveIntParam(query, "limit")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	history := r.alerts.History(query.Get("rule"), from, to, limit)
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"count":       len(history),
+		"transitions": history,
+	})
+}
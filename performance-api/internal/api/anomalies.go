@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"performance-api/internal/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleGetMetricsAnomalies detecta muestras anómalas en el historial con
+// puntaje z móvil, bandas EWMA y desviación absoluta mediana. Parámetros
+// opcionales: series, methods, window, threshold, alpha, from, to y limit.
+func (r *Router) handleGetMetricsAnomalies(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	from, to, err := parseTimeRange(query, time.Now())
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, err := parseAnomalyOptions(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := r.collector.DetectAnomalies(from, to, opts)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if report == nil {
+		r.respondError(w, http.StatusNotFound, "No hay métricas disponibles aún")
+		return
+	}
+	r.respondJSON(w, http.StatusOK, report)
+}
+
+// parseAnomalyOptions interpreta los parámetros de la detección de anomalías
+func parseAnomalyOptions(query url.Values) (metrics.AnomalyOptions, error) {
+	var opts metrics.AnomalyOptions
+	var err error
+
+	opts.Series = parseListParam(query, "series")
+	opts.Methods = parseListParam(query, "methods")
+	if opts.Window, err = parsePositiveIntParam(query, "window"); err != nil {
+		return opts, err
+	}
+	if opts.Limit, err = parsePositiveIntParam(query, "limit"); err != nil {
+		return opts, err
+	}
+	if opts.Threshold, err = parsePositiveFloatParam(query, "threshold"); err != nil {
+		return opts, err
+	}
+	if opts.Alpha, err = parsePositiveFloatParam(query, "alpha"); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// parseListParam interpreta una lista separada por comas (vacío retorna nil)
+func parseListParam(query url.Values, name string) []string {
+	var values []string
+	for _, value := range strings.Split(query.Get(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// parsePositiveFloatParam interpreta un número positivo (vacío retorna 0)
+func parsePositiveFloatParam(query url.Values, name string) (float64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(f > 0) {
+		return 0, fmt.Errorf("parámetro %s inválido %q (debe ser un número positivo)", name, value)
+	}
+	return f, nil
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"performance-api/internal/profiler"
+	"strconv"
+	"time"
+)
+
+// handleStartCPUProfile inicia una sesión de perfil de CPU que se termina
+// con /api/profile/cpu/stop; seconds limita su duración (máximo 300)
+func (r *Router) handleStartCPUProfile(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	seconds, err := parsePositiveIntParam(query, "seconds")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := time.Duration(seconds) * time.Second
+	if limit > profiler.MaxCPUProfileDuration {
+		r.respondError(w, http.StatusBadRequest, fmt.Sprintf("La duración máxima de un perfil de CPU es %.0f segundos", profiler.MaxCPUProfileDuration.Seconds()))
+		return
+	}
+	labels, err := parseLabelParams(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	session, err := r.profiler.StartCPUProfile(limit, labels)
+	if err != nil {
+		r.respondCPUProfileError(w, err)
+		return
+	}
+	r.respondJSON(w, http.StatusCreated, session)
+}
+
+// handleStopCPUProfile termina la sesión de perfil de CPU activa y la
+// descarga como cualquier otro perfil de CPU
+func (r *Router) handleStopCPUProfile(w http.ResponseWriter, req *http.Request) {
+	profile, err := r.profiler.StopCPUProfile()
+	if err != nil {
+		r.respondCPUProfileError(w, err)
+		return
+	}
+	r.respondProfile(w, profile)
+}
+
+// handleCPUProfileSession retorna la sesión de perfil de CPU activa
+func (r *Router) handleCPUProfileSession(w http.ResponseWriter, req *http.Request) {
+	session, ok := r.profiler.CPUSession()
+	if !ok {
+		r.respondError(w, http.StatusNotFound, profiler.ErrNoCPUSession.Error())
+		return
+	}
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"session":     session,
+		"eta_seconds": session.ETA().Seconds(),
+	})
+}
+
+// respondCPUProfileError traduce los errores de las sesiones de perfil de
+// CPU: 409 con el tiempo estimado (Retry-After) si hay otra sesión activa
+func (r *Router) respondCPUProfileError(w http.ResponseWriter, err error) {
+	var busy *profiler.CPUBusyError
+	switch {
+	case errors.As(err, &busy):
+		body := map[string]interface{}{"error": err.Error()}
+		if busy.Active != nil {
+			eta := busy.Active.ETA().Seconds()
+			body["active"] = busy.Active
+			body["eta_seconds"] = eta
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(eta))))
+		}
+		r.respondJSON(w, http.StatusConflict, body)
+	case errors.Is(err, profiler.ErrNoCPUSession):
+		r.respondError(w, http.StatusNotFound, err.Error())
+	default:
+		r.respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"performance-api/internal/profiler"
+	"strconv"
+	"testing"
+)
+
+func TestStartCPUProfileBusy(t *testing.T) {
+	r := &Router{profiler: profiler.NewProfiler()}
+	start := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.handleStartCPUProfile(rec, httptest.NewRequest(http.MethodPost, "/api/profile/cpu/start?seconds=30", nil))
+		return rec
+	}
+
+	if rec := start(); rec.Code != http.StatusCreated {
+		t.Fatalf("primer start = %d, se esperaba %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	defer r.profiler.StopCPUProfile()
+
+	rec := start()
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("segundo start = %d, se esperaba %d", rec.Code, http.StatusConflict)
+	}
+	retry, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retry < 29 || retry > 30 {
+		t.Errorf("Retry-After = %q, se esperaban unos 30 segundos", rec.Header().Get("Retry-After"))
+	}
+	var body struct {
+		Error      string               `json:"error"`
+		ETASeconds float64              `json:"eta_seconds"`
+		Active     *profiler.CPUSession `json:"active"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("cuerpo inválido: %v", err)
+	}
+	if body.Error == "" || body.Active == nil || body.ETASeconds <= 28 || body.ETASeconds > 30 {
+		t.Errorf("cuerpo = %+v, se esperaba el error con la sesión activa y su ETA", body)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"performance-api/internal/metrics"
+
+	"github.com/gorilla/mux"
+)
+
+// handleWatchProcess registra un proceso externo por PID o patrón de nombre
+func (r *Router) handleWatchProcess(w http.ResponseWriter, req *http.Request) {
+	var target metrics.ProcessTarget
+	if err := json.NewDecoder(req.Body).Decode(&target); err != nil {
+		r.respondError(w, http.StatusBadRequest, "Cuerpo JSON inválido: "+err.Error())
+		return
+	}
+
+	info, err := r.watcher.Watch(target)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	r.respondJSON(w, http.StatusCreated, info)
+}
+
+// handleListProcesses lista los procesos supervisados con su última muestra
+func (r *Router) handleListProcesses(w http.ResponseWriter, req *http.Request) {
+	watches := r.watcher.ListWatches()
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"count":     len(watches),
+		"processes": watches,
+	})
+}
+
+// handleGetProcess retorna el estado de un proceso supervisado
+func (r *Router) handleGetProcess(w http.ResponseWriter, req *http.Request) {
+	info, err := r.watcher.GetWatch(mux.Vars(req)["id"])
+	if err != nil {
+		r.respondProcessError(w, err)
+		return
+	}
+	r.respondJSON(w, http.StatusOK, info)
+}
+
+// handleUnwatchProcess deja de supervisar un proceso
+func (r *Router) handleUnwatchProcess(w http.ResponseWriter, req *http.Request) {
+	if err := r.watcher.Unwatch(mux.Vars(req)["id"]); err != nil {
+		r.respondProcessError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetProcessHistory retorna el historial de un proceso supervisado
+func (r *Router) handleGetProcessHistory(w http.ResponseWriter, req *http.Request) {
+	history, err := r.watcher.GetHistory(mux.Vars(req)["id"])
+	if err != nil {
+		r.respondProcessError(w, err)
+		return
+	}
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"count":   len(history),
+		"history": history,
+	})
+}
+
+// handleGetProcessStats retorna estadísticas del historial de un proceso supervisado
+func (r *Router) handleGetProcessStats(w http.ResponseWriter, req *http.Request) {
+	opts, err := parseStatsOptions(req.URL.Query())
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := r.watcher.GetStats(mux.Vars(req)["id"], opts)
+	if err != nil {
+		r.respondProcessError(w, err)
+		return
+	}
+	if stats == nil {
+		r.respondError(w, http.StatusNotFound, "No hay muestras del proceso aún")
+		return
+	}
+	r.respondJSON(w, http.StatusOK, stats)
+}
+
+// respondProcessError traduce los errores del supervisor de procesos a respuestas HTTP
+func (r *Router) respondProcessError(w http.ResponseWriter, err error) {
+	if errors.Is(err, metrics.ErrProcessWatchNotFound) {
+		r.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	r.respondError(w, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"performance-api/internal/profiler"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// handleListProfiles lista los tipos de perfil disponibles y los perfiles
+// archivados, filtrados por tipo (type), rango (from, to), etiquetas
+// (label=clave:valor) y cantidad (limit)
+func (r *Router) handleListProfiles(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	from, to, err := parseTimeRange(query, time.Now())
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	labels, err := parseLabelParams(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePositiveIntParam(query, "limit")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	archived := r.profiler.Archive().List(profiler.ArchiveFilter{
+		Name:   query.Get("type"),
+		From:   from,
+		To:     to,
+		Labels: labels,
+		Limit:  limit,
+	})
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"profiles": r.profiler.ListProfiles(),
+		"rates":    r.profiler.Rates(),
+		"count":    len(archived),
+		"archive":  archived,
+	})
+}
+
+// handleGetProfileRates retorna las tasas de muestreo de los perfiles del runtime
+func (r *Router) handleGetProfileRates(w http.ResponseWriter, req *http.Request) {
+	r.respondJSON(w, http.StatusOK, r.profiler.Rates())
+}
+
+// handleSetProfileRates modifica en caliente las tasas de muestreo de los
+// perfiles de bloqueos, mutex y memoria; los campos omitidos no cambian
+func (r *Router) handleSetProfileRates(w http.ResponseWriter, req *http.Request) {
+	var update profiler.RateUpdate
+	if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+		r.respondError(w, http.StatusBadRequest, "Cuerpo JSON inválido: "+err.Error())
+		return
+	}
+
+	rates, err := r.profiler.SetRates(update)
+	if err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+	log.Printf("Tasas de perfilamiento actualizadas: bloqueos=%d mutex=%d memoria=%d",
+		rates.BlockProfileRate, rates.MutexProfileFraction, rates.MemProfileRate)
+	r.respondJSON(w, http.StatusOK, rates)
+}
+
+// handleGetArchivedProfile descarga un perfil archivado por ID
+func (r *Router) handleGetArchivedProfile(w http.ResponseWriter, req *http.Request) {
+	profile, err := r.profiler.Archive().Get(mux.Vars(req)["id"])
+	if err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+	r.respondProfile(w, profile)
+}
+
+// handleDeleteArchivedProfile elimina un perfil archivado
+func (r *Router) handleDeleteArchivedProfile(w http.ResponseWriter, req *http.Request) {
+	if err := r.profiler.Archive().Delete(mux.Vars(req)["id"]); err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDiffProfiles compara dos perfiles (por ID o tipo) y retorna el cambio
+// por función en JSON o, con format=pprof, un perfil con la base negada
+func (r *Router) handleDiffProfiles(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	base, target := query.Get("base"), query.Get("target")
+	if base == "" || target == "" {
+		r.respondError(w, http.StatusBadRequest, "Los parámetros base y target son obligatorios")
+		return
+	}
+	sortBy, err := parseSortParam(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePositiveIntParam(query, "n")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "pprof" {
+		r.respondError(w, http.StatusBadRequest, fmt.Sprintf("Formato inválido %q (usa json o pprof)", format))
+		return
+	}
+
+	diff, merged, err := r.profiler.Diff(base, target, profiler.DiffOptions{
+		SampleType: query.Get("sample_type"),
+		SortBy:     sortBy,
+		Limit:      limit,
+	})
+	if err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+
+	if format != "pprof" {
+		r.respondJSON(w, http.StatusOK, diff)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := merged.Write(&buf); err != nil {
+		r.respondError(w, http.StatusInternalServerError, "Error al generar el perfil diferencial: "+err.Error())
+		return
+	}
+	r.respondProfile(w, &profiler.ProfileData{
+		Name:      diff.Base.Name + "-diff",
+		Timestamp: diff.Target.Timestamp,
+		Data:      buf.Bytes(),
+	})
+}
+
+// handleMergedProfiles combina los perfiles archivados de un tipo (type) en
+// un rango (from, to; por defecto la última hora), con etiquetas
+// (label=clave:valor) y cantidad (limit) en un solo perfil agregado
+func (r *Router) handleMergedProfiles(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	name := query.Get("type")
+	if name == "" {
+		r.respondError(w, http.StatusBadRequest, "El parámetro type es obligatorio")
+		return
+	}
+	from, to, err := parseWindowedTimeRange(query, time.Now(), profiler.DefaultMergeWindow)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	labels, err := parseLabelParams(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePositiveIntParam(query, "limit")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit > profiler.MaxMergedProfiles {
+		r.respondError(w, http.StatusBadRequest, fmt.Sprintf("El parámetro limit no puede superar %d", profiler.MaxMergedProfiles))
+		return
+	}
+
+	merged, sources, err := r.profiler.Merge(profiler.ArchiveFilter{
+		Name:   name,
+		From:   from,
+		To:     to,
+		Labels: labels,
+		Limit:  limit,
+	})
+	if err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+	w.Header().Set("X-Merged-Count", strconv.Itoa(len(sources)))
+	r.respondProfile(w, merged)
+}
+
+// handleProfileTop retorna las funciones con mayor consumo de un perfil
+// (por ID o tipo), ordenadas por valor propio (flat) o acumulado (cum)
+func (r *Router) handleProfileTop(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	sortBy, err := parseSortParam(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePositiveIntParam(query, "n")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = 20
+	}
+
+	report, err := r.profiler.Top(mux.Vars(req)["id"], profiler.TopOptions{
+		SampleType: query.Get("sample_type"),
+		SortBy:     sortBy,
+		Limit:      limit,
+	})
+	if err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+	r.respondJSON(w, http.StatusOK, report)
+}
+
+// maxFlameGraphWidth limita el ancho en píxeles del flame graph solicitado
+const maxFlameGraphWidth = 10000
+
+// handleProfileFolded retorna las trazas de un perfil (por ID o tipo) en
+// formato folded, una línea por traza, compatible con flamegraph.pl
+func (r *Router) handleProfileFolded(w http.ResponseWriter, req *http.Request) {
+	folded, err := r.profiler.Folded(mux.Vars(req)["id"], req.URL.Query().Get("sample_type"))
+	if err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Profile-ID", folded.Profile.ID)
+	w.Header().Set("X-Sample-Type", folded.SampleType)
+	w.WriteHeader(http.StatusOK)
+	folded.WriteFolded(w)
+}
+
+// handleProfileFlameGraph retorna un flame graph SVG interactivo de un
+// perfil (por ID o tipo); icicle=true dibuja la raíz arriba
+func (r *Router) handleProfileFlameGraph(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	icicle, err := parseBoolParam(query, "icicle")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	width, err := parsePositiveIntParam(query, "width")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if width > maxFlameGraphWidth {
+		r.respondError(w, http.StatusBadRequest, fmt.Sprintf("parámetro width no puede superar %d", maxFlameGraphWidth))
+		return
+	}
+
+	folded, err := r.profiler.Folded(mux.Vars(req)["id"], query.Get("sample_type"))
+	if err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("X-Profile-ID", folded.Profile.ID)
+	w.Header().Set("X-Sample-Type", folded.SampleType)
+	w.WriteHeader(http.StatusOK)
+	folded.WriteFlameGraph(w, profiler.FlameGraphOptions{
+		Icicle: icicle,
+		Title:  query.Get("title"),
+		Width:  width,
+	})
+}
+
+// respondProfileError traduce los errores del perfilador a respuestas HTTP
+func (r *Router) respondProfileError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, profiler.ErrProfileNotFound):
+		r.respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, profiler.ErrInvalidProfile):
+		r.respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		r.respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
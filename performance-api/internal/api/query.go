@@ -0,0 +1,174 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"performance-api/internal/metrics"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseTimeParam interpreta un parámetro de tiempo en formato RFC3339,
+// "now" o relativo al momento actual (por ejemplo "-15m" o "-2h").
+// Un valor vacío retorna el tiempo cero.
+func parseTimeParam(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return time.Time{}, nil
+	case value == "now":
+		return now, nil
+	case strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+"):
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tiempo relativo inválido %q", value)
+		}
+		return now.Add(d), nil
+	default:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("tiempo inválido %q (usa RFC3339 o relativo como -15m)", value)
+		}
+		return t, nil
+	}
+}
+
+// parseTimeRange lee los parámetros from y to de la consulta
+func parseTimeRange(query url.Values, now time.Time) (time.Time, time.Time, error) {
+	from, err := parseTimeParam(query.Get("from"), now)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parámetro from: %w", err)
+	}
+	to, err := parseTimeParam(query.Get("to"), now)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parámetro to: %w", err)
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("el parámetro to debe ser posterior a from")
+	}
+	return from, to, nil
+}
+
+// parseWindowedTimeRange lee from y to como parseTimeRange, pero sin from
+// el rango comienza una ventana antes de to (o del momento actual)
+func parseWindowedTimeRange(query url.Values, now time.Time, window time.Duration) (time.Time, time.Time, error) {
+	from, to, err := parseTimeRange(query, now)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if from.IsZero() {
+		end := to
+		if end.IsZero() {
+			end = now
+		}
+		from = end.Add(-window)
+	}
+	return from, to, nil
+}
+
+// parseDurationParam interpreta una duración positiva (vacío retorna 0)
+func parseDurationParam(query url.Values, name string) (time.Duration, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("parámetro %s inválido %q (usa una duración como 30s o 5m)", name, value)
+	}
+	return d, nil
+}
+
+// parsePositiveIntParam interpreta un entero positivo (vacío retorna 0)
+func parsePositiveIntParam(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("parámetro %s inválido %q (debe ser un entero positivo)", name, value)
+	}
+	return n, nil
+}
+
+// maxHistogramBuckets limita el tamaño de los histogramas solicitados
+const maxHistogramBuckets = 1000
+
+// parseStatsOptions interpreta las estadísticas opcionales solicitadas:
+// percentiles=true, variance=true y histogram=<cubetas>
+func parseStatsOptions(query url.Values) (metrics.StatsOptions, error) {
+	var opts metrics.StatsOptions
+	var err error
+
+	if opts.Percentiles, err = parseBoolParam(query, "percentiles"); err != nil {
+		return opts, err
+	}
+	if opts.Variance, err = parseBoolParam(query, "variance"); err != nil {
+		return opts, err
+	}
+	if opts.HistogramBuckets, err = parsePositiveIntParam(query, "histogram"); err != nil {
+		return opts, err
+	}
+	if opts.HistogramBuckets > maxHistogramBuckets {
+		return opts, fmt.Errorf("parámetro histogram inválido %d (máximo %d cubetas)", opts.HistogramBuckets, maxHistogramBuckets)
+	}
+	return opts, nil
+}
+
+// parseBoolParam interpreta un parámetro booleano (vacío retorna false)
+func parseBoolParam(query url.Values, name string) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("parámetro %s inválido %q (debe ser true o false)", name, value)
+	}
+	return b, nil
+}
+
+// parseDebugParam interpreta el formato de un perfil: 0 (protobuf), 1 o 2 (texto)
+func parseDebugParam(query url.Values) (int, error) {
+	value := query.Get("debug")
+	if value == "" {
+		return 0, nil
+	}
+	debug, err := strconv.Atoi(value)
+	if err != nil || debug < 0 || debug > 2 {
+		return 0, fmt.Errorf("parámetro debug inválido %q (usa 0, 1 o 2)", value)
+	}
+	return debug, nil
+}
+
+// parseLabelParams interpreta los parámetros label=clave:valor (repetibles)
+func parseLabelParams(query url.Values) (map[string]string, error) {
+	values := query["label"]
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	labels := make(map[string]string, len(values))
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, ":")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("parámetro label inválido %q (usa clave:valor)", value)
+		}
+		labels[key] = val
+	}
+	return labels, nil
+}
+
+// parseSortParam interpreta el orden de las funciones de un perfil: flat (por defecto) o cum
+func parseSortParam(query url.Values) (string, error) {
+	switch value := query.Get("sort"); value {
+	case "", "flat":
+		return "flat", nil
+	case "cum":
+		return "cum", nil
+	default:
+		return "", fmt.Errorf("parámetro sort inválido %q (usa flat o cum)", value)
+	}
+}
@@ -2,9 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"performance-api/internal/alerts"
+	"performance-api/internal/exposition"
 	"performance-api/internal/metrics"
 	"performance-api/internal/profiler"
+	"performance-api/internal/runner"
 	"strconv"
 	"time"
 
@@ -15,14 +19,20 @@ import (
 type Router struct {
 	collector *metrics.Collector
 	profiler  *profiler.Profiler
+	watcher   *metrics.ProcessWatcher
+	runner    *runner.Runner
+	alerts    *alerts.Engine
 	mux       *mux.Router
 }
 
 // NewRouter crea un nuevo router con los handlers configurados
-func NewRouter(collector *metrics.Collector, profiler *profiler.Profiler) *Router {
+func NewRouter(collector *metrics.Collector, profiler *profiler.Profiler, watcher *metrics.ProcessWatcher, runner *runner.Runner, alerts *alerts.Engine) *Router {
 	r := &Router{
 		collector: collector,
 		profiler:  profiler,
+		watcher:   watcher,
+		runner:    runner,
+		alerts:    alerts,
 		mux:       mux.NewRouter(),
 	}
 	
@@ -36,13 +46,66 @@ func (r *Router) setupRoutes() {
 	r.mux.HandleFunc("/api/metrics", r.handleGetMetrics).Methods("GET")
 	r.mux.HandleFunc("/api/metrics/history", r.handleGetMetricsHistory).Methods("GET")
 	r.mux.HandleFunc("/api/metrics/stats", r.handleGetMetricsStats).Methods("GET")
+	r.mux.HandleFunc("/api/metrics/anomalies", r.handleGetMetricsAnomalies).Methods("GET")
+	r.mux.HandleFunc("/api/metrics/stream", r.handleMetricsStream).Methods("GET")
+	
+	// Endpoints de alertas
+	r.mux.HandleFunc("/api/alerts", r.handleListAlerts).Methods("GET")
+	r.mux.HandleFunc("/api/alerts/rules", r.handleListAlertRules).Methods("GET")
+	r.mux.HandleFunc("/api/alerts/history", r.handleAlertHistory).Methods("GET")
+	r.mux.HandleFunc("/api/alerts/sinks", r.handleListAlertSinks).Methods("GET")
+	
+	// Endpoint WebSocket con suscripciones por familia y perfiles bajo demanda
+	r.mux.HandleFunc("/api/ws", r.handleWebSocket).Methods("GET")
+	
+	// Endpoint de exposición para Prometheus/OpenMetrics
+	r.mux.HandleFunc("/metrics", r.handlePrometheusMetrics).Methods("GET")
+	
+	// Endpoints de supervisión de procesos externos
+	r.mux.HandleFunc("/api/processes", r.handleWatchProcess).Methods("POST")
+	r.mux.HandleFunc("/api/processes", r.handleListProcesses).Methods("GET")
+	r.mux.HandleFunc("/api/processes/{id}", r.handleGetProcess).Methods("GET")
+	r.mux.HandleFunc("/api/processes/{id}", r.handleUnwatchProcess).Methods("DELETE")
+	r.mux.HandleFunc("/api/processes/{id}/history", r.handleGetProcessHistory).Methods("GET")
+	r.mux.HandleFunc("/api/processes/{id}/stats", r.handleGetProcessStats).Methods("GET")
+	
+	// Endpoints de ejecución y medición de comandos
+	r.mux.HandleFunc("/api/runs", r.handleStartRun).Methods("POST")
+	r.mux.HandleFunc("/api/runs", r.handleListRuns).Methods("GET")
+	r.mux.HandleFunc("/api/runs/{id}", r.handleGetRun).Methods("GET")
 	
 	// Endpoints de perfilamiento
 	r.mux.HandleFunc("/api/profile/cpu", r.handleCPUProfile).Methods("GET")
+	r.mux.HandleFunc("/api/profile/cpu/start", r.handleStartCPUProfile).Methods("POST")
+	r.mux.HandleFunc("/api/profile/cpu/stop", r.handleStopCPUProfile).Methods("POST")
+	r.mux.HandleFunc("/api/profile/cpu/session", r.handleCPUProfileSession).Methods("GET")
 	r.mux.HandleFunc("/api/profile/heap", r.handleHeapProfile).Methods("GET")
 	r.mux.HandleFunc("/api/profile/goroutine", r.handleGoroutineProfile).Methods("GET")
 	r.mux.HandleFunc("/api/profile/block", r.handleBlockProfile).Methods("GET")
+	r.mux.HandleFunc("/api/profile/mutex", r.handleMutexProfile).Methods("GET")
+	r.mux.HandleFunc("/api/profile/allocs", r.handleAllocsProfile).Methods("GET")
+	r.mux.HandleFunc("/api/profile/threadcreate", r.handleThreadcreateProfile).Methods("GET")
+	r.mux.HandleFunc("/api/profile/rates", r.handleGetProfileRates).Methods("GET")
+	r.mux.HandleFunc("/api/profile/rates", r.handleSetProfileRates).Methods("PUT")
 	r.mux.HandleFunc("/api/profile/list", r.handleListProfiles).Methods("GET")
+	r.mux.HandleFunc("/api/profile/diff", r.handleDiffProfiles).Methods("GET")
+	r.mux.HandleFunc("/api/profile/merged", r.handleMergedProfiles).Methods("GET")
+	r.mux.HandleFunc("/api/profile/{id}", r.handleGetArchivedProfile).Methods("GET")
+	r.mux.HandleFunc("/api/profile/{id}/top", r.handleProfileTop).Methods("GET")
+	r.mux.HandleFunc("/api/profile/{id}/folded", r.handleProfileFolded).Methods("GET")
+	r.mux.HandleFunc("/api/profile/{id}/flamegraph.svg", r.handleProfileFlameGraph).Methods("GET")
+	r.mux.HandleFunc("/api/profile/{id}", r.handleDeleteArchivedProfile).Methods("DELETE")
+	
+	// Endpoints de servicios remotos con net/http/pprof
+	r.mux.HandleFunc("/api/targets", r.handleAddTarget).Methods("POST")
+	r.mux.HandleFunc("/api/targets", r.handleListTargets).Methods("GET")
+	r.mux.HandleFunc("/api/targets/{name}", r.handleGetTarget).Methods("GET")
+	r.mux.HandleFunc("/api/targets/{name}", r.handleRemoveTarget).Methods("DELETE")
+	r.mux.HandleFunc("/api/targets/{name}/profile/{type}", r.handleFetchTargetProfile).Methods("GET")
+	
+	// Endpoints de trazas de ejecución
+	r.mux.HandleFunc("/api/trace", r.handleCaptureTrace).Methods("GET")
+	r.mux.HandleFunc("/api/trace/{id}", r.handleTraceSummary).Methods("GET")
 	
 	// Endpoint de salud
 	r.mux.HandleFunc("/api/health", r.handleHealth).Methods("GET")
@@ -62,18 +125,70 @@ func (r *Router) handleGetMetrics(w http.ResponseWriter, req *http.Request) {
 	r.respondJSON(w, http.StatusOK, metrics)
 }
 
-// handleGetMetricsHistory retorna el historial de métricas
+// handleGetMetricsHistory retorna el historial de métricas, opcionalmente
+// filtrado por rango (from, to; por defecto la última hora), reducido por
+// intervalo (step, agg) y limitado (limit)
 func (r *Router) handleGetMetricsHistory(w http.ResponseWriter, req *http.Request) {
-	history := r.collector.GetMetricsHistory()
-	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+	query := req.URL.Query()
+
+	from, to, err := parseWindowedTimeRange(query, time.Now(), metrics.DefaultHistoryWindow)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	step, err := parseDurationParam(query, "step")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := parsePositiveIntParam(query, "limit")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	agg, err := metrics.ParseAggregation(query.Get("agg"))
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	history := r.collector.QueryMetricsHistory(metrics.HistoryQuery{
+		From:        from,
+		To:          to,
+		Step:        step,
+		Aggregation: agg,
+		Limit:       limit,
+	})
+
+	response := map[string]interface{}{
 		"count":   len(history),
 		"history": history,
-	})
+	}
+	if step > 0 {
+		response["step"] = step.String()
+		response["aggregation"] = agg
+	}
+	r.respondJSON(w, http.StatusOK, response)
 }
 
 // handleGetMetricsStats retorna estadísticas del historial de métricas
+// dentro de [from, to] (por defecto la última hora), con percentiles,
+// varianza muestral e histograma opcionales
 func (r *Router) handleGetMetricsStats(w http.ResponseWriter, req *http.Request) {
-	stats := r.collector.GetMetricsStats()
+	query := req.URL.Query()
+
+	from, to, err := parseWindowedTimeRange(query, time.Now(), metrics.DefaultHistoryWindow)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, err := parseStatsOptions(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats := r.collector.GetMetricsStats(from, to, opts)
 	if stats == nil {
 		r.respondError(w, http.StatusNotFound, "No hay métricas disponibles aún")
 		return
@@ -81,7 +196,20 @@ func (r *Router) handleGetMetricsStats(w http.ResponseWriter, req *http.Request)
 	r.respondJSON(w, http.StatusOK, stats)
 }
 
-// handleCPUProfile genera un perfil de CPU
+// handlePrometheusMetrics expone la última muestra en formato Prometheus u
+// OpenMetrics según la cabecera Accept
+func (r *Router) handlePrometheusMetrics(w http.ResponseWriter, req *http.Request) {
+	format := exposition.Negotiate(req.Header.Get("Accept"))
+	families := exposition.SystemFamilies(r.collector.GetCurrentMetrics())
+	
+	w.Header().Set("Content-Type", format.ContentType())
+	w.WriteHeader(http.StatusOK)
+	exposition.Write(w, families, format)
+}
+
+// handleCPUProfile genera un perfil de CPU. Si hay otro en curso responde
+// 409 con el tiempo estimado o, con wait=true, espera su turno. Si el
+// cliente se desconecta el perfil se detiene.
 func (r *Router) handleCPUProfile(w http.ResponseWriter, req *http.Request) {
 	seconds := 30 // Por defecto 30 segundos
 	if s := req.URL.Query().Get("seconds"); s != "" {
@@ -89,63 +217,107 @@ func (r *Router) handleCPUProfile(w http.ResponseWriter, req *http.Request) {
 			seconds = parsed
 		}
 	}
+	if req.URL.Query().Get("debug") != "" {
+		r.respondError(w, http.StatusBadRequest, "El perfil de CPU solo está disponible en formato protobuf")
+		return
+	}
+	labels, err := parseLabelParams(req.URL.Query())
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	wait, err := parseBoolParam(req.URL.Query(), "wait")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	
-	profile, err := r.profiler.GetCPUProfile(seconds)
+	profile, err := r.profiler.GetCPUProfile(req.Context(), seconds, labels, wait)
 	if err != nil {
-		r.respondError(w, http.StatusInternalServerError, err.Error())
+		if req.Context().Err() != nil {
+			// El cliente se desconectó: no hay a quién responder
+			return
+		}
+		r.respondCPUProfileError(w, err)
 		return
 	}
 	
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(profile.Data))
+	r.respondProfile(w, profile)
 }
 
 // handleHeapProfile genera un perfil de memoria heap
 func (r *Router) handleHeapProfile(w http.ResponseWriter, req *http.Request) {
-	profile, err := r.profiler.GetHeapProfile()
-	if err != nil {
-		r.respondError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(profile.Data))
+	r.handleSnapshotProfile(w, req, r.profiler.GetHeapProfile)
 }
 
 // handleGoroutineProfile genera un perfil de goroutines
 func (r *Router) handleGoroutineProfile(w http.ResponseWriter, req *http.Request) {
-	profile, err := r.profiler.GetGoroutineProfile()
-	if err != nil {
-		r.respondError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(profile.Data))
+	r.handleSnapshotProfile(w, req, r.profiler.GetGoroutineProfile)
 }
 
 // handleBlockProfile genera un perfil de bloqueos
 func (r *Router) handleBlockProfile(w http.ResponseWriter, req *http.Request) {
-	profile, err := r.profiler.GetBlockProfile()
+	r.handleSnapshotProfile(w, req, r.profiler.GetBlockProfile)
+}
+
+// handleMutexProfile genera un perfil de contención de mutex
+func (r *Router) handleMutexProfile(w http.ResponseWriter, req *http.Request) {
+	r.handleSnapshotProfile(w, req, r.profiler.GetMutexProfile)
+}
+
+// handleAllocsProfile genera un perfil de asignaciones de memoria
+func (r *Router) handleAllocsProfile(w http.ResponseWriter, req *http.Request) {
+	r.handleSnapshotProfile(w, req, r.profiler.GetAllocsProfile)
+}
+
+// handleThreadcreateProfile genera un perfil de creación de hilos
+func (r *Router) handleThreadcreateProfile(w http.ResponseWriter, req *http.Request) {
+	r.handleSnapshotProfile(w, req, r.profiler.GetThreadcreateProfile)
+}
+
+// handleSnapshotProfile genera un perfil instantáneo en el formato pedido con debug
+func (r *Router) handleSnapshotProfile(w http.ResponseWriter, req *http.Request, get func(debug int, labels map[string]string) (*profiler.ProfileData, error)) {
+	debug, err := parseDebugParam(req.URL.Query())
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	labels, err := parseLabelParams(req.URL.Query())
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	profile, err := get(debug, labels)
 	if err != nil {
 		r.respondError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(profile.Data))
+	r.respondProfile(w, profile)
 }
 
-// handleListProfiles lista los perfiles disponibles
-func (r *Router) handleListProfiles(w http.ResponseWriter, req *http.Request) {
-	profiles := r.profiler.ListProfiles()
-	r.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"profiles": profiles,
-	})
+// respondProfile envía un perfil: el protobuf de pprof como descarga binaria
+// (compatible con go tool pprof) o el formato de texto si se pidió con debug
+func (r *Router) respondProfile(w http.ResponseWriter, profile *profiler.ProfileData) {
+	if profile.IsText() {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		ext := ".pb.gz"
+		if profile.Name == profiler.TraceName {
+			ext = ".trace" // Se abre con go tool trace
+		}
+		filename := fmt.Sprintf("%s-%s%s", profile.Name, profile.Timestamp.UTC().Format("20060102T150405Z"), ext)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	if profile.ID != "" {
+		w.Header().Set("X-Profile-ID", profile.ID)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Length", strconv.Itoa(len(profile.Data)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(profile.Data)
 }
 
 // handleHealth retorna el estado de salud de la API
@@ -165,12 +337,39 @@ func (r *Router) handleRoot(w http.ResponseWriter, req *http.Request) {
 		"description": "API para recolectar y analizar métricas de rendimiento de aplicaciones",
 		"endpoints": map[string]string{
 			"metrics":        "/api/metrics",
-			"metrics_history": "/api/metrics/history",
-			"metrics_stats":  "/api/metrics/stats",
+			"metrics_history": "/api/metrics/history?from=-15m&to=now&step=1m&agg=avg&limit=100",
+			"metrics_stream": "/api/metrics/stream",
+			"websocket":      "/api/ws",
+			"metrics_stats":  "/api/metrics/stats?percentiles=true&variance=true&histogram=10",
+			"metrics_anomalies": "/api/metrics/anomalies?from=-1h&methods=zscore,ewma,mad",
+			"prometheus":     "/metrics",
+			"alerts":         "/api/alerts",
+			"alert_rules":    "/api/alerts/rules",
+			"alert_history":  "/api/alerts/history?from=-24h",
+			"alert_sinks":    "/api/alerts/sinks",
+			"processes":      "/api/processes",
+			"runs":           "/api/runs",
 			"cpu_profile":    "/api/profile/cpu?seconds=30",
+			"cpu_profile_start": "POST /api/profile/cpu/start",
+			"cpu_profile_stop":  "POST /api/profile/cpu/stop",
+			"cpu_profile_session": "/api/profile/cpu/session",
 			"heap_profile":   "/api/profile/heap",
 			"goroutine_profile": "/api/profile/goroutine",
 			"block_profile":  "/api/profile/block",
+			"mutex_profile":  "/api/profile/mutex",
+			"allocs_profile": "/api/profile/allocs",
+			"threadcreate_profile": "/api/profile/threadcreate",
+			"profile_rates":  "/api/profile/rates",
+			"profiles":       "/api/profile/list",
+			"profile_diff":   "/api/profile/diff?base=ID&target=ID",
+			"profile_merged": "/api/profile/merged?type=cpu&from=-1h",
+			"profile_top":    "/api/profile/{id}/top?n=20&sort=flat",
+			"profile_folded": "/api/profile/{id}/folded",
+			"flamegraph":     "/api/profile/{id}/flamegraph.svg?icicle=false",
+			"targets":        "/api/targets",
+			"target_profile": "/api/targets/{name}/profile/{type}?seconds=10",
+			"trace":          "/api/trace?seconds=5",
+			"trace_summary":  "/api/trace/{id}",
 			"health":         "/api/health",
 		},
 	}
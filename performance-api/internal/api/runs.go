@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"performance-api/internal/runner"
+
+	"github.com/gorilla/mux"
+)
+
+// handleStartRun lanza un comando permitido y mide su consumo. Con
+// wait=true la respuesta se envía cuando el comando termina.
+func (r *Router) handleStartRun(w http.ResponseWriter, req *http.Request) {
+	var runReq runner.Request
+	if err := json.NewDecoder(req.Body).Decode(&runReq); err != nil {
+		r.respondError(w, http.StatusBadRequest, "Cuerpo JSON inválido: "+err.Error())
+		return
+	}
+
+	run, done, err := r.runner.Start(runReq)
+	if err != nil {
+		r.respondRunError(w, err)
+		return
+	}
+
+	if req.URL.Query().Get("wait") != "true" {
+		w.Header().Set("Location", "/api/runs/"+run.ID)
+		r.respondJSON(w, http.StatusAccepted, run)
+		return
+	}
+
+	select {
+	case <-done:
+	case <-req.Context().Done():
+		// El cliente se desconectó; la ejecución continúa y puede consultarse después
+		return
+	}
+	if run, err = r.runner.Get(run.ID); err != nil {
+		r.respondRunError(w, err)
+		return
+	}
+	r.respondJSON(w, http.StatusCreated, run)
+}
+
+// handleListRuns lista las ejecuciones guardadas y los comandos permitidos
+func (r *Router) handleListRuns(w http.ResponseWriter, req *http.Request) {
+	runs := r.runner.List()
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"count":    len(runs),
+		"commands": r.runner.Commands(),
+		"runs":     runs,
+	})
+}
+
+// handleGetRun retorna el registro completo de una ejecución
+func (r *Router) handleGetRun(w http.ResponseWriter, req *http.Request) {
+	run, err := r.runner.Get(mux.Vars(req)["id"])
+	if err != nil {
+		r.respondRunError(w, err)
+		return
+	}
+	r.respondJSON(w, http.StatusOK, run)
+}
+
+// respondRunError traduce los errores del ejecutor a respuestas HTTP
+func (r *Router) respondRunError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, runner.ErrRunNotFound):
+		r.respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, runner.ErrCommandNotAllowed), errors.Is(err, runner.ErrArgNotAllowed):
+		r.respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, runner.ErrTooManyRuns):
+		r.respondError(w, http.StatusTooManyRequests, err.Error())
+	default:
+		r.respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"performance-api/internal/metrics"
+	"strconv"
+	"time"
+)
+
+// Parámetros del stream de eventos
+const (
+	streamHeartbeat  = 15 * time.Second // Intervalo de comentarios para mantener viva la conexión
+	streamRetry      = 3 * time.Second  // Espera sugerida al cliente antes de reconectarse
+	streamBufferSize = 16               // Muestras pendientes por cliente antes de descartar
+)
+
+// handleMetricsStream envía cada nueva muestra como un evento SSE. El ID de
+// cada evento es el timestamp de la muestra en nanosegundos; al reconectarse
+// con Last-Event-ID se reenvían primero las muestras posteriores del historial.
+func (r *Router) handleMetricsStream(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		r.respondError(w, http.StatusInternalServerError, "El servidor no soporta streaming")
+		return
+	}
+
+	lastID := req.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		// EventSource no permite cabeceras propias en la primera conexión
+		lastID = req.URL.Query().Get("last_event_id")
+	}
+	var resumeFrom int64
+	if lastID != "" {
+		id, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil {
+			r.respondError(w, http.StatusBadRequest, fmt.Sprintf("Last-Event-ID inválido %q", lastID))
+			return
+		}
+		resumeFrom = id
+	}
+
+	// Suscribirse antes de leer el historial para no perder muestras intermedias
+	samples, unsubscribe := r.collector.Subscribe(streamBufferSize)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+	lastSent := resumeFrom
+	if resumeFrom > 0 {
+		// Se reenvía como mucho la última DefaultHistoryWindow
+		since := time.Unix(0, resumeFrom+1)
+		if oldest := time.Now().Add(-metrics.DefaultHistoryWindow); since.Before(oldest) {
+			since = oldest
+		}
+		for _, m := range r.collector.GetMetricsRange(since, time.Time{}) {
+			if err := writeMetricsEvent(w, m); err != nil {
+				return
+			}
+			lastSent = m.Timestamp.UnixNano()
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case m, ok := <-samples:
+			if !ok {
+				// El recolector se detuvo
+				return
+			}
+			if m.Timestamp.UnixNano() <= lastSent {
+				// Ya enviada durante la reanudación desde el historial
+				continue
+			}
+			if err := writeMetricsEvent(w, m); err != nil {
+				return
+			}
+			lastSent = m.Timestamp.UnixNano()
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeMetricsEvent escribe una muestra como evento SSE "metrics"
+func writeMetricsEvent(w http.ResponseWriter, m metrics.SystemMetrics) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: metrics\ndata: %s\n\n", m.Timestamp.UnixNano(), data)
+	return err
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"performance-api/internal/profiler"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// targetRequest es el cuerpo de la solicitud de registro de un objetivo remoto
+type targetRequest struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`
+	Types       []string `json:"types,omitempty"`
+	Interval    string   `json:"interval,omitempty"`     // Duración como "5m"; vacío = solo bajo demanda
+	CPUDuration string   `json:"cpu_duration,omitempty"` // Duración como "10s"
+}
+
+// handleAddTarget registra un servicio remoto que expone net/http/pprof
+func (r *Router) handleAddTarget(w http.ResponseWriter, req *http.Request) {
+	var body targetRequest
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		r.respondError(w, http.StatusBadRequest, "Cuerpo JSON inválido: "+err.Error())
+		return
+	}
+
+	opts := profiler.TargetOptions{Name: body.Name, URL: body.URL, Types: body.Types}
+	var err error
+	if opts.Interval, err = parseBodyDuration("interval", body.Interval); err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.CPUDuration, err = parseBodyDuration("cpu_duration", body.CPUDuration); err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := r.profiler.Targets().CheckHost(opts.URL); err != nil {
+		r.respondTargetError(w, err)
+		return
+	}
+	info, err := r.profiler.Targets().Add(opts)
+	if err != nil {
+		r.respondTargetError(w, err)
+		return
+	}
+	r.respondJSON(w, http.StatusCreated, info)
+}
+
+// handleListTargets lista los servicios remotos registrados
+func (r *Router) handleListTargets(w http.ResponseWriter, req *http.Request) {
+	targets := r.profiler.Targets().List()
+	r.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"count":   len(targets),
+		"targets": targets,
+	})
+}
+
+// handleGetTarget retorna el estado de un servicio remoto
+func (r *Router) handleGetTarget(w http.ResponseWriter, req *http.Request) {
+	info, err := r.profiler.Targets().Get(mux.Vars(req)["name"])
+	if err != nil {
+		r.respondTargetError(w, err)
+		return
+	}
+	r.respondJSON(w, http.StatusOK, info)
+}
+
+// handleRemoveTarget elimina un servicio remoto y detiene sus capturas programadas
+func (r *Router) handleRemoveTarget(w http.ResponseWriter, req *http.Request) {
+	if err := r.profiler.Targets().Remove(mux.Vars(req)["name"]); err != nil {
+		r.respondTargetError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleFetchTargetProfile obtiene un perfil de un servicio remoto, lo
+// archiva con la etiqueta target y lo descarga
+func (r *Router) handleFetchTargetProfile(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	vars := mux.Vars(req)
+
+	seconds := 0 // La duración del objetivo
+	if s := query.Get("seconds"); s != "" {
+		parsed, err := strconv.Atoi(s)
+		if err != nil || parsed <= 0 {
+			r.respondError(w, http.StatusBadRequest, fmt.Sprintf("Parámetro seconds inválido %q", s))
+			return
+		}
+		seconds = parsed
+	}
+	labels, err := parseLabelParams(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	profile, err := r.profiler.Targets().Fetch(req.Context(), vars["name"], vars["type"], seconds, labels)
+	if err != nil {
+		if req.Context().Err() != nil {
+			// El cliente se desconectó: no hay a quién responder
+			return
+		}
+		r.respondTargetError(w, err)
+		return
+	}
+	r.respondProfile(w, profile)
+}
+
+// parseBodyDuration interpreta una duración del cuerpo de una solicitud (vacío retorna 0)
+func parseBodyDuration(name, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("campo %s inválido %q (usa duraciones como 30s o 5m)", name, value)
+	}
+	return d, nil
+}
+
+// respondTargetError traduce los errores de los objetivos remotos a respuestas HTTP
+func (r *Router) respondTargetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, profiler.ErrTargetNotFound):
+		r.respondError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, profiler.ErrTargetExists):
+		r.respondError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, profiler.ErrTargetNotAllowed):
+		r.respondError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, profiler.ErrRemoteFetch):
+		r.respondError(w, http.StatusBadGateway, err.Error())
+	default:
+		r.respondProfileError(w, err)
+	}
+}
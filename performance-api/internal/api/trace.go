@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"performance-api/internal/profiler"
+
+	"github.com/gorilla/mux"
+)
+
+// Duración de las trazas de ejecución
+const (
+	defaultTraceSeconds = 5
+	maxTraceSeconds     = 60
+)
+
+// handleCaptureTrace captura una traza de ejecución durante seconds, la
+// archiva y retorna su resumen; con format=trace descarga la traza binaria
+// para abrirla con go tool trace
+func (r *Router) handleCaptureTrace(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+
+	seconds, err := parsePositiveIntParam(query, "seconds")
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if seconds == 0 {
+		seconds = defaultTraceSeconds
+	}
+	if seconds > maxTraceSeconds {
+		r.respondError(w, http.StatusBadRequest, fmt.Sprintf("La duración máxima de una traza es %d segundos", maxTraceSeconds))
+		return
+	}
+	format, err := parseTraceFormat(query.Get("format"))
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	labels, err := parseLabelParams(query)
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := r.profiler.GetTrace(req.Context(), seconds, labels)
+	if err != nil {
+		if req.Context().Err() != nil {
+			// El cliente se desconectó: no hay a quién responder
+			return
+		}
+		r.respondTraceError(w, err)
+		return
+	}
+	r.respondTrace(w, data, format)
+}
+
+// handleTraceSummary retorna el resumen de una traza archivada (por ID, o
+// "trace" para la más reciente); con format=trace descarga la traza binaria
+func (r *Router) handleTraceSummary(w http.ResponseWriter, req *http.Request) {
+	format, err := parseTraceFormat(req.URL.Query().Get("format"))
+	if err != nil {
+		r.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := r.profiler.Archive().Lookup(mux.Vars(req)["id"])
+	if err != nil {
+		r.respondProfileError(w, err)
+		return
+	}
+	r.respondTrace(w, data, format)
+}
+
+// respondTrace envía una traza como resumen JSON o como descarga binaria
+func (r *Router) respondTrace(w http.ResponseWriter, data *profiler.ProfileData, format string) {
+	if data.Name != profiler.TraceName {
+		r.respondError(w, http.StatusBadRequest, fmt.Sprintf("%s no es una traza de ejecución", data.ID))
+		return
+	}
+	if format == "trace" {
+		r.respondProfile(w, data)
+		return
+	}
+
+	summary, err := profiler.SummarizeTrace(data)
+	if err != nil {
+		r.respondTraceError(w, err)
+		return
+	}
+	w.Header().Set("X-Profile-ID", data.ID)
+	r.respondJSON(w, http.StatusOK, summary)
+}
+
+// parseTraceFormat interpreta el formato de respuesta de una traza: json o trace
+func parseTraceFormat(format string) (string, error) {
+	switch format {
+	case "", "json":
+		return "json", nil
+	case "trace":
+		return format, nil
+	}
+	return "", fmt.Errorf("Formato inválido %q (usa json o trace)", format)
+}
+
+// respondTraceError traduce los errores de las trazas a respuestas HTTP
+func (r *Router) respondTraceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, profiler.ErrTraceInProgress) {
+		r.respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+	r.respondProfileError(w, err)
+}
@@ -0,0 +1,490 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"performance-api/internal/metrics"
+	"performance-api/internal/profiler"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Familias de métricas a las que puede suscribirse un cliente WebSocket
+const (
+	familyCPU     = "cpu"
+	familyMemory  = "memory"
+	familyDisk    = "disk"
+	familyNetwork = "network"
+	familyRuntime = "runtime"
+	familyProcess = "process"
+)
+
+// wsFamilies es el conjunto de familias válidas
+var wsFamilies = map[string]bool{
+	familyCPU:     true,
+	familyMemory:  true,
+	familyDisk:    true,
+	familyNetwork: true,
+	familyRuntime: true,
+	familyProcess: true,
+}
+
+// Parámetros de las conexiones WebSocket
+const (
+	wsSendBuffer      = 8                // Mensajes pendientes por cliente antes de descartar muestras
+	wsWriteTimeout    = 10 * time.Second // Tiempo máximo para escribir un mensaje
+	wsPongTimeout     = 60 * time.Second // Tiempo sin respuesta antes de cerrar la conexión
+	wsPingInterval    = 25 * time.Second // Debe ser menor que wsPongTimeout
+	wsMinInterval     = 100 * time.Millisecond
+	wsMaxInterval     = time.Hour
+	wsMaxProfileSecs  = 60
+	wsProfileInterval = 2 * time.Second // Tiempo mínimo entre perfiles de una conexión
+	wsMaxMessageBytes = 64 << 10
+)
+
+// wsProfileLabels identifica en el archivo los perfiles pedidos por WebSocket
+var wsProfileLabels = map[string]string{"source": "websocket"}
+
+// wsUpgrader convierte las solicitudes HTTP en conexiones WebSocket
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// wsRequest es un mensaje del cliente. Tipos: subscribe, unsubscribe, rate y profile.
+type wsRequest struct {
+	Type      string   `json:"type"`
+	ID        string   `json:"id,omitempty"`        // Identificador opcional que se repite en la respuesta
+	Families  []string `json:"families,omitempty"`  // subscribe/unsubscribe
+	Processes []string `json:"processes,omitempty"` // subscribe: IDs de supervisión (vacío = todos)
+	Interval  string   `json:"interval,omitempty"`  // rate: intervalo mínimo entre envíos ("0" = cada muestra)
+	Profile   string   `json:"profile,omitempty"`   // profile: cpu, heap, goroutine, block, mutex, allocs o threadcreate
+	Seconds   int      `json:"seconds,omitempty"`   // profile: duración del perfil de CPU
+	Debug     int      `json:"debug,omitempty"`     // profile: 1 o 2 para el formato de texto
+}
+
+// wsState describe la configuración actual de un cliente
+type wsState struct {
+	Type      string   `json:"type"`
+	ID        string   `json:"id,omitempty"`
+	Families  []string `json:"families"`
+	Processes []string `json:"processes,omitempty"`
+	Interval  string   `json:"interval"`
+	Available []string `json:"available"`
+}
+
+// wsMetricsFrame contiene las familias suscritas de una muestra
+type wsMetricsFrame struct {
+	Type       string                     `json:"type"`
+	Timestamp  time.Time                  `json:"timestamp"`
+	Dropped    uint64                     `json:"dropped,omitempty"` // Muestras descartadas desde el envío anterior
+	CPU        *metrics.CPUInfo           `json:"cpu,omitempty"`
+	Memory     *metrics.MemoryInfo        `json:"memory,omitempty"`
+	Disk       *metrics.DiskInfo          `json:"disk,omitempty"`
+	Network    *metrics.NetworkInfo       `json:"network,omitempty"`
+	Runtime    *metrics.RuntimeInfo       `json:"runtime,omitempty"`
+	Goroutines *int                       `json:"goroutines,omitempty"`
+	Processes  []metrics.ProcessWatchInfo `json:"processes,omitempty"`
+}
+
+// wsProfileFrame contiene un perfil solicitado por el cliente
+type wsProfileFrame struct {
+	Type    string                `json:"type"`
+	ID      string                `json:"id,omitempty"`
+	Profile *profiler.ProfileData `json:"profile"`
+}
+
+// wsErrorFrame informa un error al cliente sin cerrar la conexión
+type wsErrorFrame struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// wsClient es el estado de una conexión WebSocket
+type wsClient struct {
+	router *Router
+	conn   *websocket.Conn
+	send   chan interface{}
+	done   chan struct{}
+	once   sync.Once
+
+	mu          sync.Mutex
+	families    map[string]bool
+	processes   map[string]bool
+	interval    time.Duration
+	rate        chan struct{} // Avisa al ciclo de envío que cambió el intervalo
+	dropped     uint64
+	capturing   bool      // Hay un perfil en curso para esta conexión
+	lastCapture time.Time // Inicio del último perfil
+}
+
+// handleWebSocket atiende una conexión WebSocket bidireccional: el cliente
+// elige familias de métricas y frecuencia de envío, y puede pedir perfiles
+func (r *Router) handleWebSocket(w http.ResponseWriter, req *http.Request) {
+	conn, err := wsUpgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// Upgrade ya respondió al cliente con el error
+		return
+	}
+
+	client := &wsClient{
+		router:    r,
+		conn:      conn,
+		send:      make(chan interface{}, wsSendBuffer),
+		done:      make(chan struct{}),
+		families:  make(map[string]bool),
+		processes: make(map[string]bool),
+		rate:      make(chan struct{}, 1),
+	}
+
+	samples, unsubscribe := r.collector.Subscribe(wsSendBuffer)
+	defer unsubscribe()
+
+	go client.writeLoop()
+	go client.readLoop()
+
+	client.reply(client.state(""))
+	client.pushLoop(samples)
+	conn.Close()
+}
+
+// close marca la conexión como terminada
+func (c *wsClient) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+// readLoop procesa los mensajes del cliente hasta que la conexión se cierra
+func (c *wsClient) readLoop() {
+	defer c.close()
+
+	c.conn.SetReadLimit(wsMaxMessageBytes)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg wsRequest
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reply(wsErrorFrame{Type: "error", Error: "Mensaje JSON inválido: " + err.Error()})
+			continue
+		}
+		c.handle(msg)
+	}
+}
+
+// writeLoop es el único escritor de la conexión; también envía pings periódicos
+func (c *wsClient) writeLoop() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	defer c.close()
+
+	for {
+		select {
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(wsWriteTimeout))
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// pushLoop envía las muestras nuevas respetando la frecuencia elegida por el
+// cliente: a lo sumo una por intervalo, siempre la más reciente
+func (c *wsClient) pushLoop(samples <-chan metrics.SystemMetrics) {
+	var pending *metrics.SystemMetrics
+	var lastPush time.Time
+	timer := time.NewTimer(time.Hour)
+	stopTimer(timer)
+	defer timer.Stop()
+
+	send := func(m metrics.SystemMetrics) {
+		c.push(m)
+		lastPush = time.Now()
+		pending = nil
+		stopTimer(timer)
+	}
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case m, ok := <-samples:
+			if !ok {
+				// El recolector se detuvo
+				c.close()
+				return
+			}
+			interval := c.currentInterval()
+			if interval == 0 || time.Since(lastPush) >= interval {
+				send(m)
+				continue
+			}
+			if pending == nil {
+				timer.Reset(time.Until(lastPush.Add(interval)))
+			}
+			pending = &m
+		case <-c.rate:
+			// Con el nuevo intervalo la muestra pendiente puede enviarse antes
+			if pending != nil {
+				stopTimer(timer)
+				timer.Reset(time.Until(lastPush.Add(c.currentInterval())))
+			}
+		case <-timer.C:
+			if pending != nil {
+				send(*pending)
+			}
+		}
+	}
+}
+
+// stopTimer detiene un timer y descarta un disparo pendiente en su canal
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
+// push arma el mensaje con las familias suscritas y lo encola sin bloquear.
+// Si el cliente no consume a tiempo la muestra se descarta y se cuenta.
+func (c *wsClient) push(m metrics.SystemMetrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.families) == 0 {
+		return
+	}
+
+	frame := wsMetricsFrame{Type: "metrics", Timestamp: m.Timestamp, Dropped: c.dropped}
+	if c.families[familyCPU] {
+		frame.CPU = &m.CPU
+	}
+	if c.families[familyMemory] {
+		frame.Memory = &m.Memory
+	}
+	if c.families[familyDisk] {
+		frame.Disk = &m.Disk
+	}
+	if c.families[familyNetwork] {
+		frame.Network = &m.Network
+	}
+	if c.families[familyRuntime] {
+		frame.Runtime = &m.Runtime
+		frame.Goroutines = &m.Goroutines
+	}
+	if c.families[familyProcess] {
+		frame.Processes = []metrics.ProcessWatchInfo{}
+		for _, watch := range c.router.watcher.ListWatches() {
+			if len(c.processes) == 0 || c.processes[watch.ID] {
+				frame.Processes = append(frame.Processes, watch)
+			}
+		}
+	}
+
+	select {
+	case c.send <- frame:
+		c.dropped = 0
+	default:
+		c.dropped++
+	}
+}
+
+// reply encola una respuesta a un mensaje del cliente. A diferencia de las
+// muestras, las respuestas no se descartan: se espera hasta poder encolarlas.
+func (c *wsClient) reply(msg interface{}) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	}
+}
+
+// handle procesa un mensaje del cliente
+func (c *wsClient) handle(msg wsRequest) {
+	switch msg.Type {
+	case "subscribe", "unsubscribe":
+		for _, family := range msg.Families {
+			if !wsFamilies[family] {
+				c.reply(wsErrorFrame{Type: "error", ID: msg.ID, Error: fmt.Sprintf("familia desconocida %q", family)})
+				return
+			}
+		}
+		c.mu.Lock()
+		for _, family := range msg.Families {
+			if msg.Type == "subscribe" {
+				c.families[family] = true
+			} else {
+				delete(c.families, family)
+			}
+		}
+		if msg.Type == "subscribe" && msg.Processes != nil {
+			c.processes = make(map[string]bool, len(msg.Processes))
+			for _, id := range msg.Processes {
+				c.processes[id] = true
+			}
+		}
+		c.mu.Unlock()
+		c.reply(c.state(msg.ID))
+
+	case "rate":
+		interval, err := time.ParseDuration(msg.Interval)
+		if err != nil || (interval != 0 && (interval < wsMinInterval || interval > wsMaxInterval)) {
+			c.reply(wsErrorFrame{Type: "error", ID: msg.ID,
+				Error: fmt.Sprintf("intervalo inválido %q (usa 0 o una duración entre %s y %s)", msg.Interval, wsMinInterval, wsMaxInterval)})
+			return
+		}
+		c.mu.Lock()
+		c.interval = interval
+		c.mu.Unlock()
+		select {
+		case c.rate <- struct{}{}:
+		default:
+		}
+		c.reply(c.state(msg.ID))
+
+	case "profile":
+		// Un perfil a la vez por conexión y con un intervalo mínimo entre ellos
+		c.mu.Lock()
+		var busy string
+		switch {
+		case c.capturing:
+			busy = "ya hay un perfil en curso en esta conexión"
+		case time.Since(c.lastCapture) < wsProfileInterval:
+			busy = fmt.Sprintf("espera al menos %s entre perfiles", wsProfileInterval)
+		default:
+			c.capturing = true
+			c.lastCapture = time.Now()
+		}
+		c.mu.Unlock()
+		if busy != "" {
+			c.reply(wsErrorFrame{Type: "error", ID: msg.ID, Error: busy})
+			return
+		}
+
+		// El perfil de CPU tarda varios segundos: no bloquear la lectura
+		go func() {
+			defer func() {
+				c.mu.Lock()
+				c.capturing = false
+				c.mu.Unlock()
+			}()
+			c.captureProfile(msg)
+		}()
+
+	default:
+		c.reply(wsErrorFrame{Type: "error", ID: msg.ID, Error: fmt.Sprintf("tipo de mensaje desconocido %q", msg.Type)})
+	}
+}
+
+// captureProfile obtiene el perfil solicitado y lo envía al cliente
+func (c *wsClient) captureProfile(msg wsRequest) {
+	var profile *profiler.ProfileData
+	var err error
+
+	if msg.Debug < 0 || msg.Debug > 2 || (msg.Debug > 0 && msg.Profile == "cpu") {
+		c.reply(wsErrorFrame{Type: "error", ID: msg.ID, Error: fmt.Sprintf("debug inválido %d para el perfil %q", msg.Debug, msg.Profile)})
+		return
+	}
+
+	p := c.router.profiler
+	switch msg.Profile {
+	case "cpu":
+		seconds := msg.Seconds
+		if seconds <= 0 {
+			seconds = 5
+		}
+		if seconds > wsMaxProfileSecs {
+			c.reply(wsErrorFrame{Type: "error", ID: msg.ID, Error: fmt.Sprintf("la duración máxima del perfil de CPU es %d segundos", wsMaxProfileSecs)})
+			return
+		}
+		// Detener el perfil si el cliente se desconecta antes de que termine
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-c.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		profile, err = p.GetCPUProfile(ctx, seconds, wsProfileLabels, false)
+		cancel()
+	case "heap":
+		profile, err = p.GetHeapProfile(msg.Debug, wsProfileLabels)
+	case "goroutine":
+		profile, err = p.GetGoroutineProfile(msg.Debug, wsProfileLabels)
+	case "block":
+		profile, err = p.GetBlockProfile(msg.Debug, wsProfileLabels)
+	case "mutex":
+		profile, err = p.GetMutexProfile(msg.Debug, wsProfileLabels)
+	case "allocs":
+		profile, err = p.GetAllocsProfile(msg.Debug, wsProfileLabels)
+	case "threadcreate":
+		profile, err = p.GetThreadcreateProfile(msg.Debug, wsProfileLabels)
+	default:
+		err = fmt.Errorf("perfil desconocido %q (usa cpu, heap, goroutine, block, mutex, allocs o threadcreate)", msg.Profile)
+	}
+
+	if err != nil {
+		log.Printf("Error al generar el perfil %s solicitado por WebSocket: %v", msg.Profile, err)
+		c.reply(wsErrorFrame{Type: "error", ID: msg.ID, Error: err.Error()})
+		return
+	}
+	c.reply(wsProfileFrame{Type: "profile", ID: msg.ID, Profile: profile})
+}
+
+// currentInterval retorna el intervalo mínimo entre envíos del cliente
+func (c *wsClient) currentInterval() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.interval
+}
+
+// state retorna la configuración actual del cliente
+func (c *wsClient) state(id string) wsState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := wsState{
+		Type:      "state",
+		ID:        id,
+		Families:  make([]string, 0, len(c.families)),
+		Interval:  c.interval.String(),
+		Available: make([]string, 0, len(wsFamilies)),
+	}
+	for family := range c.families {
+		state.Families = append(state.Families, family)
+	}
+	for id := range c.processes {
+		state.Processes = append(state.Processes, id)
+	}
+	for family := range wsFamilies {
+		state.Available = append(state.Available, family)
+	}
+	sort.Strings(state.Families)
+	sort.Strings(state.Processes)
+	sort.Strings(state.Available)
+	return state
+}
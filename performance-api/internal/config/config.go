@@ -0,0 +1,287 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Config contiene la configuración de la API
+type Config struct {
+	Port               string         `json:"port"`
+	CollectionInterval Duration       `json:"collection_interval"`
+	Storage            StorageConfig  `json:"storage"`
+	Processes          ProcessConfig  `json:"processes"`
+	Runs               RunsConfig     `json:"runs"`
+	Profiles           ProfilesConfig `json:"profiles"`
+	Alerts             AlertsConfig   `json:"alerts"`
+}
+
+// StorageConfig configura el almacenamiento del historial de métricas
+type StorageConfig struct {
+	Backend         string   `json:"backend"`           // "segment" (en disco) o "memory"
+	Dir             string   `json:"dir"`               // Directorio de los segmentos
+	MaxSegmentBytes int64    `json:"max_segment_bytes"` // Tamaño máximo de un segmento antes de rotarlo
+	SegmentDuration Duration `json:"segment_duration"`  // Tiempo máximo que cubre un segmento
+	Retention       Duration `json:"retention"`         // Antigüedad máxima de los datos guardados
+	MaxHistory      int      `json:"max_history"`       // Capacidad del backend en memoria
+}
+
+// ProcessConfig configura la supervisión de procesos externos
+type ProcessConfig struct {
+	MaxHistory int `json:"max_history"` // Muestras conservadas por proceso
+}
+
+// ProfilesConfig configura el archivo de perfiles capturados
+type ProfilesConfig struct {
+	Dir       string   `json:"dir"`       // Directorio de los perfiles (vacío = solo en memoria)
+	MaxBytes  int64    `json:"max_bytes"` // Tamaño total máximo del archivo
+	Retention Duration `json:"retention"` // Antigüedad máxima de los perfiles
+
+	BlockProfileRate     int `json:"block_profile_rate"`     // Nanosegundos de bloqueo por muestra (0 = desactivado)
+	MutexProfileFraction int `json:"mutex_profile_fraction"` // Se registra 1 de cada N contenciones (0 = desactivado)
+	MemProfileRate       int `json:"mem_profile_rate"`       // Bytes asignados por muestra (0 = desactivado)
+
+	Continuous ContinuousConfig `json:"continuous"`
+	Triggers   TriggersConfig   `json:"triggers"`
+	Targets    []TargetConfig   `json:"targets"` // Servicios remotos con net/http/pprof
+
+	// TargetHosts son los hosts ("host" o "host:puerto") a los que POST
+	// /api/targets puede apuntar; vacío = solo objetivos de la configuración
+	TargetHosts []string `json:"target_hosts"`
+}
+
+// TargetConfig describe un servicio de Go del que se obtienen perfiles remotos
+type TargetConfig struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url"`             // URL base, por ejemplo http://localhost:6060
+	Types       []string `json:"types,omitempty"` // Tipos capturados (por defecto cpu, heap y goroutine)
+	Interval    Duration `json:"interval"`        // Frecuencia de captura (0 = solo bajo demanda)
+	CPUDuration Duration `json:"cpu_duration"`    // Duración del perfil de CPU (por defecto 10s)
+}
+
+// ContinuousConfig configura el perfilamiento continuo en segundo plano
+type ContinuousConfig struct {
+	Enabled     bool     `json:"enabled"`
+	Interval    Duration `json:"interval"`     // Frecuencia de captura
+	CPUDuration Duration `json:"cpu_duration"` // Duración de cada perfil de CPU
+	Types       []string `json:"types"`        // Tipos de perfil capturados en cada ronda
+	Retention   Duration `json:"retention"`    // Antigüedad máxima de los perfiles continuos
+}
+
+// TriggersConfig configura la captura automática de perfiles cuando una
+// muestra del recolector supera un umbral (0 = regla desactivada)
+type TriggersConfig struct {
+	Enabled           bool     `json:"enabled"`
+	CPUPercent        float64  `json:"cpu_percent"`         // Uso de CPU del sistema (%)
+	Goroutines        int      `json:"goroutines"`          // Cantidad de goroutines
+	HeapGrowthPercent float64  `json:"heap_growth_percent"` // Crecimiento del heap entre muestras (%)
+	Cooldown          Duration `json:"cooldown"`            // Tiempo mínimo entre capturas de una regla
+	CPUDuration       Duration `json:"cpu_duration"`        // Duración del perfil de CPU
+	Types             []string `json:"types"`               // Tipos de perfil capturados
+}
+
+// AlertsConfig configura las reglas de alerta evaluadas con cada muestra
+type AlertsConfig struct {
+	Rules         []AlertRule         `json:"rules"`
+	HistorySize   int                 `json:"history_size"` // Transiciones de estado conservadas
+	Notifications NotificationsConfig `json:"notifications"`
+}
+
+// NotificationsConfig configura la entrega de alertas disparadas y resueltas
+type NotificationsConfig struct {
+	GroupWait      Duration    `json:"group_wait"`      // Espera para reunir alertas en una notificación
+	GroupBy        []string    `json:"group_by"`        // rule y/o severity (vacío = un solo grupo)
+	MaxRetries     int         `json:"max_retries"`     // Reintentos de una entrega fallida
+	InitialBackoff Duration    `json:"initial_backoff"` // Espera antes del primer reintento
+	MaxBackoff     Duration    `json:"max_backoff"`     // Espera máxima entre reintentos
+	Timeout        Duration    `json:"timeout"`         // Tiempo máximo de cada intento
+	ResendInterval Duration    `json:"resend_interval"` // Reenvío de las alertas disparadas a Alertmanager
+	Sinks          []AlertSink `json:"sinks"`
+}
+
+// AlertSink describe un destino de notificaciones: webhook, slack,
+// alertmanager (con url) o file (con path)
+type AlertSink struct {
+	Name    string            `json:"name"`
+	Type    string            `json:"type"`
+	URL     string            `json:"url,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// AlertRule describe una regla de alerta, por ejemplo "cpu.percent > 90 for 2m"
+// o "memory.used_percent avg_over_time(5m) > 80"
+type AlertRule struct {
+	Name        string `json:"name"`
+	Expr        string `json:"expr"`
+	Severity    string `json:"severity,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// RunsConfig configura la ejecución y medición de comandos
+type RunsConfig struct {
+	SampleInterval Duration              `json:"sample_interval"` // Frecuencia de muestreo durante la ejecución
+	MaxDuration    Duration              `json:"max_duration"`    // Tiempo máximo antes de terminar el comando
+	MaxRuns        int                   `json:"max_runs"`        // Ejecuciones conservadas en memoria
+	MaxConcurrent  int                   `json:"max_concurrent"`  // Ejecuciones en curso a la vez
+	Commands       map[string]RunCommand `json:"commands"`        // Lista de comandos permitidos por nombre
+}
+
+// RunCommand describe un comando permitido
+type RunCommand struct {
+	Path        string   `json:"path"`                   // Ejecutable a lanzar
+	Args        []string `json:"args,omitempty"`         // Argumentos fijos antepuestos a los de la solicitud
+	Dir         string   `json:"dir,omitempty"`          // Directorio de trabajo
+	ArgPatterns []string `json:"arg_patterns,omitempty"` // Expresiones regulares de los argumentos aceptados
+	MaxArgs     int      `json:"max_args,omitempty"`     // Máximo de argumentos de la solicitud
+}
+
+// Duration permite expresar duraciones como texto ("15s", "24h") en JSON
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON serializa la duración como texto
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON acepta duraciones como texto o como número de nanosegundos
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("duración inválida %q: %w", s, err)
+		}
+		d.Duration = parsed
+		return nil
+	}
+
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("duración inválida %s", string(data))
+	}
+	d.Duration = time.Duration(n)
+	return nil
+}
+
+// Default retorna la configuración por defecto
+func Default() *Config {
+	return &Config{
+		Port:               ":8080",
+		CollectionInterval: Duration{15 * time.Second},
+		Storage: StorageConfig{
+			Backend:         "segment",
+			Dir:             "data/metrics",
+			MaxSegmentBytes: 4 << 20, // 4 MiB
+			SegmentDuration: Duration{6 * time.Hour},
+			Retention:       Duration{7 * 24 * time.Hour},
+			MaxHistory:      100,
+		},
+		Processes: ProcessConfig{
+			MaxHistory: 240, // Una hora con el intervalo por defecto
+		},
+		Profiles: ProfilesConfig{
+			Dir:       "data/profiles",
+			MaxBytes:  256 << 20, // 256 MiB
+			Retention: Duration{7 * 24 * time.Hour},
+
+			BlockProfileRate:     0, // Desactivados: tienen costo en cada bloqueo y contención
+			MutexProfileFraction: 0,
+			MemProfileRate:       512 * 1024, // Valor por defecto del runtime
+
+			Continuous: ContinuousConfig{
+				Enabled:     false,
+				Interval:    Duration{5 * time.Minute},
+				CPUDuration: Duration{10 * time.Second},
+				Types:       []string{"cpu", "heap"},
+				Retention:   Duration{24 * time.Hour},
+			},
+			Triggers: TriggersConfig{
+				Enabled:           false,
+				CPUPercent:        90,
+				Goroutines:        10000,
+				HeapGrowthPercent: 50,
+				Cooldown:          Duration{10 * time.Minute},
+				CPUDuration:       Duration{10 * time.Second},
+				Types:             []string{"cpu", "heap", "goroutine"},
+			},
+		},
+		Alerts: AlertsConfig{
+			HistorySize: 1000,
+			Notifications: NotificationsConfig{
+				GroupWait:      Duration{10 * time.Second},
+				GroupBy:        []string{"rule"},
+				MaxRetries:     5,
+				InitialBackoff: Duration{time.Second},
+				MaxBackoff:     Duration{time.Minute},
+				Timeout:        Duration{10 * time.Second},
+				ResendInterval: Duration{time.Minute},
+			},
+		},
+		Runs: RunsConfig{
+			SampleInterval: Duration{100 * time.Millisecond},
+			MaxDuration:    Duration{10 * time.Minute},
+			MaxRuns:        50,
+			MaxConcurrent:  4,
+		},
+	}
+}
+
+// Load lee la configuración desde un archivo JSON. Los campos ausentes
+// conservan su valor por defecto; si path está vacío se usa Default.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer la configuración: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("error al interpretar la configuración: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("configuración inválida: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// validate rechaza los valores con los que la API no puede funcionar,
+// como intervalos o tamaños que no son positivos
+func (c *Config) validate() error {
+	if c.CollectionInterval.Duration <= 0 {
+		return fmt.Errorf("collection_interval debe ser positivo (%v)", c.CollectionInterval.Duration)
+	}
+	if c.Processes.MaxHistory <= 0 {
+		return fmt.Errorf("processes.max_history debe ser positivo (%d)", c.Processes.MaxHistory)
+	}
+
+	s := c.Storage
+	switch s.Backend {
+	case "memory":
+		if s.MaxHistory <= 0 {
+			return fmt.Errorf("storage.max_history debe ser positivo (%d)", s.MaxHistory)
+		}
+	case "segment":
+		if s.MaxSegmentBytes <= 0 {
+			return fmt.Errorf("storage.max_segment_bytes debe ser positivo (%d)", s.MaxSegmentBytes)
+		}
+		if s.SegmentDuration.Duration <= 0 {
+			return fmt.Errorf("storage.segment_duration debe ser positivo (%v)", s.SegmentDuration.Duration)
+		}
+		if s.Retention.Duration <= 0 {
+			return fmt.Errorf("storage.retention debe ser positivo (%v)", s.Retention.Duration)
+		}
+	default:
+		return fmt.Errorf("storage.backend desconocido %q (usa segment o memory)", s.Backend)
+	}
+	return nil
+}
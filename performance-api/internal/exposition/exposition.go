@@ -0,0 +1,164 @@
+package exposition
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Format identifica el formato de exposición de métricas
+type Format int
+
+const (
+	// FormatPrometheus es el formato de texto de Prometheus (versión 0.0.4)
+	FormatPrometheus Format = iota
+	// FormatOpenMetrics es el formato de texto de OpenMetrics 1.0
+	FormatOpenMetrics
+)
+
+// Tipos de contenido de cada formato
+const (
+	ContentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+	ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// Tipos de métrica soportados
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// Family agrupa las muestras de una métrica con su metadata
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Unit    string // Unidad de OpenMetrics; el nombre debe terminar en ella
+	Samples []Sample
+}
+
+// Sample es un valor de una métrica con sus etiquetas
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Label es un par nombre/valor que identifica una serie
+type Label struct {
+	Name  string
+	Value string
+}
+
+// ContentType retorna el tipo de contenido HTTP del formato
+func (f Format) ContentType() string {
+	if f == FormatOpenMetrics {
+		return ContentTypeOpenMetrics
+	}
+	return ContentTypePrometheus
+}
+
+// Negotiate elige el formato según la cabecera Accept. Se usa OpenMetrics
+// solo si el cliente lo acepta con una preferencia (q) no menor que la del
+// resto de tipos aceptados.
+func Negotiate(accept string) Format {
+	openMetricsQ, otherQ := -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if mediaType == "application/openmetrics-text" {
+			openMetricsQ = math.Max(openMetricsQ, q)
+		} else {
+			otherQ = math.Max(otherQ, q)
+		}
+	}
+	if openMetricsQ > 0 && openMetricsQ >= otherQ {
+		return FormatOpenMetrics
+	}
+	return FormatPrometheus
+}
+
+// Write escribe las familias en el formato indicado
+func Write(w io.Writer, families []Family, format Format) error {
+	bw := bufio.NewWriter(w)
+	for _, family := range families {
+		writeFamily(bw, family, format)
+	}
+	if format == FormatOpenMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+// writeFamily escribe las líneas HELP/TYPE/UNIT y las muestras de una familia
+func writeFamily(w *bufio.Writer, family Family, format Format) {
+	name := family.Name
+	sampleName := name
+	if family.Type == TypeCounter {
+		// En OpenMetrics la familia de un contador no lleva el sufijo _total,
+		// pero sus muestras sí; en Prometheus el nombre incluye el sufijo.
+		name = strings.TrimSuffix(name, "_total")
+		sampleName = name + "_total"
+		if format == FormatPrometheus {
+			name = sampleName
+		}
+	}
+
+	w.WriteString("# HELP " + name + " " + escapeHelp(family.Help) + "\n")
+	w.WriteString("# TYPE " + name + " " + family.Type + "\n")
+	if format == FormatOpenMetrics && family.Unit != "" {
+		w.WriteString("# UNIT " + name + " " + family.Unit + "\n")
+	}
+
+	for _, sample := range family.Samples {
+		w.WriteString(sampleName)
+		if len(sample.Labels) > 0 {
+			w.WriteByte('{')
+			for i, label := range sample.Labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				w.WriteString(label.Name + "=\"" + escapeLabelValue(label.Value) + "\"")
+			}
+			w.WriteByte('}')
+		}
+		w.WriteString(" " + formatValue(sample.Value) + "\n")
+	}
+}
+
+// formatValue formatea un valor según las reglas del formato de texto
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// escapeHelp escapa el texto de ayuda (barra invertida y saltos de línea)
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabelValue escapa el valor de una etiqueta
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
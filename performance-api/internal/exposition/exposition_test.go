@@ -0,0 +1,147 @@
+package exposition
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   Format
+	}{
+		{"", FormatPrometheus},
+		{"*/*", FormatPrometheus},
+		{"text/plain", FormatPrometheus},
+		{"application/openmetrics-text", FormatOpenMetrics},
+		{"application/openmetrics-text; version=1.0.0; charset=utf-8", FormatOpenMetrics},
+		// Encabezado típico de Prometheus: OpenMetrics con la mayor preferencia
+		{"application/openmetrics-text;version=1.0.0;q=0.5,text/plain;version=0.0.4;q=0.4,*/*;q=0.1", FormatOpenMetrics},
+		{"application/openmetrics-text;q=0.3,text/plain;q=0.9", FormatPrometheus},
+		{"application/openmetrics-text;q=0.3,*/*", FormatPrometheus},
+		{"text/plain;q=0.5, application/openmetrics-text;q=0.5", FormatOpenMetrics},
+		{"APPLICATION/OpenMetrics-Text; Q=1", FormatOpenMetrics},
+		{"application/openmetrics-text;q=0", FormatPrometheus},
+		{"application/openmetrics-text;q=abc", FormatOpenMetrics},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := Negotiate(tt.accept); got != tt.want {
+				t.Errorf("Negotiate(%q) = %v, se esperaba %v", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+// write retorna las familias escritas en el formato indicado
+func write(t *testing.T, families []Family, format Format) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, families, format); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return buf.String()
+}
+
+func TestWriteFormats(t *testing.T) {
+	families := []Family{
+		{
+			Name:    "perfapi_go_gc_cycles_total",
+			Help:    "Ciclos de GC.",
+			Type:    TypeCounter,
+			Samples: []Sample{{Value: 42}},
+		},
+		{
+			Name:    "perfapi_memory_used_bytes",
+			Help:    "Memoria en uso.",
+			Type:    TypeGauge,
+			Unit:    "bytes",
+			Samples: []Sample{{Value: 1024}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		format Format
+		want   string
+	}{
+		{"prometheus", FormatPrometheus, `# HELP perfapi_go_gc_cycles_total Ciclos de GC.
+# TYPE perfapi_go_gc_cycles_total counter
+perfapi_go_gc_cycles_total 42
+# HELP perfapi_memory_used_bytes Memoria en uso.
+# TYPE perfapi_memory_used_bytes gauge
+perfapi_memory_used_bytes 1024
+`},
+		{"openmetrics", FormatOpenMetrics, `# HELP perfapi_go_gc_cycles Ciclos de GC.
+# TYPE perfapi_go_gc_cycles counter
+perfapi_go_gc_cycles_total 42
+# HELP perfapi_memory_used_bytes Memoria en uso.
+# TYPE perfapi_memory_used_bytes gauge
+# UNIT perfapi_memory_used_bytes bytes
+perfapi_memory_used_bytes 1024
+# EOF
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := write(t, families, tt.format); got != tt.want {
+				t.Errorf("Write =\n%s\nse esperaba\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCounterWithoutSuffix(t *testing.T) {
+	// Un contador declarado sin _total recibe el sufijo en sus muestras
+	family := Family{Name: "perfapi_requests", Help: "Solicitudes.", Type: TypeCounter, Samples: []Sample{{Value: 1}}}
+	tests := []struct {
+		format Format
+		family string
+	}{
+		{FormatPrometheus, "perfapi_requests_total"},
+		{FormatOpenMetrics, "perfapi_requests"},
+	}
+	for _, tt := range tests {
+		got := write(t, []Family{family}, tt.format)
+		if !strings.Contains(got, "# TYPE "+tt.family+" counter\n") || !strings.Contains(got, "\nperfapi_requests_total 1\n") {
+			t.Errorf("formato %v:\n%s\nse esperaba la familia %s con muestras perfapi_requests_total", tt.format, got, tt.family)
+		}
+	}
+}
+
+func TestWriteEscapingAndValues(t *testing.T) {
+	family := Family{
+		Name: "perfapi_value",
+		Help: "Ayuda con \\ y\nsalto.",
+		Type: TypeGauge,
+		Samples: []Sample{
+			{Labels: []Label{{Name: "path", Value: `C:\dir "x"` + "\n" + "fin"}, {Name: "b", Value: "2"}}, Value: math.NaN()},
+			{Labels: []Label{{Name: "v", Value: "inf"}}, Value: math.Inf(1)},
+			{Labels: []Label{{Name: "v", Value: "-inf"}}, Value: math.Inf(-1)},
+			{Labels: []Label{{Name: "v", Value: "chico"}}, Value: 1.5e-7},
+			{Labels: []Label{{Name: "v", Value: "grande"}}, Value: 12345678901234},
+		},
+	}
+	want := `# HELP perfapi_value Ayuda con \\ y\nsalto.
+# TYPE perfapi_value gauge
+perfapi_value{path="C:\\dir \"x\"\nfin",b="2"} NaN
+perfapi_value{v="inf"} +Inf
+perfapi_value{v="-inf"} -Inf
+perfapi_value{v="chico"} 1.5e-07
+perfapi_value{v="grande"} 1.2345678901234e+13
+`
+	if got := write(t, []Family{family}, FormatPrometheus); got != want {
+		t.Errorf("Write =\n%s\nse esperaba\n%s", got, want)
+	}
+}
+
+func TestWriteEmptyOpenMetrics(t *testing.T) {
+	if got := write(t, nil, FormatOpenMetrics); got != "# EOF\n" {
+		t.Errorf("Write sin familias = %q, se esperaba solo # EOF", got)
+	}
+	if got := write(t, nil, FormatPrometheus); got != "" {
+		t.Errorf("Write sin familias = %q, se esperaba vacío", got)
+	}
+}
@@ -0,0 +1,208 @@
+package exposition
+
+import (
+	"performance-api/internal/metrics"
+	"sort"
+	"strconv"
+)
+
+// namespace es el prefijo de todas las métricas expuestas por la API
+const namespace = "perfapi_"
+
+// SystemFamilies convierte una muestra del recolector en familias de métricas
+func SystemFamilies(m *metrics.SystemMetrics) []Family {
+	perCPU := make([]Sample, len(m.CPU.PerCPU))
+	for i, percent := range m.CPU.PerCPU {
+		perCPU[i] = Sample{
+			Labels: []Label{{Name: "cpu", Value: strconv.Itoa(i)}},
+			Value:  percent,
+		}
+	}
+
+	families := []Family{
+		gauge("cpu_usage_percent", "Porcentaje de uso total de CPU.", "", m.CPU.Percent),
+		{
+			Name:    namespace + "cpu_core_usage_percent",
+			Help:    "Porcentaje de uso de CPU por núcleo lógico.",
+			Type:    TypeGauge,
+			Samples: perCPU,
+		},
+		gauge("cpu_logical_count", "Número de CPUs lógicas reportadas por el sistema.", "", float64(m.CPU.Count)),
+		gauge("memory_total_bytes", "Memoria física total.", "bytes", float64(m.Memory.Total)),
+		gauge("memory_available_bytes", "Memoria disponible para nuevos procesos.", "bytes", float64(m.Memory.Available)),
+		gauge("memory_used_bytes", "Memoria en uso.", "bytes", float64(m.Memory.Used)),
+		gauge("memory_free_bytes", "Memoria libre.", "bytes", float64(m.Memory.Free)),
+		gauge("memory_used_percent", "Porcentaje de memoria en uso.", "", m.Memory.UsedPercent),
+		gauge("goroutines", "Número de goroutines de la API.", "", float64(m.Goroutines)),
+		gauge("num_cpu", "Número de CPUs utilizables por el proceso de Go.", "", float64(m.NumCPU)),
+	}
+
+	families = append(families, diskFamilies(m.Disk)...)
+	families = append(families, networkFamilies(m.Network)...)
+	families = append(families, runtimeFamilies(m.Runtime)...)
+
+	if !m.Timestamp.IsZero() {
+		families = append(families, gauge("last_sample_timestamp_seconds",
+			"Momento de la última recolección en segundos Unix.", "seconds",
+			float64(m.Timestamp.UnixNano())/1e9))
+	}
+
+	return families
+}
+
+// gauge crea una familia de tipo gauge con una sola muestra sin etiquetas
+func gauge(name, help, unit string, value float64) Family {
+	return Family{
+		Name:    namespace + name,
+		Help:    help,
+		Type:    TypeGauge,
+		Unit:    unit,
+		Samples: []Sample{{Value: value}},
+	}
+}
+
+// diskFamilies convierte las tasas de E/S y el uso de sistemas de archivos en familias
+func diskFamilies(d metrics.DiskInfo) []Family {
+	device := func(name, help, unit string, get func(dev metrics.DiskDeviceIO) float64) Family {
+		family := Family{Name: namespace + name, Help: help, Type: TypeGauge, Unit: unit}
+		for _, dev := range d.Devices {
+			family.Samples = append(family.Samples, Sample{
+				Labels: []Label{{Name: "device", Value: dev.Name}},
+				Value:  get(dev),
+			})
+		}
+		return family
+	}
+	filesystem := func(name, help, unit string, get func(fs metrics.FilesystemUsage) float64) Family {
+		family := Family{Name: namespace + name, Help: help, Type: TypeGauge, Unit: unit}
+		for _, fs := range d.Filesystems {
+			family.Samples = append(family.Samples, Sample{
+				Labels: []Label{
+					{Name: "mountpoint", Value: fs.Mountpoint},
+					{Name: "device", Value: fs.Device},
+					{Name: "fstype", Value: fs.Fstype},
+				},
+				Value: get(fs),
+			})
+		}
+		return family
+	}
+
+	return []Family{
+		device("disk_read_bytes_per_second", "Bytes leídos por segundo entre las dos últimas muestras.", "",
+			func(dev metrics.DiskDeviceIO) float64 { return dev.ReadBytesPerSec }),
+		device("disk_written_bytes_per_second", "Bytes escritos por segundo entre las dos últimas muestras.", "",
+			func(dev metrics.DiskDeviceIO) float64 { return dev.WriteBytesPerSec }),
+		device("disk_reads_per_second", "Operaciones de lectura por segundo.", "",
+			func(dev metrics.DiskDeviceIO) float64 { return dev.ReadOpsPerSec }),
+		device("disk_writes_per_second", "Operaciones de escritura por segundo.", "",
+			func(dev metrics.DiskDeviceIO) float64 { return dev.WriteOpsPerSec }),
+		device("disk_read_time_ratio", "Segundos dedicados a lecturas por segundo transcurrido.", "ratio",
+			func(dev metrics.DiskDeviceIO) float64 { return dev.ReadTimeMsPerSec / 1000 }),
+		device("disk_write_time_ratio", "Segundos dedicados a escrituras por segundo transcurrido.", "ratio",
+			func(dev metrics.DiskDeviceIO) float64 { return dev.WriteTimeMsPerSec / 1000 }),
+		device("disk_io_time_ratio", "Fracción del tiempo en que el dispositivo estuvo ocupado.", "ratio",
+			func(dev metrics.DiskDeviceIO) float64 { return dev.IOTimeMsPerSec / 1000 }),
+		filesystem("filesystem_size_bytes", "Tamaño total del sistema de archivos.", "bytes",
+			func(fs metrics.FilesystemUsage) float64 { return float64(fs.Total) }),
+		filesystem("filesystem_used_bytes", "Espacio usado del sistema de archivos.", "bytes",
+			func(fs metrics.FilesystemUsage) float64 { return float64(fs.Used) }),
+		filesystem("filesystem_free_bytes", "Espacio libre del sistema de archivos.", "bytes",
+			func(fs metrics.FilesystemUsage) float64 { return float64(fs.Free) }),
+		filesystem("filesystem_used_percent", "Porcentaje de uso del sistema de archivos.", "",
+			func(fs metrics.FilesystemUsage) float64 { return fs.UsedPercent }),
+	}
+}
+
+// networkFamilies convierte las tasas por interfaz y las conexiones TCP en familias
+func networkFamilies(n metrics.NetworkInfo) []Family {
+	iface := func(name, help string, get func(i metrics.NetworkInterfaceIO) float64) Family {
+		family := Family{Name: namespace + name, Help: help, Type: TypeGauge}
+		for _, i := range n.Interfaces {
+			family.Samples = append(family.Samples, Sample{
+				Labels: []Label{{Name: "interface", Value: i.Name}},
+				Value:  get(i),
+			})
+		}
+		return family
+	}
+
+	states := make([]string, 0, len(n.TCPConnections))
+	for state := range n.TCPConnections {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	tcp := Family{
+		Name: namespace + "tcp_connections",
+		Help: "Conexiones TCP del sistema por estado.",
+		Type: TypeGauge,
+	}
+	for _, state := range states {
+		tcp.Samples = append(tcp.Samples, Sample{
+			Labels: []Label{{Name: "state", Value: state}},
+			Value:  float64(n.TCPConnections[state]),
+		})
+	}
+
+	return []Family{
+		iface("network_receive_bytes_per_second", "Bytes recibidos por segundo entre las dos últimas muestras.",
+			func(i metrics.NetworkInterfaceIO) float64 { return i.RxBytesPerSec }),
+		iface("network_transmit_bytes_per_second", "Bytes enviados por segundo entre las dos últimas muestras.",
+			func(i metrics.NetworkInterfaceIO) float64 { return i.TxBytesPerSec }),
+		iface("network_receive_packets_per_second", "Paquetes recibidos por segundo.",
+			func(i metrics.NetworkInterfaceIO) float64 { return i.RxPacketsPerSec }),
+		iface("network_transmit_packets_per_second", "Paquetes enviados por segundo.",
+			func(i metrics.NetworkInterfaceIO) float64 { return i.TxPacketsPerSec }),
+		iface("network_receive_errors_per_second", "Errores de recepción por segundo.",
+			func(i metrics.NetworkInterfaceIO) float64 { return i.RxErrorsPerSec }),
+		iface("network_transmit_errors_per_second", "Errores de envío por segundo.",
+			func(i metrics.NetworkInterfaceIO) float64 { return i.TxErrorsPerSec }),
+		iface("network_receive_drops_per_second", "Paquetes recibidos descartados por segundo.",
+			func(i metrics.NetworkInterfaceIO) float64 { return i.RxDropsPerSec }),
+		iface("network_transmit_drops_per_second", "Paquetes enviados descartados por segundo.",
+			func(i metrics.NetworkInterfaceIO) float64 { return i.TxDropsPerSec }),
+		tcp,
+	}
+}
+
+// runtimeFamilies convierte las métricas del runtime de Go en familias
+func runtimeFamilies(r metrics.RuntimeInfo) []Family {
+	counter := func(name, help, unit string, value float64) Family {
+		return Family{
+			Name:    namespace + name,
+			Help:    help,
+			Type:    TypeCounter,
+			Unit:    unit,
+			Samples: []Sample{{Value: value}},
+		}
+	}
+	quantiles := func(name, help string, h metrics.RuntimeHistogram) Family {
+		return Family{
+			Name: namespace + name,
+			Help: help,
+			Type: TypeGauge,
+			Unit: "seconds",
+			Samples: []Sample{
+				{Labels: []Label{{Name: "quantile", Value: "0.5"}}, Value: h.P50},
+				{Labels: []Label{{Name: "quantile", Value: "0.9"}}, Value: h.P90},
+				{Labels: []Label{{Name: "quantile", Value: "0.99"}}, Value: h.P99},
+				{Labels: []Label{{Name: "quantile", Value: "1"}}, Value: h.Max},
+			},
+		}
+	}
+
+	return []Family{
+		gauge("go_gomaxprocs", "Valor actual de GOMAXPROCS.", "", float64(r.GOMAXPROCS)),
+		gauge("go_heap_objects", "Objetos vivos o no barridos en el heap.", "", float64(r.HeapObjects)),
+		gauge("go_heap_bytes", "Memoria del heap ocupada por objetos.", "bytes", float64(r.HeapBytes)),
+		gauge("go_heap_goal_bytes", "Tamaño del heap objetivo para el siguiente ciclo de GC.", "bytes", float64(r.HeapGoalBytes)),
+		counter("go_gc_cycles_total", "Ciclos de GC completados.", "", float64(r.GCCycles)),
+		counter("go_gc_cycles_forced_total", "Ciclos de GC forzados por la aplicación.", "", float64(r.GCCyclesForced)),
+		gauge("go_alloc_bytes_per_second", "Bytes asignados en el heap por segundo.", "", r.AllocBytesPerSec),
+		gauge("go_alloc_objects_per_second", "Objetos asignados en el heap por segundo.", "", r.AllocObjectsPerSec),
+		counter("go_cgo_calls_total", "Llamadas de Go a C.", "", float64(r.CgoCalls)),
+		counter("go_mutex_wait_seconds_total", "Tiempo total que las goroutines esperaron por mutex.", "seconds", r.MutexWaitSeconds),
+		quantiles("go_gc_pause_seconds", "Cuantiles de las pausas de GC desde la muestra anterior.", r.GCPauses),
+		quantiles("go_sched_latency_seconds", "Cuantiles de la espera de goroutines listas para ejecutarse.", r.SchedLatencies),
+	}
+}
@@ -0,0 +1,360 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Métodos de detección de anomalías
+const (
+	AnomalyZScore = "zscore" // Puntaje z sobre la media y desviación de la ventana móvil
+	AnomalyEWMA   = "ewma"   // Bandas de control sobre la media y varianza exponenciales
+	AnomalyMAD    = "mad"    // Puntaje z robusto sobre la mediana y la desviación absoluta mediana
+)
+
+// Valores por defecto de la detección de anomalías
+const (
+	DefaultAnomalyWindow    = 30  // Muestras de la ventana móvil
+	DefaultAnomalyThreshold = 3.0 // Puntaje a partir del cual una muestra es anómala
+	DefaultAnomalyAlpha     = 0.3 // Factor de suavizado del EWMA
+)
+
+// MaxAnomalyRange es el rango máximo del historial que se analiza en una consulta
+const MaxAnomalyRange = 24 * time.Hour
+
+// minAnomalyBaseline es la cantidad mínima de muestras previas para puntuar una muestra
+const minAnomalyBaseline = 10
+
+// madScale convierte la desviación absoluta mediana en una estimación de la
+// desviación estándar de una distribución normal
+const madScale = 1.4826
+
+// DefaultAnomalySeries son las series analizadas si no se indican otras
+var DefaultAnomalySeries = []string{"cpu.percent", "memory.used_percent", "goroutines"}
+
+// AnomalyOptions configura la detección de anomalías
+type AnomalyOptions struct {
+	Series    []string // Campos analizados (vacío = DefaultAnomalySeries)
+	Methods   []string // Métodos aplicados (vacío = los tres)
+	Window    int      // Muestras previas usadas como línea base (0 = 30)
+	Threshold float64  // Puntaje absoluto que marca una anomalía (0 = 3)
+	Alpha     float64  // Suavizado del EWMA, entre 0 y 1 (0 = 0.3)
+	Limit     int      // Anomalías retornadas por serie, las más recientes (0 = sin límite)
+}
+
+// MethodScore es el puntaje de una muestra según un método: cuántas
+// desviaciones se aleja de la línea base y la banda considerada normal
+type MethodScore struct {
+	Score    float64 `json:"score"`
+	Baseline float64 `json:"baseline"` // Media, EWMA o mediana previa
+	Lower    float64 `json:"lower"`
+	Upper    float64 `json:"upper"`
+}
+
+// AnomalyPoint es una muestra puntuada de una serie
+type AnomalyPoint struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Value     float64                `json:"value"`
+	Anomalous bool                   `json:"anomalous"`
+	Methods   []string               `json:"methods,omitempty"` // Métodos que la marcaron como anómala
+	Scores    map[string]MethodScore `json:"scores"`
+}
+
+// SeriesAnomalies contiene las anomalías detectadas en una serie
+type SeriesAnomalies struct {
+	Series    string         `json:"series"`
+	Evaluated int            `json:"evaluated"` // Muestras con línea base suficiente para puntuarlas
+	Count     int            `json:"count"`     // Total de muestras anómalas
+	Latest    *AnomalyPoint  `json:"latest,omitempty"`
+	Anomalies []AnomalyPoint `json:"anomalies"`
+}
+
+// AnomalyReport es el resultado de la detección sobre un rango del historial
+type AnomalyReport struct {
+	SampleCount int               `json:"sample_count"`
+	TimeRange   TimeRange         `json:"time_range"`
+	Methods     []string          `json:"methods"`
+	Window      int               `json:"window"`
+	Threshold   float64           `json:"threshold"`
+	Alpha       float64           `json:"alpha"`
+	Series      []SeriesAnomalies `json:"series"`
+}
+
+// normalize completa los valores por defecto y valida las opciones
+func (o *AnomalyOptions) normalize() error {
+	if len(o.Series) == 0 {
+		o.Series = DefaultAnomalySeries
+	}
+	for _, name := range o.Series {
+		if _, ok := LookupField(name); !ok {
+			return fmt.Errorf("serie desconocida %q (disponibles: %s)", name, strings.Join(FieldNames(), ", "))
+		}
+	}
+	if len(o.Methods) == 0 {
+		o.Methods = []string{AnomalyZScore, AnomalyEWMA, AnomalyMAD}
+	}
+	for _, method := range o.Methods {
+		switch method {
+		case AnomalyZScore, AnomalyEWMA, AnomalyMAD:
+		default:
+			return fmt.Errorf("método desconocido %q (usa zscore, ewma o mad)", method)
+		}
+	}
+	if o.Window == 0 {
+		o.Window = DefaultAnomalyWindow
+	}
+	if o.Window < minAnomalyBaseline || o.Window > 1000 {
+		return fmt.Errorf("ventana inválida %d (entre %d y 1000 muestras)", o.Window, minAnomalyBaseline)
+	}
+	if o.Threshold == 0 {
+		o.Threshold = DefaultAnomalyThreshold
+	}
+	if o.Threshold < 0 || math.IsNaN(o.Threshold) || math.IsInf(o.Threshold, 0) {
+		return fmt.Errorf("umbral inválido %g (debe ser positivo)", o.Threshold)
+	}
+	if o.Alpha == 0 {
+		o.Alpha = DefaultAnomalyAlpha
+	}
+	if !(o.Alpha > 0 && o.Alpha <= 1) {
+		return fmt.Errorf("alpha inválido %g (debe estar entre 0 y 1)", o.Alpha)
+	}
+	if o.Limit < 0 {
+		return fmt.Errorf("límite inválido %d", o.Limit)
+	}
+	return nil
+}
+
+// DetectAnomalies analiza las muestras del historial dentro de [from, to].
+// Sin from se analiza la última DefaultHistoryWindow antes de to, y el rango
+// no puede superar MaxAnomalyRange. Retorna nil si no hay muestras en el rango.
+func (c *Collector) DetectAnomalies(from, to time.Time, opts AnomalyOptions) (*AnomalyReport, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	end := to
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if from.IsZero() {
+		from = end.Add(-DefaultHistoryWindow)
+	}
+	if span := end.Sub(from); span > MaxAnomalyRange {
+		return nil, fmt.Errorf("rango demasiado largo %v (máximo %v)", span.Round(time.Second), MaxAnomalyRange)
+	}
+	history := c.GetMetricsRange(from, to)
+	if len(history) == 0 {
+		return nil, nil
+	}
+	return DetectAnomalies(history, opts)
+}
+
+// DetectAnomalies puntúa cada muestra de las series indicadas contra la
+// línea base formada por las muestras anteriores, sin estacionalidad: las
+// últimas Window muestras para zscore y mad, y el promedio exponencial de
+// todas las anteriores para ewma. Una muestra es anómala si algún método
+// supera el umbral en valor absoluto.
+func DetectAnomalies(samples []SystemMetrics, opts AnomalyOptions) (*AnomalyReport, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	report := &AnomalyReport{
+		SampleCount: len(samples),
+		Methods:     opts.Methods,
+		Window:      opts.Window,
+		Threshold:   opts.Threshold,
+		Alpha:       opts.Alpha,
+		Series:      make([]SeriesAnomalies, 0, len(opts.Series)),
+	}
+	if len(samples) > 0 {
+		report.TimeRange = TimeRange{Start: samples[0].Timestamp, End: samples[len(samples)-1].Timestamp}
+	}
+
+	for _, name := range opts.Series {
+		field, _ := LookupField(name)
+		values := make([]float64, len(samples))
+		for i, m := range samples {
+			values[i] = field(m)
+		}
+		series := detectSeries(values, samples, opts)
+		series.Series = name
+		report.Series = append(report.Series, series)
+	}
+	return report, nil
+}
+
+// detectSeries puntúa los valores de una serie. La ventana de mad se
+// mantiene ordenada al avanzar, sin reordenarla en cada muestra.
+func detectSeries(values []float64, samples []SystemMetrics, opts AnomalyOptions) SeriesAnomalies {
+	series := SeriesAnomalies{Anomalies: make([]AnomalyPoint, 0)}
+	ewma := newEWMA(opts.Alpha)
+	sorted := make([]float64, 0, opts.Window+1)
+
+	for i, v := range values {
+		var window []float64
+		if i >= minAnomalyBaseline {
+			window = values[max(0, i-opts.Window):i]
+		}
+
+		point := AnomalyPoint{Timestamp: samples[i].Timestamp, Value: v, Scores: make(map[string]MethodScore)}
+		for _, method := range opts.Methods {
+			var score MethodScore
+			switch {
+			case method == AnomalyEWMA && ewma.count >= minAnomalyBaseline:
+				score = ewma.score(v, opts.Threshold)
+			case method == AnomalyZScore && window != nil:
+				score = zScore(window, v, opts.Threshold)
+			case method == AnomalyMAD && window != nil:
+				score = madScore(sorted, v, opts.Threshold)
+			default:
+				continue
+			}
+			point.Scores[method] = score
+			if math.Abs(score.Score) >= opts.Threshold {
+				point.Methods = append(point.Methods, method)
+			}
+		}
+		ewma.add(v)
+		sorted = insertSorted(sorted, v)
+		if i >= opts.Window {
+			sorted = removeSorted(sorted, values[i-opts.Window])
+		}
+
+		if len(point.Scores) == 0 {
+			continue
+		}
+		point.Anomalous = len(point.Methods) > 0
+		series.Evaluated++
+		if point.Anomalous {
+			series.Count++
+			series.Anomalies = append(series.Anomalies, point)
+		}
+		if i == len(values)-1 {
+			series.Latest = &point
+		}
+	}
+
+	if opts.Limit > 0 && len(series.Anomalies) > opts.Limit {
+		series.Anomalies = series.Anomalies[len(series.Anomalies)-opts.Limit:]
+	}
+	return series
+}
+
+// zScore puntúa v contra la media y la desviación estándar de la ventana
+func zScore(window []float64, v, threshold float64) MethodScore {
+	var sum float64
+	for _, x := range window {
+		sum += x
+	}
+	mean := sum / float64(len(window))
+	var variance float64
+	for _, x := range window {
+		variance += (x - mean) * (x - mean)
+	}
+	stdDev := math.Sqrt(variance / float64(len(window)))
+	return bandScore(v, mean, stdDev, threshold)
+}
+
+// madScore puntúa v contra la mediana de la ventana ordenada y su
+// desviación absoluta mediana, que no se ven afectadas por picos aislados
+func madScore(sorted []float64, v, threshold float64) MethodScore {
+	med := medianSorted(sorted)
+	return bandScore(v, med, madScale*medianDeviation(sorted, med), threshold)
+}
+
+// ewmaState mantiene la media y la varianza con promedio exponencial
+type ewmaState struct {
+	alpha    float64
+	mean     float64
+	variance float64
+	count    int
+}
+
+func newEWMA(alpha float64) *ewmaState {
+	return &ewmaState{alpha: alpha}
+}
+
+// score puntúa v contra la media y la varianza exponenciales previas
+func (e *ewmaState) score(v, threshold float64) MethodScore {
+	return bandScore(v, e.mean, math.Sqrt(e.variance), threshold)
+}
+
+// add incorpora v a la media y la varianza exponenciales
+func (e *ewmaState) add(v float64) {
+	if e.count == 0 {
+		e.mean = v
+	} else {
+		diff := v - e.mean
+		e.mean += e.alpha * diff
+		e.variance = (1 - e.alpha) * (e.variance + e.alpha*diff*diff)
+	}
+	e.count++
+}
+
+// bandScore calcula cuántas desviaciones se aleja v de la línea base. La
+// desviación se limita al 1% de la línea base para que una serie constante
+// no produzca puntajes infinitos ante el menor cambio.
+func bandScore(v, baseline, deviation, threshold float64) MethodScore {
+	deviation = max(deviation, math.Abs(baseline)*0.01, 1e-9)
+	return MethodScore{
+		Score:    (v - baseline) / deviation,
+		Baseline: baseline,
+		Lower:    baseline - threshold*deviation,
+		Upper:    baseline + threshold*deviation,
+	}
+}
+
+// medianSorted retorna la mediana de valores ya ordenados
+func medianSorted(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// medianDeviation retorna la mediana de |x - med| sobre valores ordenados.
+// Las desviaciones crecen hacia ambos lados de med, de modo que se recorren
+// en orden avanzando dos índices, sin ordenarlas.
+func medianDeviation(sorted []float64, med float64) float64 {
+	n := len(sorted)
+	right := sort.SearchFloat64s(sorted, med)
+	left := right - 1
+	next := func() float64 {
+		if left < 0 || (right < n && sorted[right]-med < med-sorted[left]) {
+			right++
+			return sorted[right-1] - med
+		}
+		left--
+		return med - sorted[left+1]
+	}
+
+	var prev, cur float64
+	for i := 0; i <= n/2; i++ {
+		prev, cur = cur, next()
+	}
+	if n%2 == 1 {
+		return cur
+	}
+	return (prev + cur) / 2
+}
+
+// insertSorted agrega v a los valores ordenados conservando el orden
+func insertSorted(sorted []float64, v float64) []float64 {
+	i := sort.SearchFloat64s(sorted, v)
+	sorted = append(sorted, 0)
+	copy(sorted[i+1:], sorted[i:])
+	sorted[i] = v
+	return sorted
+}
+
+// removeSorted quita una aparición de v de los valores ordenados
+func removeSorted(sorted []float64, v float64) []float64 {
+	i := sort.SearchFloat64s(sorted, v)
+	if i == len(sorted) || sorted[i] != v {
+		return sorted
+	}
+	return append(sorted[:i], sorted[i+1:]...)
+}
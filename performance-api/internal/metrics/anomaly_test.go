@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// approxEqual compara con una tolerancia absoluta pequeña
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestZScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		window []float64
+		v      float64
+		want   MethodScore
+	}{
+		// Media 5 y desviación estándar poblacional 2
+		{"serie conocida", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 9, MethodScore{Score: 2, Baseline: 5, Lower: -1, Upper: 11}},
+		{"por debajo", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 1, MethodScore{Score: -2, Baseline: 5, Lower: -1, Upper: 11}},
+		// Serie constante: la desviación se limita al 1% de la línea base
+		{"constante", []float64{10, 10, 10, 10}, 11, MethodScore{Score: 10, Baseline: 10, Lower: 9.7, Upper: 10.3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := zScore(tt.window, tt.v, 3)
+			if !approxEqual(got.Score, tt.want.Score) || !approxEqual(got.Baseline, tt.want.Baseline) ||
+				!approxEqual(got.Lower, tt.want.Lower) || !approxEqual(got.Upper, tt.want.Upper) {
+				t.Errorf("zScore = %+v, se esperaba %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMADScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		v      float64
+		median float64
+		mad    float64
+	}{
+		// Mediana 2; desviaciones 0 0 1 1 2 4 7, mediana 1
+		{"impar", []float64{1, 1, 2, 2, 4, 6, 9}, 5, 2, 1},
+		// Mediana 2.5; desviaciones 0.5 0.5 1.5 1.5, mediana 1
+		{"par", []float64{1, 2, 3, 4}, 0, 2.5, 1},
+		// Un pico aislado no mueve la mediana ni la desviación absoluta mediana
+		{"con pico", []float64{10, 10, 11, 11, 12, 12, 1000}, 20, 11, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := medianSorted(tt.sorted); !approxEqual(got, tt.median) {
+				t.Errorf("medianSorted = %g, se esperaba %g", got, tt.median)
+			}
+			if got := medianDeviation(tt.sorted, tt.median); !approxEqual(got, tt.mad) {
+				t.Errorf("medianDeviation = %g, se esperaba %g", got, tt.mad)
+			}
+			got := madScore(tt.sorted, tt.v, 3)
+			want := (tt.v - tt.median) / (madScale * tt.mad)
+			if !approxEqual(got.Score, want) || !approxEqual(got.Baseline, tt.median) {
+				t.Errorf("madScore = %+v, se esperaba puntaje %g sobre la mediana %g", got, want, tt.median)
+			}
+			if !approxEqual(got.Upper-got.Lower, 2*3*madScale*tt.mad) {
+				t.Errorf("banda = [%g, %g], se esperaba un ancho de %g", got.Lower, got.Upper, 2*3*madScale*tt.mad)
+			}
+		})
+	}
+}
+
+func TestEWMA(t *testing.T) {
+	e := newEWMA(0.5)
+	steps := []struct {
+		v        float64
+		mean     float64
+		variance float64
+	}{
+		{10, 10, 0},
+		{20, 15, 25},      // diff 10: 0.5 * (0 + 0.5*100)
+		{10, 12.5, 18.75}, // diff -5: 0.5 * (25 + 0.5*25)
+		{12.5, 12.5, 9.375},
+	}
+	for i, step := range steps {
+		e.add(step.v)
+		if !approxEqual(e.mean, step.mean) || !approxEqual(e.variance, step.variance) {
+			t.Fatalf("paso %d: media %g y varianza %g, se esperaban %g y %g", i, e.mean, e.variance, step.mean, step.variance)
+		}
+	}
+	got := e.score(12.5+3*math.Sqrt(9.375), 3)
+	if !approxEqual(got.Score, 3) || !approxEqual(got.Baseline, 12.5) || !approxEqual(got.Upper, 12.5+3*math.Sqrt(9.375)) {
+		t.Errorf("score = %+v, se esperaba puntaje 3 sobre 12.5", got)
+	}
+}
+
+func TestDetectAnomaliesFlagsSpike(t *testing.T) {
+	const n, spike = 60, 40
+	base := time.Now().Add(-time.Hour)
+	samples := make([]SystemMetrics, n)
+	for i := range samples {
+		samples[i] = SystemMetrics{Timestamp: base.Add(time.Duration(i) * time.Second), CPU: CPUInfo{Percent: 50 + float64(i%5)}}
+	}
+	samples[spike].CPU.Percent = 100
+
+	report, err := DetectAnomalies(samples, AnomalyOptions{Series: []string{"cpu.percent"}, Window: 20})
+	if err != nil {
+		t.Fatalf("DetectAnomalies: %v", err)
+	}
+	if report.SampleCount != n || len(report.Series) != 1 {
+		t.Fatalf("SampleCount = %d con %d series, se esperaban %d y 1", report.SampleCount, len(report.Series), n)
+	}
+	series := report.Series[0]
+	if series.Evaluated != n-minAnomalyBaseline {
+		t.Errorf("Evaluated = %d, se esperaban %d", series.Evaluated, n-minAnomalyBaseline)
+	}
+	if series.Latest == nil || !series.Latest.Timestamp.Equal(samples[n-1].Timestamp) {
+		t.Errorf("Latest = %v, se esperaba la última muestra", series.Latest)
+	}
+
+	var found *AnomalyPoint
+	for i, a := range series.Anomalies {
+		if a.Timestamp.Before(samples[spike].Timestamp) {
+			t.Errorf("anomalía antes del pico: %+v", a)
+		}
+		if a.Timestamp.Equal(samples[spike].Timestamp) {
+			found = &series.Anomalies[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("el pico no se marcó como anomalía: %+v", series.Anomalies)
+	}
+	if len(found.Methods) != 3 {
+		t.Errorf("el pico se marcó con %v, se esperaban los tres métodos", found.Methods)
+	}
+
+	limited, err := DetectAnomalies(samples, AnomalyOptions{Series: []string{"cpu.percent"}, Window: 20, Limit: 1})
+	if err != nil {
+		t.Fatalf("DetectAnomalies con límite: %v", err)
+	}
+	if got := len(limited.Series[0].Anomalies); got > 1 || limited.Series[0].Count != series.Count {
+		t.Errorf("con Limit 1 se retornaron %d anomalías de %d, se esperaba como mucho 1 con el mismo total %d",
+			got, limited.Series[0].Count, series.Count)
+	}
+}
+
+func TestAnomalyOptionsRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		opts AnomalyOptions
+	}{
+		{"serie desconocida", AnomalyOptions{Series: []string{"cpu.temperature"}}},
+		{"método desconocido", AnomalyOptions{Methods: []string{"prophet"}}},
+		{"ventana chica", AnomalyOptions{Window: minAnomalyBaseline - 1}},
+		{"ventana grande", AnomalyOptions{Window: 1001}},
+		{"umbral negativo", AnomalyOptions{Threshold: -1}},
+		{"alpha mayor que 1", AnomalyOptions{Alpha: 1.5}},
+		{"límite negativo", AnomalyOptions{Limit: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DetectAnomalies(nil, tt.opts); err == nil {
+				t.Error("se esperaba un error")
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"context"
+	"log"
 	"math"
 	"runtime"
 	"sync"
@@ -16,6 +17,9 @@ type SystemMetrics struct {
 	Timestamp    time.Time `json:"timestamp"`
 	CPU          CPUInfo   `json:"cpu"`
 	Memory       MemoryInfo `json:"memory"`
+	Disk         DiskInfo  `json:"disk"`
+	Network      NetworkInfo `json:"network"`
+	Runtime      RuntimeInfo `json:"runtime"`
 	Goroutines   int       `json:"goroutines"`
 	NumCPU       int       `json:"num_cpu"`
 }
@@ -36,33 +40,59 @@ type MemoryInfo struct {
 	Free        uint64  `json:"free"`
 }
 
+// DefaultHistoryWindow es el rango del historial que se lee cuando una
+// consulta no indica su inicio, para no recorrer toda la retención en disco
+const DefaultHistoryWindow = time.Hour
+
 // Collector gestiona la recolección de métricas del sistema
 type Collector struct {
 	mu              sync.RWMutex
 	currentMetrics  *SystemMetrics
-	metricsHistory  []SystemMetrics
-	maxHistory      int
+	store           Store
+	disk            diskSampler
+	network         networkSampler
+	runtime         runtimeSampler
 	collectionInterval time.Duration
 	ctx             context.Context
 	cancel          context.CancelFunc
+	running         sync.WaitGroup // Recolección en curso, esperada por Stop
+	subMu           sync.Mutex
+	subscribers     map[int]chan SystemMetrics
+	nextSubscriber  int
 }
 
-// NewCollector crea una nueva instancia del recolector
+// NewCollector crea una nueva instancia del recolector con historial en memoria
 func NewCollector() *Collector {
+	return NewCollectorWithStore(NewMemoryStore(100)) // Mantener últimas 100 métricas
+}
+
+// NewCollectorWithStore crea un recolector que guarda el historial en el store indicado
+func NewCollectorWithStore(store Store) *Collector {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Collector{
 		currentMetrics:    &SystemMetrics{},
-		metricsHistory:    make([]SystemMetrics, 0),
-		maxHistory:        100, // Mantener últimas 100 métricas
+		store:             store,
 		collectionInterval: 15 * time.Second,
 		ctx:               ctx,
 		cancel:            cancel,
+		subscribers:       make(map[int]chan SystemMetrics),
 	}
 }
 
 // StartCollection inicia la recolección periódica de métricas
 func (c *Collector) StartCollection(interval time.Duration) {
+	// El registro se hace bajo c.mu para que Stop no pueda esperar antes de
+	// que la recolección se anote, ni la recolección empezar tras Stop
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		return
+	}
+	c.running.Add(1)
 	c.collectionInterval = interval
+	c.mu.Unlock()
+	defer c.running.Done()
+
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -113,18 +143,30 @@ func (c *Collector) collectMetrics() {
 		metrics.Memory.Free = memInfo.Free
 	}
 
+	// Obtener E/S de disco y uso de sistemas de archivos
+	metrics.Disk = c.disk.collect(time.Now())
+
+	// Obtener tráfico de red por interfaz y conexiones TCP
+	metrics.Network = c.network.collect(time.Now())
+
 	// Información de goroutines
 	metrics.Goroutines = runtime.NumGoroutine()
 	metrics.NumCPU = runtime.NumCPU()
 
+	// Métricas internas del runtime de Go (heap, GC, planificador)
+	metrics.Runtime = c.runtime.collect(time.Now())
+
 	c.mu.Lock()
 	c.currentMetrics = metrics
+	c.mu.Unlock()
+
 	// Agregar al historial
-	c.metricsHistory = append(c.metricsHistory, *metrics)
-	if len(c.metricsHistory) > c.maxHistory {
-		c.metricsHistory = c.metricsHistory[1:]
+	if err := c.store.Append(*metrics); err != nil {
+		log.Printf("Error al guardar métricas en el historial: %v", err)
 	}
-	c.mu.Unlock()
+
+	// Notificar a los suscriptores
+	c.publish(*metrics)
 }
 
 // GetCurrentMetrics retorna las métricas actuales
@@ -134,63 +176,85 @@ func (c *Collector) GetCurrentMetrics() *SystemMetrics {
 	return c.currentMetrics
 }
 
-// GetMetricsHistory retorna el historial de métricas
+// GetMetricsHistory retorna el historial de métricas de la última DefaultHistoryWindow
 func (c *Collector) GetMetricsHistory() []SystemMetrics {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	history := make([]SystemMetrics, len(c.metricsHistory))
-	copy(history, c.metricsHistory)
-	return history
+	return c.GetMetricsRange(time.Now().Add(-DefaultHistoryWindow), time.Time{})
 }
 
-// GetMetricsStats calcula estadísticas del historial de métricas
-func (c *Collector) GetMetricsStats() *MetricsStatistics {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// GetMetricsStats calcula estadísticas del historial dentro de [from, to]
+func (c *Collector) GetMetricsStats(from, to time.Time, opts StatsOptions) *MetricsStatistics {
+	history := c.GetMetricsRange(from, to)
 
-	if len(c.metricsHistory) == 0 {
+	if len(history) == 0 {
 		return nil
 	}
 
 	stats := &MetricsStatistics{
-		SampleCount: len(c.metricsHistory),
+		SampleCount: len(history),
 		TimeRange: TimeRange{
-			Start: c.metricsHistory[0].Timestamp,
-			End:   c.metricsHistory[len(c.metricsHistory)-1].Timestamp,
+			Start: history[0].Timestamp,
+			End:   history[len(history)-1].Timestamp,
 		},
 	}
 
 	// Calcular estadísticas de CPU
-	cpuValues := make([]float64, 0, len(c.metricsHistory))
-	for _, m := range c.metricsHistory {
+	cpuValues := make([]float64, 0, len(history))
+	for _, m := range history {
 		cpuValues = append(cpuValues, m.CPU.Percent)
 	}
-	stats.CPU = calculateStats(cpuValues)
+	stats.CPU = calculateStats(cpuValues, opts)
 
 	// Calcular estadísticas de memoria
-	memUsedValues := make([]float64, 0, len(c.metricsHistory))
-	for _, m := range c.metricsHistory {
+	memUsedValues := make([]float64, 0, len(history))
+	for _, m := range history {
 		memUsedValues = append(memUsedValues, float64(m.Memory.Used))
 	}
-	stats.Memory = calculateStats(memUsedValues)
+	stats.Memory = calculateStats(memUsedValues, opts)
 
 	// Calcular estadísticas de goroutines
-	goroutineValues := make([]float64, 0, len(c.metricsHistory))
-	for _, m := range c.metricsHistory {
+	goroutineValues := make([]float64, 0, len(history))
+	for _, m := range history {
 		goroutineValues = append(goroutineValues, float64(m.Goroutines))
 	}
-	stats.Goroutines = calculateStats(goroutineValues)
+	stats.Goroutines = calculateStats(goroutineValues, opts)
+
+	// Calcular estadísticas de E/S de disco (suma de todos los dispositivos)
+	diskTotals := make([]DiskDeviceIO, 0, len(history))
+	for _, m := range history {
+		diskTotals = append(diskTotals, m.Disk.Total())
+	}
+	stats.Disk = calculateDiskStats(diskTotals, opts)
+
+	// Calcular estadísticas de red (suma de todas las interfaces)
+	networkTotals := make([]NetworkInterfaceIO, 0, len(history))
+	for _, m := range history {
+		networkTotals = append(networkTotals, m.Network.Total())
+	}
+	stats.Network = calculateNetworkStats(networkTotals, opts)
+
+	// Calcular estadísticas del runtime de Go
+	stats.Runtime = calculateRuntimeStats(history, opts)
 
 	return stats
 }
 
-// Stop detiene la recolección de métricas
+// Stop detiene la recolección de métricas y cierra el almacenamiento una
+// vez terminada la muestra en curso
 func (c *Collector) Stop() {
+	c.mu.Lock()
 	c.cancel()
+	c.mu.Unlock()
+	c.running.Wait()
+	c.closeSubscribers()
+	if err := c.store.Close(); err != nil {
+		log.Printf("Error al cerrar el historial de métricas: %v", err)
+	}
 }
 
-// calculateStats calcula estadísticas básicas de un conjunto de valores
-func calculateStats(values []float64) StatInfo {
+// calculateStats calcula estadísticas básicas de un conjunto de valores y
+// las opcionales indicadas en opts. Los percentiles y el histograma se
+// obtienen de un Sketch en una sola pasada, sin ordenar los valores.
+func calculateStats(values []float64, opts StatsOptions) StatInfo {
 	if len(values) == 0 {
 		return StatInfo{}
 	}
@@ -222,11 +286,40 @@ func calculateStats(values []float64) StatInfo {
 	// Calcular raíz cuadrada (desviación estándar)
 	stdDev := math.Sqrt(variance)
 
-	return StatInfo{
+	stats := StatInfo{
 		Min:    min,
 		Max:    max,
 		Mean:   mean,
 		StdDev: stdDev,
 	}
+
+	if opts.Variance {
+		sampleVariance := 0.0
+		if len(values) > 1 {
+			sampleVariance = variance * float64(len(values)) / float64(len(values)-1)
+		}
+		stats.Variance = &sampleVariance
+	}
+
+	if opts.Percentiles || opts.HistogramBuckets > 0 {
+		sketch := NewSketch(DefaultSketchAccuracy)
+		for _, v := range values {
+			sketch.Add(v)
+		}
+		if opts.Percentiles {
+			quantile := func(q float64) *float64 {
+				v := sketch.Quantile(q)
+				return &v
+			}
+			stats.P50 = quantile(0.50)
+			stats.P90 = quantile(0.90)
+			stats.P95 = quantile(0.95)
+			stats.P99 = quantile(0.99)
+			stats.Median = stats.P50
+		}
+		stats.Histogram = sketch.Histogram(opts.HistogramBuckets)
+	}
+
+	return stats
 }
 
@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// closeTrackingStore registra si se agregaron muestras tras cerrarlo
+type closeTrackingStore struct {
+	*MemoryStore
+	mu          sync.Mutex
+	closed      bool
+	lateAppends int
+}
+
+func (s *closeTrackingStore) Append(m SystemMetrics) error {
+	s.mu.Lock()
+	if s.closed {
+		s.lateAppends++
+	}
+	s.mu.Unlock()
+	return s.MemoryStore.Append(m)
+}
+
+func (s *closeTrackingStore) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return s.MemoryStore.Close()
+}
+
+func TestCollectorStopWaitsForCollection(t *testing.T) {
+	store := &closeTrackingStore{MemoryStore: NewMemoryStore(10)}
+	c := NewCollectorWithStore(store)
+
+	done := make(chan struct{})
+	go func() {
+		c.StartCollection(time.Hour)
+		close(done)
+	}()
+	// La primera muestra tarda alrededor de dos segundos en medir la CPU
+	time.Sleep(100 * time.Millisecond)
+	c.Stop()
+
+	// La muestra en curso se guardó antes de cerrar el almacenamiento
+	samples, _ := store.Range(time.Time{}, time.Time{})
+	store.mu.Lock()
+	closed, late := store.closed, store.lateAppends
+	store.mu.Unlock()
+	if len(samples) != 1 || !closed || late != 0 {
+		t.Errorf("%d muestras, closed = %v, %d tras cerrar; se esperaba 1 muestra guardada antes del cierre", len(samples), closed, late)
+	}
+	<-done
+
+	// Iniciar tras Stop no recolecta nada
+	c.StartCollection(time.Hour)
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.lateAppends != 0 {
+		t.Errorf("StartCollection tras Stop agregó %d muestras", store.lateAppends)
+	}
+}
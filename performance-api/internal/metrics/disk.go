@@ -0,0 +1,247 @@
+package metrics
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// DiskInfo contiene la actividad de E/S por dispositivo y el uso de cada sistema de archivos
+type DiskInfo struct {
+	Devices     []DiskDeviceIO    `json:"devices,omitempty"`
+	Filesystems []FilesystemUsage `json:"filesystems,omitempty"`
+}
+
+// DiskDeviceIO contiene las tasas de E/S de un dispositivo calculadas entre dos muestras
+type DiskDeviceIO struct {
+	Name              string  `json:"name"`
+	ReadBytesPerSec   float64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec  float64 `json:"write_bytes_per_sec"`
+	ReadOpsPerSec     float64 `json:"read_ops_per_sec"`
+	WriteOpsPerSec    float64 `json:"write_ops_per_sec"`
+	ReadTimeMsPerSec  float64 `json:"read_time_ms_per_sec"`
+	WriteTimeMsPerSec float64 `json:"write_time_ms_per_sec"`
+	IOTimeMsPerSec    float64 `json:"io_time_ms_per_sec"` // Tiempo ocupado; 1000 equivale al 100% de utilización
+}
+
+// FilesystemUsage contiene el uso de un punto de montaje
+type FilesystemUsage struct {
+	Mountpoint  string  `json:"mountpoint"`
+	Device      string  `json:"device"`
+	Fstype      string  `json:"fstype"`
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	Free        uint64  `json:"free"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// Sufijos de una partición: solo dígitos si el dispositivo termina en una
+// letra (sda1) y con el separador p si termina en un dígito (nvme0n1p2,
+// mmcblk0p1), para que loop10 no se tome como partición de loop1
+var (
+	partitionSuffix      = regexp.MustCompile(`^[0-9]+$`)
+	digitPartitionSuffix = regexp.MustCompile(`^p[0-9]+$`)
+)
+
+// Total suma las tasas de los dispositivos completos, omitiendo las
+// particiones y los dispositivos de device-mapper (dm-*), que se apoyan en
+// otros de la lista, para no contar dos veces la misma E/S
+func (d DiskInfo) Total() DiskDeviceIO {
+	total := DiskDeviceIO{Name: "total"}
+	for _, dev := range d.Devices {
+		if strings.HasPrefix(dev.Name, "dm-") || isPartition(dev.Name, d.Devices) {
+			continue
+		}
+		total.ReadBytesPerSec += dev.ReadBytesPerSec
+		total.WriteBytesPerSec += dev.WriteBytesPerSec
+		total.ReadOpsPerSec += dev.ReadOpsPerSec
+		total.WriteOpsPerSec += dev.WriteOpsPerSec
+		total.ReadTimeMsPerSec += dev.ReadTimeMsPerSec
+		total.WriteTimeMsPerSec += dev.WriteTimeMsPerSec
+		total.IOTimeMsPerSec += dev.IOTimeMsPerSec
+	}
+	return total
+}
+
+// isPartition indica si name es una partición de otro dispositivo de la lista
+func isPartition(name string, devices []DiskDeviceIO) bool {
+	for _, dev := range devices {
+		if dev.Name == "" || dev.Name == name || !strings.HasPrefix(name, dev.Name) {
+			continue
+		}
+		suffix := partitionSuffix
+		if last := dev.Name[len(dev.Name)-1]; last >= '0' && last <= '9' {
+			suffix = digitPartitionSuffix
+		}
+		if suffix.MatchString(name[len(dev.Name):]) {
+			return true
+		}
+	}
+	return false
+}
+
+// diskSampler conserva los contadores de la muestra anterior para calcular tasas
+type diskSampler struct {
+	prev     map[string]disk.IOCountersStat
+	prevTime time.Time
+}
+
+// collect obtiene las tasas de E/S desde la muestra anterior y el uso de
+// los sistemas de archivos. En la primera muestra no hay tasas todavía.
+func (s *diskSampler) collect(now time.Time) DiskInfo {
+	var info DiskInfo
+
+	counters, err := disk.IOCounters()
+	if err == nil {
+		if s.prev != nil {
+			info.Devices = diskRates(s.prev, counters, now.Sub(s.prevTime).Seconds())
+		}
+		s.prev = counters
+		s.prevTime = now
+	}
+
+	partitions, err := disk.Partitions(false)
+	if err == nil {
+		seen := make(map[string]bool)
+		for _, p := range partitions {
+			if seen[p.Mountpoint] {
+				continue
+			}
+			seen[p.Mountpoint] = true
+
+			usage, err := disk.Usage(p.Mountpoint)
+			if err != nil || usage.Total == 0 {
+				continue
+			}
+			info.Filesystems = append(info.Filesystems, FilesystemUsage{
+				Mountpoint:  p.Mountpoint,
+				Device:      p.Device,
+				Fstype:      p.Fstype,
+				Total:       usage.Total,
+				Used:        usage.Used,
+				Free:        usage.Free,
+				UsedPercent: usage.UsedPercent,
+			})
+		}
+		sort.Slice(info.Filesystems, func(i, j int) bool {
+			return info.Filesystems[i].Mountpoint < info.Filesystems[j].Mountpoint
+		})
+	}
+
+	return info
+}
+
+// diskRates calcula las tasas por segundo entre dos lecturas de contadores.
+// Los dispositivos nuevos o cuyos contadores se reiniciaron se omiten.
+func diskRates(prev, cur map[string]disk.IOCountersStat, seconds float64) []DiskDeviceIO {
+	if seconds <= 0 {
+		return nil
+	}
+
+	devices := make([]DiskDeviceIO, 0, len(cur))
+	for name, c := range cur {
+		p, ok := prev[name]
+		if !ok || c.ReadBytes < p.ReadBytes || c.WriteBytes < p.WriteBytes ||
+			c.ReadCount < p.ReadCount || c.WriteCount < p.WriteCount ||
+			c.ReadTime < p.ReadTime || c.WriteTime < p.WriteTime || c.IoTime < p.IoTime {
+			continue
+		}
+		devices = append(devices, DiskDeviceIO{
+			Name:              name,
+			ReadBytesPerSec:   counterRate(p.ReadBytes, c.ReadBytes, seconds),
+			WriteBytesPerSec:  counterRate(p.WriteBytes, c.WriteBytes, seconds),
+			ReadOpsPerSec:     counterRate(p.ReadCount, c.ReadCount, seconds),
+			WriteOpsPerSec:    counterRate(p.WriteCount, c.WriteCount, seconds),
+			ReadTimeMsPerSec:  counterRate(p.ReadTime, c.ReadTime, seconds),
+			WriteTimeMsPerSec: counterRate(p.WriteTime, c.WriteTime, seconds),
+			IOTimeMsPerSec:    counterRate(p.IoTime, c.IoTime, seconds),
+		})
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+	return devices
+}
+
+// counterRate calcula la tasa por segundo de un contador monótono
+func counterRate(prev, cur uint64, seconds float64) float64 {
+	return float64(cur-prev) / seconds
+}
+
+// calculateDiskStats calcula estadísticas de las tasas agregadas de disco
+func calculateDiskStats(totals []DiskDeviceIO, opts StatsOptions) DiskStats {
+	field := func(get func(d DiskDeviceIO) float64) StatInfo {
+		values := make([]float64, len(totals))
+		for i, d := range totals {
+			values[i] = get(d)
+		}
+		return calculateStats(values, opts)
+	}
+
+	return DiskStats{
+		ReadBytesPerSec:  field(func(d DiskDeviceIO) float64 { return d.ReadBytesPerSec }),
+		WriteBytesPerSec: field(func(d DiskDeviceIO) float64 { return d.WriteBytesPerSec }),
+		ReadOpsPerSec:    field(func(d DiskDeviceIO) float64 { return d.ReadOpsPerSec }),
+		WriteOpsPerSec:   field(func(d DiskDeviceIO) float64 { return d.WriteOpsPerSec }),
+		IOTimeMsPerSec:   field(func(d DiskDeviceIO) float64 { return d.IOTimeMsPerSec }),
+	}
+}
+
+// aggregateDisk combina la información de disco de un grupo de muestras,
+// agrupando los dispositivos por nombre y los sistemas de archivos por punto de montaje
+func aggregateDisk(bucket []SystemMetrics, agg Aggregation) DiskInfo {
+	var result DiskInfo
+
+	devices := make(map[string][]DiskDeviceIO)
+	filesystems := make(map[string][]FilesystemUsage)
+	for _, m := range bucket {
+		for _, dev := range m.Disk.Devices {
+			devices[dev.Name] = append(devices[dev.Name], dev)
+		}
+		for _, fs := range m.Disk.Filesystems {
+			filesystems[fs.Mountpoint] = append(filesystems[fs.Mountpoint], fs)
+		}
+	}
+
+	for name, samples := range devices {
+		value := func(get func(d DiskDeviceIO) float64) float64 {
+			values := make([]float64, len(samples))
+			for i, d := range samples {
+				values[i] = get(d)
+			}
+			return aggregateValues(values, agg)
+		}
+		result.Devices = append(result.Devices, DiskDeviceIO{
+			Name:              name,
+			ReadBytesPerSec:   value(func(d DiskDeviceIO) float64 { return d.ReadBytesPerSec }),
+			WriteBytesPerSec:  value(func(d DiskDeviceIO) float64 { return d.WriteBytesPerSec }),
+			ReadOpsPerSec:     value(func(d DiskDeviceIO) float64 { return d.ReadOpsPerSec }),
+			WriteOpsPerSec:    value(func(d DiskDeviceIO) float64 { return d.WriteOpsPerSec }),
+			ReadTimeMsPerSec:  value(func(d DiskDeviceIO) float64 { return d.ReadTimeMsPerSec }),
+			WriteTimeMsPerSec: value(func(d DiskDeviceIO) float64 { return d.WriteTimeMsPerSec }),
+			IOTimeMsPerSec:    value(func(d DiskDeviceIO) float64 { return d.IOTimeMsPerSec }),
+		})
+	}
+	sort.Slice(result.Devices, func(i, j int) bool { return result.Devices[i].Name < result.Devices[j].Name })
+
+	for _, samples := range filesystems {
+		fs := samples[len(samples)-1]
+		value := func(get func(f FilesystemUsage) float64) float64 {
+			values := make([]float64, len(samples))
+			for i, f := range samples {
+				values[i] = get(f)
+			}
+			return aggregateValues(values, agg)
+		}
+		fs.Used = uint64(value(func(f FilesystemUsage) float64 { return float64(f.Used) }))
+		fs.Free = uint64(value(func(f FilesystemUsage) float64 { return float64(f.Free) }))
+		fs.UsedPercent = value(func(f FilesystemUsage) float64 { return f.UsedPercent })
+		result.Filesystems = append(result.Filesystems, fs)
+	}
+	sort.Slice(result.Filesystems, func(i, j int) bool {
+		return result.Filesystems[i].Mountpoint < result.Filesystems[j].Mountpoint
+	})
+
+	return result
+}
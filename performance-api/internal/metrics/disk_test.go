@@ -0,0 +1,30 @@
+package metrics
+
+import "testing"
+
+func TestDiskTotalSkipsPartitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		devices []string
+		want    float64 // Dispositivos sumados, cada uno con 1 byte/s de lectura
+	}{
+		{"disco y particiones", []string{"sda", "sda1", "sda2"}, 1},
+		{"nvme con separador p", []string{"nvme0n1", "nvme0n1p1", "nvme0n1p2"}, 1},
+		{"nvme con diez espacios de nombres", []string{"nvme0n1", "nvme0n10"}, 2},
+		{"loop", []string{"loop1", "loop10", "loop11"}, 3},
+		{"mmc", []string{"mmcblk0", "mmcblk0p1"}, 1},
+		{"device-mapper", []string{"sda", "sda1", "dm-0", "dm-1"}, 1},
+		{"partición sin su disco", []string{"sdb1"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info DiskInfo
+			for _, name := range tt.devices {
+				info.Devices = append(info.Devices, DiskDeviceIO{Name: name, ReadBytesPerSec: 1})
+			}
+			if got := info.Total().ReadBytesPerSec; got != tt.want {
+				t.Errorf("Total().ReadBytesPerSec = %g, se esperaba %g", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import "sort"
+
+// FieldFunc extrae un valor numérico de una muestra
+type FieldFunc func(m SystemMetrics) float64
+
+// fields asocia nombres con notación de puntos, como en el JSON de
+// /api/metrics, a los valores numéricos de una muestra. Disco y red son la
+// suma de todos los dispositivos e interfaces.
+var fields = map[string]FieldFunc{
+	"cpu.percent":                        func(m SystemMetrics) float64 { return m.CPU.Percent },
+	"memory.used":                        func(m SystemMetrics) float64 { return float64(m.Memory.Used) },
+	"memory.available":                   func(m SystemMetrics) float64 { return float64(m.Memory.Available) },
+	"memory.free":                        func(m SystemMetrics) float64 { return float64(m.Memory.Free) },
+	"memory.used_percent":                func(m SystemMetrics) float64 { return m.Memory.UsedPercent },
+	"goroutines":                         func(m SystemMetrics) float64 { return float64(m.Goroutines) },
+	"disk.read_bytes_per_sec":            func(m SystemMetrics) float64 { return m.Disk.Total().ReadBytesPerSec },
+	"disk.write_bytes_per_sec":           func(m SystemMetrics) float64 { return m.Disk.Total().WriteBytesPerSec },
+	"disk.io_time_ms_per_sec":            func(m SystemMetrics) float64 { return m.Disk.Total().IOTimeMsPerSec },
+	"network.rx_bytes_per_sec":           func(m SystemMetrics) float64 { return m.Network.Total().RxBytesPerSec },
+	"network.tx_bytes_per_sec":           func(m SystemMetrics) float64 { return m.Network.Total().TxBytesPerSec },
+	"network.rx_errors_per_sec":          func(m SystemMetrics) float64 { return m.Network.Total().RxErrorsPerSec },
+	"network.tx_errors_per_sec":          func(m SystemMetrics) float64 { return m.Network.Total().TxErrorsPerSec },
+	"runtime.heap_bytes":                 func(m SystemMetrics) float64 { return float64(m.Runtime.HeapBytes) },
+	"runtime.heap_objects":               func(m SystemMetrics) float64 { return float64(m.Runtime.HeapObjects) },
+	"runtime.heap_goal_bytes":            func(m SystemMetrics) float64 { return float64(m.Runtime.HeapGoalBytes) },
+	"runtime.alloc_bytes_per_sec":        func(m SystemMetrics) float64 { return m.Runtime.AllocBytesPerSec },
+	"runtime.mutex_wait_seconds_per_sec": func(m SystemMetrics) float64 { return m.Runtime.MutexWaitPerSec },
+	"runtime.gc_pauses.p99":              func(m SystemMetrics) float64 { return m.Runtime.GCPauses.P99 },
+	"runtime.sched_latencies.p99":        func(m SystemMetrics) float64 { return m.Runtime.SchedLatencies.P99 },
+}
+
+// LookupField retorna la función que extrae el campo indicado de una muestra
+func LookupField(name string) (FieldFunc, bool) {
+	field, ok := fields[name]
+	return field, ok
+}
+
+// FieldNames retorna los nombres de los campos disponibles, ordenados
+func FieldNames() []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,190 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// NetworkInfo contiene el tráfico por interfaz y las conexiones TCP por estado
+type NetworkInfo struct {
+	Interfaces     []NetworkInterfaceIO `json:"interfaces,omitempty"`
+	TCPConnections map[string]int       `json:"tcp_connections,omitempty"`
+}
+
+// NetworkInterfaceIO contiene las tasas de una interfaz calculadas entre dos muestras
+type NetworkInterfaceIO struct {
+	Name            string  `json:"name"`
+	RxBytesPerSec   float64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec   float64 `json:"tx_bytes_per_sec"`
+	RxPacketsPerSec float64 `json:"rx_packets_per_sec"`
+	TxPacketsPerSec float64 `json:"tx_packets_per_sec"`
+	RxErrorsPerSec  float64 `json:"rx_errors_per_sec"`
+	TxErrorsPerSec  float64 `json:"tx_errors_per_sec"`
+	RxDropsPerSec   float64 `json:"rx_drops_per_sec"`
+	TxDropsPerSec   float64 `json:"tx_drops_per_sec"`
+}
+
+// Total suma las tasas de todas las interfaces
+func (n NetworkInfo) Total() NetworkInterfaceIO {
+	total := NetworkInterfaceIO{Name: "total"}
+	for _, iface := range n.Interfaces {
+		total.RxBytesPerSec += iface.RxBytesPerSec
+		total.TxBytesPerSec += iface.TxBytesPerSec
+		total.RxPacketsPerSec += iface.RxPacketsPerSec
+		total.TxPacketsPerSec += iface.TxPacketsPerSec
+		total.RxErrorsPerSec += iface.RxErrorsPerSec
+		total.TxErrorsPerSec += iface.TxErrorsPerSec
+		total.RxDropsPerSec += iface.RxDropsPerSec
+		total.TxDropsPerSec += iface.TxDropsPerSec
+	}
+	return total
+}
+
+// networkSampler conserva los contadores de la muestra anterior para calcular tasas
+type networkSampler struct {
+	prev     map[string]net.IOCountersStat
+	prevTime time.Time
+}
+
+// collect obtiene las tasas por interfaz desde la muestra anterior y el
+// número de conexiones TCP en cada estado
+func (s *networkSampler) collect(now time.Time) NetworkInfo {
+	var info NetworkInfo
+
+	counters, err := net.IOCounters(true)
+	if err == nil {
+		current := make(map[string]net.IOCountersStat, len(counters))
+		for _, c := range counters {
+			current[c.Name] = c
+		}
+		if s.prev != nil {
+			info.Interfaces = networkRates(s.prev, current, now.Sub(s.prevTime).Seconds())
+		}
+		s.prev = current
+		s.prevTime = now
+	}
+
+	conns, err := net.ConnectionsWithoutUids("tcp")
+	if err == nil {
+		info.TCPConnections = make(map[string]int)
+		for _, conn := range conns {
+			status := conn.Status
+			if status == "" {
+				status = "UNKNOWN"
+			}
+			info.TCPConnections[status]++
+		}
+	}
+
+	return info
+}
+
+// networkRates calcula las tasas por segundo entre dos lecturas de contadores.
+// Las interfaces nuevas o cuyos contadores se reiniciaron se omiten.
+func networkRates(prev, cur map[string]net.IOCountersStat, seconds float64) []NetworkInterfaceIO {
+	if seconds <= 0 {
+		return nil
+	}
+
+	interfaces := make([]NetworkInterfaceIO, 0, len(cur))
+	for name, c := range cur {
+		p, ok := prev[name]
+		if !ok || c.BytesRecv < p.BytesRecv || c.BytesSent < p.BytesSent ||
+			c.PacketsRecv < p.PacketsRecv || c.PacketsSent < p.PacketsSent ||
+			c.Errin < p.Errin || c.Errout < p.Errout || c.Dropin < p.Dropin || c.Dropout < p.Dropout {
+			continue
+		}
+		interfaces = append(interfaces, NetworkInterfaceIO{
+			Name:            name,
+			RxBytesPerSec:   counterRate(p.BytesRecv, c.BytesRecv, seconds),
+			TxBytesPerSec:   counterRate(p.BytesSent, c.BytesSent, seconds),
+			RxPacketsPerSec: counterRate(p.PacketsRecv, c.PacketsRecv, seconds),
+			TxPacketsPerSec: counterRate(p.PacketsSent, c.PacketsSent, seconds),
+			RxErrorsPerSec:  counterRate(p.Errin, c.Errin, seconds),
+			TxErrorsPerSec:  counterRate(p.Errout, c.Errout, seconds),
+			RxDropsPerSec:   counterRate(p.Dropin, c.Dropin, seconds),
+			TxDropsPerSec:   counterRate(p.Dropout, c.Dropout, seconds),
+		})
+	}
+	sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+	return interfaces
+}
+
+// calculateNetworkStats calcula estadísticas de las tasas agregadas de red
+func calculateNetworkStats(totals []NetworkInterfaceIO, opts StatsOptions) NetworkStats {
+	field := func(get func(n NetworkInterfaceIO) float64) StatInfo {
+		values := make([]float64, len(totals))
+		for i, n := range totals {
+			values[i] = get(n)
+		}
+		return calculateStats(values, opts)
+	}
+
+	return NetworkStats{
+		RxBytesPerSec:   field(func(n NetworkInterfaceIO) float64 { return n.RxBytesPerSec }),
+		TxBytesPerSec:   field(func(n NetworkInterfaceIO) float64 { return n.TxBytesPerSec }),
+		RxPacketsPerSec: field(func(n NetworkInterfaceIO) float64 { return n.RxPacketsPerSec }),
+		TxPacketsPerSec: field(func(n NetworkInterfaceIO) float64 { return n.TxPacketsPerSec }),
+		ErrorsPerSec:    field(func(n NetworkInterfaceIO) float64 { return n.RxErrorsPerSec + n.TxErrorsPerSec }),
+		DropsPerSec:     field(func(n NetworkInterfaceIO) float64 { return n.RxDropsPerSec + n.TxDropsPerSec }),
+	}
+}
+
+// aggregateNetwork combina la información de red de un grupo de muestras,
+// agrupando por interfaz y por estado de conexión TCP
+func aggregateNetwork(bucket []SystemMetrics, agg Aggregation) NetworkInfo {
+	var result NetworkInfo
+
+	interfaces := make(map[string][]NetworkInterfaceIO)
+	states := make(map[string][]float64)
+	for i, m := range bucket {
+		for _, iface := range m.Network.Interfaces {
+			interfaces[iface.Name] = append(interfaces[iface.Name], iface)
+		}
+		for state, count := range m.Network.TCPConnections {
+			if states[state] == nil {
+				// Un estado ausente en una muestra equivale a cero conexiones
+				states[state] = make([]float64, i, len(bucket))
+			}
+			states[state] = append(states[state], float64(count))
+		}
+		for state, counts := range states {
+			if len(counts) == i {
+				states[state] = append(counts, 0)
+			}
+		}
+	}
+
+	for name, samples := range interfaces {
+		value := func(get func(n NetworkInterfaceIO) float64) float64 {
+			values := make([]float64, len(samples))
+			for i, n := range samples {
+				values[i] = get(n)
+			}
+			return aggregateValues(values, agg)
+		}
+		result.Interfaces = append(result.Interfaces, NetworkInterfaceIO{
+			Name:            name,
+			RxBytesPerSec:   value(func(n NetworkInterfaceIO) float64 { return n.RxBytesPerSec }),
+			TxBytesPerSec:   value(func(n NetworkInterfaceIO) float64 { return n.TxBytesPerSec }),
+			RxPacketsPerSec: value(func(n NetworkInterfaceIO) float64 { return n.RxPacketsPerSec }),
+			TxPacketsPerSec: value(func(n NetworkInterfaceIO) float64 { return n.TxPacketsPerSec }),
+			RxErrorsPerSec:  value(func(n NetworkInterfaceIO) float64 { return n.RxErrorsPerSec }),
+			TxErrorsPerSec:  value(func(n NetworkInterfaceIO) float64 { return n.TxErrorsPerSec }),
+			RxDropsPerSec:   value(func(n NetworkInterfaceIO) float64 { return n.RxDropsPerSec }),
+			TxDropsPerSec:   value(func(n NetworkInterfaceIO) float64 { return n.TxDropsPerSec }),
+		})
+	}
+	sort.Slice(result.Interfaces, func(i, j int) bool { return result.Interfaces[i].Name < result.Interfaces[j].Name })
+
+	if len(states) > 0 {
+		result.TCPConnections = make(map[string]int, len(states))
+		for state, counts := range states {
+			result.TCPConnections[state] = int(aggregateValues(counts, agg) + 0.5)
+		}
+	}
+
+	return result
+}
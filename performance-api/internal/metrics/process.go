@@ -0,0 +1,507 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Estados posibles de un proceso supervisado
+const (
+	ProcessRunning = "running" // El proceso existe y se está midiendo
+	ProcessWaiting = "waiting" // Ningún proceso coincide todavía con el patrón
+	ProcessExited  = "exited"  // El proceso terminó (solo para registros por PID)
+)
+
+// ErrProcessWatchNotFound indica que no existe una supervisión con el ID indicado
+var ErrProcessWatchNotFound = errors.New("supervisión de proceso no encontrada")
+
+// ProcessTarget identifica el proceso a supervisar: un PID o un patrón
+// (expresión regular) que se compara con el nombre y la línea de comandos
+type ProcessTarget struct {
+	PID  int32  `json:"pid,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// ProcessMetrics representa una muestra de un proceso supervisado
+type ProcessMetrics struct {
+	Timestamp              time.Time `json:"timestamp"`
+	PID                    int32     `json:"pid"`
+	Name                   string    `json:"name"`
+	CPUPercent             float64   `json:"cpu_percent"` // 100 equivale a un núcleo completo
+	RSS                    uint64    `json:"rss"`
+	VMS                    uint64    `json:"vms"`
+	Threads                int32     `json:"threads"`
+	OpenFDs                int32     `json:"open_fds"`
+	VoluntaryCtxSwitches   int64     `json:"voluntary_ctx_switches"`
+	InvoluntaryCtxSwitches int64     `json:"involuntary_ctx_switches"`
+	CtxSwitchesPerSec      float64   `json:"ctx_switches_per_sec"`
+	IO                     ProcessIO `json:"io"`
+}
+
+// ProcessIO contiene los contadores de E/S de un proceso y sus tasas
+type ProcessIO struct {
+	ReadBytes        uint64  `json:"read_bytes"`
+	WriteBytes       uint64  `json:"write_bytes"`
+	ReadCount        uint64  `json:"read_count"`
+	WriteCount       uint64  `json:"write_count"`
+	ReadBytesPerSec  float64 `json:"read_bytes_per_sec"`
+	WriteBytesPerSec float64 `json:"write_bytes_per_sec"`
+}
+
+// ProcessWatchInfo describe una supervisión registrada
+type ProcessWatchInfo struct {
+	ID          string          `json:"id"`
+	Target      ProcessTarget   `json:"target"`
+	Status      string          `json:"status"`
+	PID         int32           `json:"pid,omitempty"` // PID del proceso actualmente asociado
+	Restarts    int             `json:"restarts"`      // Veces que el patrón se asoció a un proceso nuevo
+	CreatedAt   time.Time       `json:"created_at"`
+	ExitedAt    *time.Time      `json:"exited_at,omitempty"`
+	Latest      *ProcessMetrics `json:"latest,omitempty"`
+	SampleCount int             `json:"sample_count"`
+}
+
+// ProcessStatistics contiene estadísticas del historial de un proceso
+type ProcessStatistics struct {
+	SampleCount       int       `json:"sample_count"`
+	TimeRange         TimeRange `json:"time_range"`
+	CPUPercent        StatInfo  `json:"cpu_percent"`
+	RSS               StatInfo  `json:"rss"`
+	VMS               StatInfo  `json:"vms"`
+	Threads           StatInfo  `json:"threads"`
+	OpenFDs           StatInfo  `json:"open_fds"`
+	CtxSwitchesPerSec StatInfo  `json:"ctx_switches_per_sec"`
+	ReadBytesPerSec   StatInfo  `json:"read_bytes_per_sec"`
+	WriteBytesPerSec  StatInfo  `json:"write_bytes_per_sec"`
+}
+
+// ProcessWatcher supervisa procesos externos y guarda su historial
+type ProcessWatcher struct {
+	mu         sync.RWMutex
+	watches    map[string]*processWatch
+	nextID     int
+	maxHistory int
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// processWatch es el estado interno de una supervisión
+type processWatch struct {
+	info       ProcessWatchInfo
+	pattern    *regexp.Regexp
+	proc       *process.Process
+	createTime int64
+	history    []ProcessMetrics
+	prev       *processCounters
+}
+
+// processCounters guarda los contadores acumulados de la muestra anterior
+type processCounters struct {
+	time        time.Time
+	cpuSeconds  float64
+	ctxSwitches int64
+	readBytes   uint64
+	writeBytes  uint64
+}
+
+// NewProcessWatcher crea un supervisor de procesos que conserva maxHistory muestras por proceso
+func NewProcessWatcher(maxHistory int) *ProcessWatcher {
+	if maxHistory <= 0 {
+		maxHistory = 100
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ProcessWatcher{
+		watches:    make(map[string]*processWatch),
+		maxHistory: maxHistory,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// StartCollection inicia la recolección periódica de los procesos supervisados
+func (w *ProcessWatcher) StartCollection(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.collect()
+		}
+	}
+}
+
+// Stop detiene la recolección
+func (w *ProcessWatcher) Stop() {
+	w.cancel()
+}
+
+// Watch registra un proceso a supervisar y toma su primera muestra
+func (w *ProcessWatcher) Watch(target ProcessTarget) (*ProcessWatchInfo, error) {
+	watch := &processWatch{
+		info: ProcessWatchInfo{
+			Target:    target,
+			Status:    ProcessWaiting,
+			CreatedAt: time.Now(),
+		},
+	}
+
+	switch {
+	case target.PID > 0 && target.Name != "":
+		return nil, errors.New("indica pid o name, no ambos")
+	case target.PID > 0:
+		proc, err := process.NewProcess(target.PID)
+		if err != nil {
+			return nil, fmt.Errorf("no existe un proceso con PID %d", target.PID)
+		}
+		if err := watch.bind(proc); err != nil {
+			return nil, err
+		}
+	case target.Name != "":
+		pattern, err := regexp.Compile(target.Name)
+		if err != nil {
+			return nil, fmt.Errorf("patrón de nombre inválido: %w", err)
+		}
+		watch.pattern = pattern
+		watch.findMatch()
+	default:
+		return nil, errors.New("se requiere pid o name")
+	}
+
+	if m, ok := watch.sample(time.Now()); ok {
+		watch.record(m, w.maxHistory)
+	}
+
+	w.mu.Lock()
+	w.nextID++
+	watch.info.ID = strconv.Itoa(w.nextID)
+	w.watches[watch.info.ID] = watch
+	info := watch.snapshot()
+	w.mu.Unlock()
+
+	return &info, nil
+}
+
+// Unwatch elimina una supervisión y su historial
+func (w *ProcessWatcher) Unwatch(id string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, ok := w.watches[id]; !ok {
+		return ErrProcessWatchNotFound
+	}
+	delete(w.watches, id)
+	return nil
+}
+
+// ListWatches retorna las supervisiones registradas ordenadas por ID
+func (w *ProcessWatcher) ListWatches() []ProcessWatchInfo {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	list := make([]ProcessWatchInfo, 0, len(w.watches))
+	for _, watch := range w.watches {
+		list = append(list, watch.snapshot())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, _ := strconv.Atoi(list[i].ID)
+		b, _ := strconv.Atoi(list[j].ID)
+		return a < b
+	})
+	return list
+}
+
+// GetWatch retorna el estado de una supervisión
+func (w *ProcessWatcher) GetWatch(id string) (*ProcessWatchInfo, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	watch, ok := w.watches[id]
+	if !ok {
+		return nil, ErrProcessWatchNotFound
+	}
+	info := watch.snapshot()
+	return &info, nil
+}
+
+// GetHistory retorna el historial de muestras de un proceso supervisado
+func (w *ProcessWatcher) GetHistory(id string) ([]ProcessMetrics, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	watch, ok := w.watches[id]
+	if !ok {
+		return nil, ErrProcessWatchNotFound
+	}
+	history := make([]ProcessMetrics, len(watch.history))
+	copy(history, watch.history)
+	return history, nil
+}
+
+// GetStats calcula estadísticas del historial de un proceso supervisado.
+// Retorna nil si todavía no hay muestras.
+func (w *ProcessWatcher) GetStats(id string, opts StatsOptions) (*ProcessStatistics, error) {
+	history, err := w.GetHistory(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, nil
+	}
+
+	field := func(get func(m ProcessMetrics) float64) StatInfo {
+		values := make([]float64, len(history))
+		for i, m := range history {
+			values[i] = get(m)
+		}
+		return calculateStats(values, opts)
+	}
+
+	return &ProcessStatistics{
+		SampleCount: len(history),
+		TimeRange: TimeRange{
+			Start: history[0].Timestamp,
+			End:   history[len(history)-1].Timestamp,
+		},
+		CPUPercent:        field(func(m ProcessMetrics) float64 { return m.CPUPercent }),
+		RSS:               field(func(m ProcessMetrics) float64 { return float64(m.RSS) }),
+		VMS:               field(func(m ProcessMetrics) float64 { return float64(m.VMS) }),
+		Threads:           field(func(m ProcessMetrics) float64 { return float64(m.Threads) }),
+		OpenFDs:           field(func(m ProcessMetrics) float64 { return float64(m.OpenFDs) }),
+		CtxSwitchesPerSec: field(func(m ProcessMetrics) float64 { return m.CtxSwitchesPerSec }),
+		ReadBytesPerSec:   field(func(m ProcessMetrics) float64 { return m.IO.ReadBytesPerSec }),
+		WriteBytesPerSec:  field(func(m ProcessMetrics) float64 { return m.IO.WriteBytesPerSec }),
+	}, nil
+}
+
+// pendingSample es la muestra de una supervisión tomada sobre una copia de
+// su estado, que se aplica al terminar la ronda
+type pendingSample struct {
+	watch   *processWatch
+	state   processWatch
+	metrics ProcessMetrics
+	ok      bool
+}
+
+// collect toma una muestra de cada proceso supervisado. Las muestras se
+// toman sobre copias del estado sin retener el lock, porque buscar un
+// patrón recorre todos los procesos del sistema.
+func (w *ProcessWatcher) collect() {
+	w.mu.RLock()
+	pending := make([]pendingSample, 0, len(w.watches))
+	for _, watch := range w.watches {
+		pending = append(pending, pendingSample{watch: watch, state: *watch})
+	}
+	w.mu.RUnlock()
+
+	now := time.Now()
+	for i := range pending {
+		pending[i].metrics, pending[i].ok = pending[i].state.sample(now)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, p := range pending {
+		if w.watches[p.watch.info.ID] != p.watch {
+			// Se eliminó la supervisión durante la ronda
+			continue
+		}
+		history := p.watch.history
+		*p.watch = p.state
+		p.watch.history = history
+		if p.ok {
+			p.watch.record(p.metrics, w.maxHistory)
+		}
+	}
+}
+
+// bind asocia la supervisión a un proceso concreto
+func (pw *processWatch) bind(proc *process.Process) error {
+	createTime, err := proc.CreateTime()
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el proceso %d: %w", proc.Pid, err)
+	}
+	pw.proc = proc
+	pw.createTime = createTime
+	pw.prev = nil
+	pw.info.Status = ProcessRunning
+	pw.info.PID = proc.Pid
+	pw.info.ExitedAt = nil
+	return nil
+}
+
+// findMatch busca el proceso más antiguo que coincide con el patrón
+func (pw *processWatch) findMatch() bool {
+	procs, err := process.Processes()
+	if err != nil {
+		return false
+	}
+
+	self := int32(os.Getpid())
+	var best *process.Process
+	var bestCreate int64
+	for _, proc := range procs {
+		if proc.Pid == self {
+			continue
+		}
+		name, _ := proc.Name()
+		cmdline, _ := proc.Cmdline()
+		if !pw.pattern.MatchString(name) && !pw.pattern.MatchString(cmdline) {
+			continue
+		}
+		createTime, err := proc.CreateTime()
+		if err != nil {
+			continue
+		}
+		if best == nil || createTime < bestCreate {
+			best, bestCreate = proc, createTime
+		}
+	}
+
+	return best != nil && pw.bind(best) == nil
+}
+
+// alive verifica que el proceso asociado siga existiendo y no sea otro
+// proceso que reutilizó el mismo PID
+func (pw *processWatch) alive() bool {
+	if pw.proc == nil {
+		return false
+	}
+	running, err := pw.proc.IsRunning()
+	if err != nil || !running {
+		return false
+	}
+	if createTime, err := pw.proc.CreateTime(); err != nil || createTime != pw.createTime {
+		return false
+	}
+	if status, err := pw.proc.Status(); err == nil && len(status) > 0 && status[0] == process.Zombie {
+		return false
+	}
+	return true
+}
+
+// sample toma una muestra del proceso asociado, detectando su salida y,
+// para los patrones, asociando un proceso nuevo si el anterior terminó.
+// No modifica el historial; la muestra se agrega con record.
+func (pw *processWatch) sample(now time.Time) (ProcessMetrics, bool) {
+	if pw.info.Status == ProcessRunning && !pw.alive() {
+		pw.markExited(now)
+	}
+
+	if pw.info.Status != ProcessRunning {
+		if pw.pattern == nil || !pw.findMatch() {
+			return ProcessMetrics{}, false
+		}
+		if len(pw.history) > 0 {
+			pw.info.Restarts++
+		}
+	}
+
+	m, ok := pw.read(now)
+	if !ok {
+		pw.markExited(now)
+		return ProcessMetrics{}, false
+	}
+	return m, true
+}
+
+// record agrega una muestra al historial conservando las maxHistory más recientes
+func (pw *processWatch) record(m ProcessMetrics, maxHistory int) {
+	pw.history = append(pw.history, m)
+	if len(pw.history) > maxHistory {
+		pw.history = pw.history[1:]
+	}
+}
+
+// markExited registra que el proceso asociado terminó
+func (pw *processWatch) markExited(now time.Time) {
+	pw.proc = nil
+	pw.prev = nil
+	pw.info.PID = 0
+	if pw.pattern != nil {
+		pw.info.Status = ProcessWaiting
+	} else {
+		pw.info.Status = ProcessExited
+	}
+	exitedAt := now
+	pw.info.ExitedAt = &exitedAt
+}
+
+// read obtiene las métricas del proceso asociado y calcula tasas respecto
+// a la muestra anterior. Retorna false si el proceso ya no existe.
+func (pw *processWatch) read(now time.Time) (ProcessMetrics, bool) {
+	proc := pw.proc
+	m := ProcessMetrics{Timestamp: now, PID: proc.Pid}
+
+	times, err := proc.Times()
+	if err != nil {
+		return m, false
+	}
+	m.Name, _ = proc.Name()
+
+	if mem, err := proc.MemoryInfo(); err == nil {
+		m.RSS = mem.RSS
+		m.VMS = mem.VMS
+	}
+	m.Threads, _ = proc.NumThreads()
+	m.OpenFDs, _ = proc.NumFDs()
+	if ctx, err := proc.NumCtxSwitches(); err == nil {
+		m.VoluntaryCtxSwitches = ctx.Voluntary
+		m.InvoluntaryCtxSwitches = ctx.Involuntary
+	}
+	if io, err := proc.IOCounters(); err == nil {
+		m.IO.ReadBytes = io.ReadBytes
+		m.IO.WriteBytes = io.WriteBytes
+		m.IO.ReadCount = io.ReadCount
+		m.IO.WriteCount = io.WriteCount
+	}
+
+	current := &processCounters{
+		time:        now,
+		cpuSeconds:  times.User + times.System,
+		ctxSwitches: m.VoluntaryCtxSwitches + m.InvoluntaryCtxSwitches,
+		readBytes:   m.IO.ReadBytes,
+		writeBytes:  m.IO.WriteBytes,
+	}
+	if prev := pw.prev; prev != nil {
+		if seconds := now.Sub(prev.time).Seconds(); seconds > 0 {
+			if current.cpuSeconds >= prev.cpuSeconds {
+				m.CPUPercent = (current.cpuSeconds - prev.cpuSeconds) / seconds * 100
+			}
+			if current.ctxSwitches >= prev.ctxSwitches {
+				m.CtxSwitchesPerSec = float64(current.ctxSwitches-prev.ctxSwitches) / seconds
+			}
+			if current.readBytes >= prev.readBytes {
+				m.IO.ReadBytesPerSec = counterRate(prev.readBytes, current.readBytes, seconds)
+			}
+			if current.writeBytes >= prev.writeBytes {
+				m.IO.WriteBytesPerSec = counterRate(prev.writeBytes, current.writeBytes, seconds)
+			}
+		}
+	}
+	pw.prev = current
+
+	return m, true
+}
+
+// snapshot retorna una copia del estado público de la supervisión
+func (pw *processWatch) snapshot() ProcessWatchInfo {
+	info := pw.info
+	info.SampleCount = len(pw.history)
+	if n := len(pw.history); n > 0 {
+		latest := pw.history[n-1]
+		info.Latest = &latest
+	}
+	return info
+}
@@ -0,0 +1,128 @@
+//go:build unix
+
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"testing"
+)
+
+// spawnSleep lanza un sleep identificable por arg y lo termina al finalizar la prueba
+func spawnSleep(t *testing.T, arg string) *exec.Cmd {
+	t.Helper()
+	cmd := exec.Command("sleep", arg)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("no se pudo lanzar sleep: %v", err)
+	}
+	t.Cleanup(func() { killAndReap(cmd) })
+	return cmd
+}
+
+// killAndReap termina el proceso y lo recoge para que no quede como zombie
+func killAndReap(cmd *exec.Cmd) {
+	if cmd.ProcessState == nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+}
+
+// checkWatch verifica el estado, el PID, los reinicios y el historial de una supervisión
+func checkWatch(t *testing.T, w *ProcessWatcher, id, status string, pid, restarts, samples int) {
+	t.Helper()
+	info, err := w.GetWatch(id)
+	if err != nil {
+		t.Fatalf("GetWatch: %v", err)
+	}
+	if info.Status != status || int(info.PID) != pid || info.Restarts != restarts || info.SampleCount != samples {
+		t.Fatalf("supervisión = {%s pid %d, %d reinicios, %d muestras}, se esperaba {%s pid %d, %d reinicios, %d muestras}",
+			info.Status, info.PID, info.Restarts, info.SampleCount, status, pid, restarts, samples)
+	}
+	if status == ProcessRunning && info.ExitedAt != nil || status == ProcessExited && info.ExitedAt == nil {
+		t.Errorf("ExitedAt = %v con estado %s", info.ExitedAt, status)
+	}
+}
+
+func TestProcessWatcherByPID(t *testing.T) {
+	w := NewProcessWatcher(10)
+	child := spawnSleep(t, "3600")
+	pid := child.Process.Pid
+
+	info, err := w.Watch(ProcessTarget{PID: int32(pid)})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	checkWatch(t, w, info.ID, ProcessRunning, pid, 0, 1)
+	w.collect()
+	checkWatch(t, w, info.ID, ProcessRunning, pid, 0, 2)
+
+	// Al terminar queda en exited y conserva el historial
+	killAndReap(child)
+	w.collect()
+	checkWatch(t, w, info.ID, ProcessExited, 0, 0, 2)
+	w.collect()
+	checkWatch(t, w, info.ID, ProcessExited, 0, 0, 2)
+
+	if history, _ := w.GetHistory(info.ID); len(history) != 2 || history[0].PID != int32(pid) {
+		t.Errorf("historial = %v, se esperaban 2 muestras del PID %d", history, pid)
+	}
+}
+
+func TestProcessWatcherByName(t *testing.T) {
+	// Un argumento único para no coincidir con otros procesos del sistema
+	arg := fmt.Sprintf("3600.%d", os.Getpid())
+	w := NewProcessWatcher(10)
+
+	info, err := w.Watch(ProcessTarget{Name: "sleep " + regexp.QuoteMeta(arg)})
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	checkWatch(t, w, info.ID, ProcessWaiting, 0, 0, 0)
+
+	// El primer proceso que aparece se asocia sin contar un reinicio
+	first := spawnSleep(t, arg)
+	w.collect()
+	checkWatch(t, w, info.ID, ProcessRunning, first.Process.Pid, 0, 1)
+	w.collect()
+	checkWatch(t, w, info.ID, ProcessRunning, first.Process.Pid, 0, 2)
+
+	killAndReap(first)
+	w.collect()
+	checkWatch(t, w, info.ID, ProcessWaiting, 0, 0, 2)
+
+	// Una nueva instancia cuenta como reinicio y se agrega al mismo historial
+	second := spawnSleep(t, arg)
+	w.collect()
+	checkWatch(t, w, info.ID, ProcessRunning, second.Process.Pid, 1, 3)
+
+	history, _ := w.GetHistory(info.ID)
+	if history[0].PID != int32(first.Process.Pid) || history[2].PID != int32(second.Process.Pid) {
+		t.Errorf("PIDs del historial = %d, %d, %d; se esperaban %d, %d, %d", history[0].PID, history[1].PID, history[2].PID,
+			first.Process.Pid, first.Process.Pid, second.Process.Pid)
+	}
+}
+
+func TestProcessWatcherRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		target ProcessTarget
+	}{
+		{"vacío", ProcessTarget{}},
+		{"pid y nombre", ProcessTarget{PID: int32(os.Getpid()), Name: "x"}},
+		{"patrón inválido", ProcessTarget{Name: "("}},
+		{"pid inexistente", ProcessTarget{PID: 1 << 30}},
+	}
+	w := NewProcessWatcher(10)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := w.Watch(tt.target); err == nil {
+				t.Error("se esperaba un error")
+			}
+		})
+	}
+	if err := w.Unwatch("99"); err != ErrProcessWatchNotFound {
+		t.Errorf("Unwatch = %v, se esperaba ErrProcessWatchNotFound", err)
+	}
+}
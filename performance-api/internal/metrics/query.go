@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Aggregation define cómo se combinan las muestras de un mismo intervalo
+type Aggregation string
+
+const (
+	AggregationAvg  Aggregation = "avg"
+	AggregationMin  Aggregation = "min"
+	AggregationMax  Aggregation = "max"
+	AggregationLast Aggregation = "last"
+)
+
+// ParseAggregation valida el nombre de una agregación (vacío equivale a avg)
+func ParseAggregation(name string) (Aggregation, error) {
+	switch agg := Aggregation(name); agg {
+	case "":
+		return AggregationAvg, nil
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast:
+		return agg, nil
+	default:
+		return "", fmt.Errorf("agregación desconocida %q (usa avg, min, max o last)", name)
+	}
+}
+
+// HistoryQuery describe una consulta sobre el historial de métricas
+type HistoryQuery struct {
+	From        time.Time     // Inicio del rango (cero = sin límite)
+	To          time.Time     // Fin del rango (cero = sin límite)
+	Step        time.Duration // Tamaño del intervalo de reducción (0 = sin reducir)
+	Aggregation Aggregation   // Agregación usada al reducir
+	Limit       int           // Máximo de puntos, conservando los más recientes (0 = sin límite)
+}
+
+// GetMetricsRange retorna las muestras del historial dentro de [from, to]
+func (c *Collector) GetMetricsRange(from, to time.Time) []SystemMetrics {
+	history, err := c.store.Range(from, to)
+	if err != nil {
+		log.Printf("Error al leer el historial de métricas: %v", err)
+		return []SystemMetrics{}
+	}
+	return history
+}
+
+// QueryMetricsHistory aplica rango, reducción por intervalo y límite al historial
+func (c *Collector) QueryMetricsHistory(q HistoryQuery) []SystemMetrics {
+	history := c.GetMetricsRange(q.From, q.To)
+
+	if q.Step > 0 {
+		history = Downsample(history, q.Step, q.Aggregation)
+	}
+
+	if q.Limit > 0 && len(history) > q.Limit {
+		history = history[len(history)-q.Limit:]
+	}
+	return history
+}
+
+// Downsample agrupa las muestras en intervalos de duración step alineados
+// al reloj y combina cada intervalo en una sola muestra con la agregación
+// indicada. El timestamp de cada punto es el inicio de su intervalo.
+func Downsample(samples []SystemMetrics, step time.Duration, agg Aggregation) []SystemMetrics {
+	if step <= 0 || len(samples) == 0 {
+		return samples
+	}
+	if agg == "" {
+		agg = AggregationAvg
+	}
+
+	result := make([]SystemMetrics, 0)
+	bucketStart := samples[0].Timestamp.Truncate(step)
+	begin := 0
+	for i := 1; i <= len(samples); i++ {
+		if i < len(samples) && samples[i].Timestamp.Truncate(step).Equal(bucketStart) {
+			continue
+		}
+		point := aggregateSamples(samples[begin:i], agg)
+		point.Timestamp = bucketStart
+		result = append(result, point)
+
+		if i < len(samples) {
+			begin = i
+			bucketStart = samples[i].Timestamp.Truncate(step)
+		}
+	}
+	return result
+}
+
+// aggregateSamples combina un grupo de muestras en una sola. Los campos
+// que no son mediciones (conteos de CPUs, totales) se toman de la última.
+func aggregateSamples(bucket []SystemMetrics, agg Aggregation) SystemMetrics {
+	last := bucket[len(bucket)-1]
+	if agg == AggregationLast {
+		return last
+	}
+
+	result := last
+	result.CPU.Percent = aggregateField(bucket, agg, func(m SystemMetrics) float64 { return m.CPU.Percent })
+
+	result.CPU.PerCPU = make([]float64, len(last.CPU.PerCPU))
+	for core := range result.CPU.PerCPU {
+		values := make([]float64, 0, len(bucket))
+		for _, m := range bucket {
+			if core < len(m.CPU.PerCPU) {
+				values = append(values, m.CPU.PerCPU[core])
+			}
+		}
+		result.CPU.PerCPU[core] = aggregateValues(values, agg)
+	}
+
+	result.Memory.Available = uint64(aggregateField(bucket, agg, func(m SystemMetrics) float64 { return float64(m.Memory.Available) }))
+	result.Memory.Used = uint64(aggregateField(bucket, agg, func(m SystemMetrics) float64 { return float64(m.Memory.Used) }))
+	result.Memory.UsedPercent = aggregateField(bucket, agg, func(m SystemMetrics) float64 { return m.Memory.UsedPercent })
+	result.Memory.Free = uint64(aggregateField(bucket, agg, func(m SystemMetrics) float64 { return float64(m.Memory.Free) }))
+
+	result.Goroutines = int(aggregateField(bucket, agg, func(m SystemMetrics) float64 { return float64(m.Goroutines) }) + 0.5)
+
+	result.Disk = aggregateDisk(bucket, agg)
+	result.Network = aggregateNetwork(bucket, agg)
+	result.Runtime = aggregateRuntime(bucket, agg)
+
+	return result
+}
+
+// aggregateField extrae un campo de cada muestra y lo agrega
+func aggregateField(bucket []SystemMetrics, agg Aggregation, field func(m SystemMetrics) float64) float64 {
+	values := make([]float64, len(bucket))
+	for i, m := range bucket {
+		values[i] = field(m)
+	}
+	return aggregateValues(values, agg)
+}
+
+// aggregateValues aplica una agregación sobre un conjunto de valores
+func aggregateValues(values []float64, agg Aggregation) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	switch agg {
+	case AggregationMin:
+		result := values[0]
+		for _, v := range values[1:] {
+			if v < result {
+				result = v
+			}
+		}
+		return result
+	case AggregationMax:
+		result := values[0]
+		for _, v := range values[1:] {
+			if v > result {
+				result = v
+			}
+		}
+		return result
+	case AggregationLast:
+		return values[len(values)-1]
+	default:
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	}
+}
@@ -0,0 +1,292 @@
+package metrics
+
+import (
+	"math"
+	rtmetrics "runtime/metrics"
+	"sort"
+	"time"
+)
+
+// RuntimeInfo contiene métricas internas del runtime de Go obtenidas con runtime/metrics
+type RuntimeInfo struct {
+	GOMAXPROCS         int              `json:"gomaxprocs"`
+	HeapObjects        uint64           `json:"heap_objects"`
+	HeapBytes          uint64           `json:"heap_bytes"`
+	HeapGoalBytes      uint64           `json:"heap_goal_bytes"`
+	GCCycles           uint64           `json:"gc_cycles"`
+	GCCyclesForced     uint64           `json:"gc_cycles_forced"`
+	AllocBytesPerSec   float64          `json:"alloc_bytes_per_sec"`
+	AllocObjectsPerSec float64          `json:"alloc_objects_per_sec"`
+	CgoCalls           uint64           `json:"cgo_calls"`
+	MutexWaitSeconds   float64          `json:"mutex_wait_seconds"`
+	MutexWaitPerSec    float64          `json:"mutex_wait_seconds_per_sec"`
+	GCPauses           RuntimeHistogram `json:"gc_pauses"`       // Pausas de GC desde la muestra anterior
+	SchedLatencies     RuntimeHistogram `json:"sched_latencies"` // Espera de goroutines listas para ejecutarse
+}
+
+// RuntimeHistogram resume un histograma de tiempos (en segundos) del runtime
+type RuntimeHistogram struct {
+	Count   uint64            `json:"count"`
+	P50     float64           `json:"p50"`
+	P90     float64           `json:"p90"`
+	P99     float64           `json:"p99"`
+	Max     float64           `json:"max"`
+	Buckets []HistogramBucket `json:"buckets,omitempty"` // Solo los intervalos con eventos
+}
+
+// HistogramBucket es un intervalo [Lower, Upper) de un histograma con su conteo
+type HistogramBucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count uint64  `json:"count"`
+}
+
+// Nombres de las métricas de runtime/metrics utilizadas
+const (
+	rtGOMAXPROCS     = "/sched/gomaxprocs:threads"
+	rtHeapObjects    = "/gc/heap/objects:objects"
+	rtHeapBytes      = "/memory/classes/heap/objects:bytes"
+	rtHeapGoal       = "/gc/heap/goal:bytes"
+	rtGCCycles       = "/gc/cycles/total:gc-cycles"
+	rtGCCyclesForced = "/gc/cycles/forced:gc-cycles"
+	rtAllocBytes     = "/gc/heap/allocs:bytes"
+	rtAllocObjects   = "/gc/heap/allocs:objects"
+	rtCgoCalls       = "/cgo/go-to-c-calls:calls"
+	rtMutexWait      = "/sync/mutex/wait/total:seconds"
+	rtSchedLatencies = "/sched/latencies:seconds"
+	// Desde Go 1.22 las pausas de GC se publican con un nombre nuevo
+	rtGCPauses       = "/sched/pauses/total/gc:seconds"
+	rtGCPausesLegacy = "/gc/pauses:seconds"
+)
+
+// runtimeSampler lee runtime/metrics y conserva los valores acumulados de
+// la muestra anterior para calcular tasas y deltas de histogramas
+type runtimeSampler struct {
+	samples  []rtmetrics.Sample
+	index    map[string]int
+	prev     map[string]float64
+	prevHist map[string][]uint64
+	prevTime time.Time
+}
+
+// init prepara la lista de métricas a leer, omitiendo las que no existen
+// en la versión de Go con la que se compiló la API
+func (s *runtimeSampler) init() {
+	available := make(map[string]bool)
+	for _, d := range rtmetrics.All() {
+		available[d.Name] = true
+	}
+
+	names := []string{rtGOMAXPROCS, rtHeapObjects, rtHeapBytes, rtHeapGoal, rtGCCycles,
+		rtGCCyclesForced, rtAllocBytes, rtAllocObjects, rtCgoCalls, rtMutexWait, rtSchedLatencies}
+	if available[rtGCPauses] {
+		names = append(names, rtGCPauses)
+	} else {
+		names = append(names, rtGCPausesLegacy)
+	}
+
+	s.index = make(map[string]int)
+	for _, name := range names {
+		if !available[name] {
+			continue
+		}
+		s.index[name] = len(s.samples)
+		s.samples = append(s.samples, rtmetrics.Sample{Name: name})
+	}
+}
+
+// collect lee las métricas del runtime. Las tasas y los histogramas cubren
+// el intervalo desde la muestra anterior (en la primera, desde el inicio del proceso).
+func (s *runtimeSampler) collect(now time.Time) RuntimeInfo {
+	if s.index == nil {
+		s.init()
+	}
+	rtmetrics.Read(s.samples)
+
+	info := RuntimeInfo{
+		GOMAXPROCS:       int(s.uint(rtGOMAXPROCS)),
+		HeapObjects:      s.uint(rtHeapObjects),
+		HeapBytes:        s.uint(rtHeapBytes),
+		HeapGoalBytes:    s.uint(rtHeapGoal),
+		GCCycles:         s.uint(rtGCCycles),
+		GCCyclesForced:   s.uint(rtGCCyclesForced),
+		CgoCalls:         s.uint(rtCgoCalls),
+		MutexWaitSeconds: s.float(rtMutexWait),
+	}
+
+	current := map[string]float64{
+		rtAllocBytes:   float64(s.uint(rtAllocBytes)),
+		rtAllocObjects: float64(s.uint(rtAllocObjects)),
+		rtMutexWait:    info.MutexWaitSeconds,
+	}
+	if s.prev != nil {
+		if seconds := now.Sub(s.prevTime).Seconds(); seconds > 0 {
+			rate := func(name string) float64 {
+				return math.Max(0, current[name]-s.prev[name]) / seconds
+			}
+			info.AllocBytesPerSec = rate(rtAllocBytes)
+			info.AllocObjectsPerSec = rate(rtAllocObjects)
+			info.MutexWaitPerSec = rate(rtMutexWait)
+		}
+	}
+	s.prev = current
+	s.prevTime = now
+
+	if s.prevHist == nil {
+		s.prevHist = make(map[string][]uint64)
+	}
+	info.SchedLatencies = s.histogram(rtSchedLatencies)
+	if _, ok := s.index[rtGCPauses]; ok {
+		info.GCPauses = s.histogram(rtGCPauses)
+	} else {
+		info.GCPauses = s.histogram(rtGCPausesLegacy)
+	}
+
+	return info
+}
+
+// uint retorna el valor entero de una métrica (0 si no está disponible)
+func (s *runtimeSampler) uint(name string) uint64 {
+	i, ok := s.index[name]
+	if !ok || s.samples[i].Value.Kind() != rtmetrics.KindUint64 {
+		return 0
+	}
+	return s.samples[i].Value.Uint64()
+}
+
+// float retorna el valor real de una métrica (0 si no está disponible)
+func (s *runtimeSampler) float(name string) float64 {
+	i, ok := s.index[name]
+	if !ok || s.samples[i].Value.Kind() != rtmetrics.KindFloat64 {
+		return 0
+	}
+	return s.samples[i].Value.Float64()
+}
+
+// histogram calcula el histograma de eventos ocurridos desde la lectura anterior
+func (s *runtimeSampler) histogram(name string) RuntimeHistogram {
+	i, ok := s.index[name]
+	if !ok || s.samples[i].Value.Kind() != rtmetrics.KindFloat64Histogram {
+		return RuntimeHistogram{}
+	}
+	h := s.samples[i].Value.Float64Histogram()
+
+	prev := s.prevHist[name]
+	if len(prev) != len(h.Counts) {
+		prev = nil
+	}
+
+	buckets := make([]HistogramBucket, 0)
+	for b, count := range h.Counts {
+		if prev != nil && count >= prev[b] {
+			count -= prev[b]
+		}
+		if count == 0 {
+			continue
+		}
+		lower, upper := h.Buckets[b], h.Buckets[b+1]
+		// Los extremos del histograma son infinitos; se acotan al límite finito
+		if math.IsInf(lower, -1) {
+			lower = upper
+		}
+		if math.IsInf(upper, 1) {
+			upper = lower
+		}
+		buckets = append(buckets, HistogramBucket{Lower: lower, Upper: upper, Count: count})
+	}
+
+	s.prevHist[name] = append(s.prevHist[name][:0], h.Counts...)
+	return newRuntimeHistogram(buckets)
+}
+
+// newRuntimeHistogram construye el resumen (conteo y cuantiles) de un histograma
+func newRuntimeHistogram(buckets []HistogramBucket) RuntimeHistogram {
+	h := RuntimeHistogram{Buckets: buckets}
+	for _, b := range buckets {
+		h.Count += b.Count
+	}
+	if h.Count == 0 {
+		return h
+	}
+	h.P50 = histogramQuantile(buckets, h.Count, 0.50)
+	h.P90 = histogramQuantile(buckets, h.Count, 0.90)
+	h.P99 = histogramQuantile(buckets, h.Count, 0.99)
+	h.Max = buckets[len(buckets)-1].Upper
+	return h
+}
+
+// histogramQuantile estima un cuantil con el límite superior del intervalo
+// donde el conteo acumulado alcanza la fracción q
+func histogramQuantile(buckets []HistogramBucket, total uint64, q float64) float64 {
+	target := q * float64(total)
+	var cumulative uint64
+	for _, b := range buckets {
+		cumulative += b.Count
+		if float64(cumulative) >= target {
+			return b.Upper
+		}
+	}
+	return buckets[len(buckets)-1].Upper
+}
+
+// mergeRuntimeHistograms suma los conteos de varios histogramas por intervalo
+func mergeRuntimeHistograms(histograms []RuntimeHistogram) RuntimeHistogram {
+	type bounds struct{ lower, upper float64 }
+	counts := make(map[bounds]uint64)
+	for _, h := range histograms {
+		for _, b := range h.Buckets {
+			counts[bounds{b.Lower, b.Upper}] += b.Count
+		}
+	}
+
+	buckets := make([]HistogramBucket, 0, len(counts))
+	for k, count := range counts {
+		buckets = append(buckets, HistogramBucket{Lower: k.lower, Upper: k.upper, Count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Lower < buckets[j].Lower })
+	return newRuntimeHistogram(buckets)
+}
+
+// calculateRuntimeStats calcula estadísticas de las métricas del runtime
+func calculateRuntimeStats(history []SystemMetrics, opts StatsOptions) RuntimeStats {
+	field := func(get func(r RuntimeInfo) float64) StatInfo {
+		values := make([]float64, len(history))
+		for i, m := range history {
+			values[i] = get(m.Runtime)
+		}
+		return calculateStats(values, opts)
+	}
+
+	return RuntimeStats{
+		HeapBytes:              field(func(r RuntimeInfo) float64 { return float64(r.HeapBytes) }),
+		HeapObjects:            field(func(r RuntimeInfo) float64 { return float64(r.HeapObjects) }),
+		AllocBytesPerSec:       field(func(r RuntimeInfo) float64 { return r.AllocBytesPerSec }),
+		GCPauseP99Seconds:      field(func(r RuntimeInfo) float64 { return r.GCPauses.P99 }),
+		SchedLatencyP99Seconds: field(func(r RuntimeInfo) float64 { return r.SchedLatencies.P99 }),
+		MutexWaitPerSec:        field(func(r RuntimeInfo) float64 { return r.MutexWaitPerSec }),
+	}
+}
+
+// aggregateRuntime combina las métricas del runtime de un grupo de muestras.
+// Los histogramas se suman, ya que cada muestra cubre un intervalo distinto.
+func aggregateRuntime(bucket []SystemMetrics, agg Aggregation) RuntimeInfo {
+	result := bucket[len(bucket)-1].Runtime
+
+	result.HeapObjects = uint64(aggregateField(bucket, agg, func(m SystemMetrics) float64 { return float64(m.Runtime.HeapObjects) }))
+	result.HeapBytes = uint64(aggregateField(bucket, agg, func(m SystemMetrics) float64 { return float64(m.Runtime.HeapBytes) }))
+	result.HeapGoalBytes = uint64(aggregateField(bucket, agg, func(m SystemMetrics) float64 { return float64(m.Runtime.HeapGoalBytes) }))
+	result.AllocBytesPerSec = aggregateField(bucket, agg, func(m SystemMetrics) float64 { return m.Runtime.AllocBytesPerSec })
+	result.AllocObjectsPerSec = aggregateField(bucket, agg, func(m SystemMetrics) float64 { return m.Runtime.AllocObjectsPerSec })
+	result.MutexWaitPerSec = aggregateField(bucket, agg, func(m SystemMetrics) float64 { return m.Runtime.MutexWaitPerSec })
+
+	gcPauses := make([]RuntimeHistogram, len(bucket))
+	schedLatencies := make([]RuntimeHistogram, len(bucket))
+	for i, m := range bucket {
+		gcPauses[i] = m.Runtime.GCPauses
+		schedLatencies[i] = m.Runtime.SchedLatencies
+	}
+	result.GCPauses = mergeRuntimeHistograms(gcPauses)
+	result.SchedLatencies = mergeRuntimeHistograms(schedLatencies)
+
+	return result
+}
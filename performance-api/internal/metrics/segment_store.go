@@ -0,0 +1,586 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formato de los segmentos en disco:
+//
+//	archivo .seg: magic (8 bytes) seguido de registros
+//	registro:     longitud (4) | crc32 del payload (4) | timestamp en ns (8) | payload JSON
+//	archivo .idx: entradas timestamp en ns (8) | offset del registro en el .seg (8)
+//
+// Todos los enteros se escriben en big endian. Los archivos solo se
+// escriben al final, de modo que un corte abrupto deja a lo sumo un
+// registro incompleto que se descarta al abrir el almacenamiento. Dentro
+// de un segmento los timestamps no decrecen: si el reloj retrocede se abre
+// un segmento nuevo.
+const (
+	segmentMagic     = "PAPISEG1"
+	segmentExt       = ".seg"
+	indexExt         = ".idx"
+	corruptExt       = ".corrupt"
+	recordHeaderSize = 16
+	indexEntrySize   = 16
+	maxRecordSize    = 16 << 20
+)
+
+// retentionCheckInterval es la frecuencia con que se eliminan los
+// segmentos vencidos aunque no se roten segmentos nuevos
+const retentionCheckInterval = time.Minute
+
+// ErrStoreClosed indica que se intentó escribir tras cerrar el almacenamiento
+var ErrStoreClosed = errors.New("el almacenamiento de métricas está cerrado")
+
+// errSegmentHeader indica que un archivo .seg no comienza con segmentMagic
+var errSegmentHeader = errors.New("cabecera de segmento inválida")
+
+// SegmentStoreOptions configura el almacenamiento en segmentos
+type SegmentStoreOptions struct {
+	Dir             string
+	MaxSegmentBytes int64
+	SegmentDuration time.Duration
+	Retention       time.Duration
+}
+
+// SegmentStore guarda el historial en archivos de segmento de solo
+// escritura al final, con un índice por timestamp para cada segmento
+type SegmentStore struct {
+	mu          sync.RWMutex
+	opts        SegmentStoreOptions
+	segments    []*segment
+	active      *os.File
+	activeIndex *os.File
+	sealed      bool // El último segmento no admite más registros
+	closed      bool
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// segment describe un archivo de segmento y su índice en memoria
+type segment struct {
+	path  string
+	size  int64
+	index []indexEntry
+}
+
+// indexEntry ubica un registro dentro de un segmento
+type indexEntry struct {
+	timestamp int64
+	offset    int64
+}
+
+// OpenSegmentStore abre (o crea) un almacenamiento de segmentos en el
+// directorio indicado, reparando registros incompletos si los hay
+func OpenSegmentStore(opts SegmentStoreOptions) (*SegmentStore, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("el directorio de segmentos es obligatorio")
+	}
+	if opts.MaxSegmentBytes <= 0 {
+		opts.MaxSegmentBytes = 4 << 20
+	}
+	if opts.SegmentDuration <= 0 {
+		opts.SegmentDuration = 6 * time.Hour
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error al crear el directorio de segmentos: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(opts.Dir, "*"+segmentExt))
+	if err != nil {
+		return nil, fmt.Errorf("error al listar segmentos: %w", err)
+	}
+	sort.Strings(paths)
+
+	s := &SegmentStore{opts: opts, stop: make(chan struct{})}
+	for _, path := range paths {
+		seg, err := loadSegment(path)
+		if errors.Is(err, errSegmentHeader) {
+			// No es un segmento de este formato: se aparta sin borrarlo
+			if err := quarantineSegment(path); err != nil {
+				return nil, err
+			}
+			log.Printf("Segmento %s con cabecera inválida: se renombró a %s", path, path+corruptExt)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(seg.index) == 0 {
+			// Segmento sin registros válidos: no aporta nada
+			removeSegmentFiles(seg.path)
+			continue
+		}
+		s.segments = append(s.segments, seg)
+	}
+
+	s.enforceRetention(time.Now())
+	if opts.Retention > 0 {
+		go s.retentionLoop()
+	}
+	return s, nil
+}
+
+// retentionLoop aplica la retención periódicamente hasta que se cierre el
+// almacenamiento
+func (s *SegmentStore) retentionLoop() {
+	ticker := time.NewTicker(retentionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			s.enforceRetention(now)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Append agrega una muestra al segmento activo, rotándolo si es necesario
+func (s *SegmentStore) Append(m SystemMetrics) error {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("error al serializar métricas: %w", err)
+	}
+	if len(payload) > maxRecordSize {
+		return fmt.Errorf("registro demasiado grande: %d bytes", len(payload))
+	}
+
+	ts := m.Timestamp.UnixNano()
+	record := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	binary.BigEndian.PutUint64(record[8:16], uint64(ts))
+	copy(record[recordHeaderSize:], payload)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+	if s.needsRoll(ts) {
+		if err := s.roll(ts); err != nil {
+			return err
+		}
+	}
+
+	// El tamaño y el índice en memoria solo avanzan cuando ambas escrituras
+	// se completaron; si alguna falla se deshace lo escrito parcialmente
+	seg := s.segments[len(s.segments)-1]
+	entry := indexEntry{timestamp: ts, offset: seg.size}
+	if _, err := s.active.Write(record); err != nil {
+		s.discardPartial(seg)
+		return fmt.Errorf("error al escribir el segmento: %w", err)
+	}
+	if _, err := s.activeIndex.Write(encodeIndexEntry(entry)); err != nil {
+		s.discardPartial(seg)
+		return fmt.Errorf("error al escribir el índice: %w", err)
+	}
+	seg.size += int64(len(record))
+	seg.index = append(seg.index, entry)
+
+	return nil
+}
+
+// discardPartial trunca el segmento activo y su índice a lo último
+// confirmado en memoria. Si no se puede truncar, el segmento se sella y la
+// próxima muestra abre uno nuevo; la cola sobrante se repara al reabrir.
+func (s *SegmentStore) discardPartial(seg *segment) {
+	dataErr := s.active.Truncate(seg.size)
+	indexErr := s.activeIndex.Truncate(int64(len(seg.index) * indexEntrySize))
+	if dataErr == nil && indexErr == nil {
+		return
+	}
+	log.Printf("No se pudo deshacer una escritura incompleta en %s: se sella el segmento", filepath.Base(seg.path))
+	s.closeActive()
+	s.sealed = true
+}
+
+// Range retorna las muestras con timestamp dentro de [from, to]
+func (s *SegmentStore) Range(from, to time.Time) ([]SystemMetrics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fromNs, toNs := int64(-1<<63), int64(1<<63-1)
+	if !from.IsZero() {
+		fromNs = from.UnixNano()
+	}
+	if !to.IsZero() {
+		toNs = to.UnixNano()
+	}
+
+	result := make([]SystemMetrics, 0)
+	unordered := false
+	prevLast := int64(-1 << 63)
+	for _, seg := range s.segments {
+		if len(seg.index) == 0 || seg.last() < fromNs || seg.first() > toNs {
+			continue
+		}
+		samples, err := seg.read(fromNs, toNs)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, samples...)
+		unordered = unordered || seg.first() < prevLast
+		prevLast = seg.last()
+	}
+	if unordered {
+		// Solo ocurre si el reloj retrocedió entre segmentos
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].Timestamp.Before(result[j].Timestamp)
+		})
+	}
+	return result, nil
+}
+
+// Close detiene la retención periódica y cierra los archivos del segmento activo
+func (s *SegmentStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.closeActive()
+}
+
+// needsRoll indica si se debe abrir un segmento nuevo antes de escribir
+func (s *SegmentStore) needsRoll(ts int64) bool {
+	if s.active == nil || len(s.segments) == 0 {
+		return true
+	}
+	seg := s.segments[len(s.segments)-1]
+	if seg.size >= s.opts.MaxSegmentBytes {
+		return true
+	}
+	if len(seg.index) == 0 {
+		return false
+	}
+	// Si el reloj retrocedió se abre un segmento nuevo: dentro de cada
+	// segmento los timestamps no decrecen y read busca sobre el índice
+	return ts < seg.last() || time.Duration(ts-seg.first()) >= s.opts.SegmentDuration
+}
+
+// roll cierra el segmento activo y abre uno nuevo. Si el último segmento
+// existente todavía tiene espacio (por ejemplo, tras reiniciar) se reutiliza.
+func (s *SegmentStore) roll(ts int64) error {
+	reopening := s.active == nil
+	if err := s.closeActive(); err != nil {
+		return err
+	}
+
+	if n := len(s.segments); n > 0 && reopening && !s.sealed {
+		last := s.segments[n-1]
+		if last.size < s.opts.MaxSegmentBytes && len(last.index) > 0 && ts >= last.last() && time.Duration(ts-last.first()) < s.opts.SegmentDuration {
+			return s.openActive(last)
+		}
+	}
+
+	// Los nombres siguen el orden de creación aunque el reloj retroceda, para
+	// que al reabrir los segmentos se carguen en el mismo orden
+	name := ts
+	if n := len(s.segments); n > 0 {
+		if prev := segmentNameTimestamp(s.segments[n-1].path); name <= prev {
+			name = prev + 1
+		}
+	}
+	seg := &segment{path: filepath.Join(s.opts.Dir, fmt.Sprintf("%020d%s", name, segmentExt))}
+	if err := os.WriteFile(seg.path, []byte(segmentMagic), 0o644); err != nil {
+		return fmt.Errorf("error al crear el segmento: %w", err)
+	}
+	if err := os.WriteFile(indexPath(seg.path), nil, 0o644); err != nil {
+		return fmt.Errorf("error al crear el índice: %w", err)
+	}
+	seg.size = int64(len(segmentMagic))
+
+	if err := s.openActive(seg); err != nil {
+		return err
+	}
+	s.segments = append(s.segments, seg)
+	s.sealed = false
+	s.enforceRetention(time.Unix(0, ts))
+	return nil
+}
+
+// openActive abre los archivos de un segmento para agregar registros
+func (s *SegmentStore) openActive(seg *segment) error {
+	f, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("error al abrir el segmento: %w", err)
+	}
+	idx, err := os.OpenFile(indexPath(seg.path), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("error al abrir el índice: %w", err)
+	}
+	s.active = f
+	s.activeIndex = idx
+	return nil
+}
+
+// closeActive cierra los archivos del segmento activo
+func (s *SegmentStore) closeActive() error {
+	var firstErr error
+	if s.active != nil {
+		firstErr = s.active.Close()
+		s.active = nil
+	}
+	if s.activeIndex != nil {
+		if err := s.activeIndex.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		s.activeIndex = nil
+	}
+	return firstErr
+}
+
+// enforceRetention elimina los segmentos cerrados cuyos datos son más
+// antiguos que la retención configurada
+func (s *SegmentStore) enforceRetention(now time.Time) {
+	if s.opts.Retention <= 0 {
+		return
+	}
+	cutoff := now.Add(-s.opts.Retention).UnixNano()
+
+	kept := s.segments[:0]
+	for i, seg := range s.segments {
+		isActive := s.active != nil && i == len(s.segments)-1
+		if !isActive && len(seg.index) > 0 && seg.last() < cutoff {
+			removeSegmentFiles(seg.path)
+			continue
+		}
+		kept = append(kept, seg)
+	}
+	s.segments = kept
+}
+
+// first retorna el timestamp del primer registro
+func (seg *segment) first() int64 {
+	return seg.index[0].timestamp
+}
+
+// last retorna el timestamp del último registro
+func (seg *segment) last() int64 {
+	return seg.index[len(seg.index)-1].timestamp
+}
+
+// read decodifica los registros del segmento dentro de [fromNs, toNs]
+func (seg *segment) read(fromNs, toNs int64) ([]SystemMetrics, error) {
+	start := sort.Search(len(seg.index), func(i int) bool {
+		return seg.index[i].timestamp >= fromNs
+	})
+	end := sort.Search(len(seg.index), func(i int) bool {
+		return seg.index[i].timestamp > toNs
+	})
+	if start >= end {
+		return nil, nil
+	}
+
+	endOffset := seg.size
+	if end < len(seg.index) {
+		endOffset = seg.index[end].offset
+	}
+
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir el segmento: %w", err)
+	}
+	defer f.Close()
+
+	startOffset := seg.index[start].offset
+	data := make([]byte, endOffset-startOffset)
+	if _, err := f.ReadAt(data, startOffset); err != nil {
+		return nil, fmt.Errorf("error al leer el segmento: %w", err)
+	}
+
+	samples := make([]SystemMetrics, 0, end-start)
+	for pos := 0; pos < len(data); {
+		_, payload, n, err := decodeRecord(data[pos:])
+		if err != nil {
+			return nil, fmt.Errorf("segmento %s dañado: %w", filepath.Base(seg.path), err)
+		}
+		var m SystemMetrics
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return nil, fmt.Errorf("error al decodificar métricas: %w", err)
+		}
+		samples = append(samples, m)
+		pos += n
+	}
+	return samples, nil
+}
+
+// loadSegment carga el índice de un segmento y completa o repara lo que
+// falte a partir del propio archivo de datos
+func loadSegment(path string) (*segment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el segmento: %w", err)
+	}
+	seg := &segment{path: path}
+	if len(data) < len(segmentMagic) && bytes.HasPrefix([]byte(segmentMagic), data) {
+		// Creación interrumpida antes de escribir la cabecera completa
+		return seg, nil
+	}
+	if !bytes.HasPrefix(data, []byte(segmentMagic)) {
+		return nil, fmt.Errorf("%w: %s", errSegmentHeader, filepath.Base(path))
+	}
+
+	seg.index = readIndex(indexPath(path), data)
+
+	pos := int64(len(segmentMagic))
+	if n := len(seg.index); n > 0 {
+		last := seg.index[n-1]
+		_, _, size, _ := decodeRecord(data[last.offset:])
+		pos = last.offset + int64(size)
+	}
+
+	rebuilt := false
+	for pos < int64(len(data)) {
+		ts, _, size, err := decodeRecord(data[pos:])
+		if err != nil {
+			break
+		}
+		seg.index = append(seg.index, indexEntry{timestamp: ts, offset: pos})
+		pos += int64(size)
+		rebuilt = true
+	}
+
+	if pos < int64(len(data)) {
+		// Cola incompleta o dañada: se descarta para poder seguir escribiendo
+		if err := os.Truncate(path, pos); err != nil {
+			return nil, fmt.Errorf("error al reparar el segmento: %w", err)
+		}
+	}
+	seg.size = pos
+
+	if rebuilt || !indexMatches(indexPath(path), len(seg.index)) {
+		if err := writeIndex(indexPath(path), seg.index); err != nil {
+			return nil, err
+		}
+	}
+	return seg, nil
+}
+
+// readIndex lee las entradas válidas del índice de un segmento
+func readIndex(path string, data []byte) []indexEntry {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	entries := make([]indexEntry, 0, len(raw)/indexEntrySize)
+	prev := int64(-1)
+	for i := 0; i+indexEntrySize <= len(raw); i += indexEntrySize {
+		entry := indexEntry{
+			timestamp: int64(binary.BigEndian.Uint64(raw[i : i+8])),
+			offset:    int64(binary.BigEndian.Uint64(raw[i+8 : i+16])),
+		}
+		if entry.offset <= prev || entry.offset >= int64(len(data)) {
+			break
+		}
+		ts, _, _, err := decodeRecord(data[entry.offset:])
+		if err != nil || ts != entry.timestamp {
+			break
+		}
+		entries = append(entries, entry)
+		prev = entry.offset
+	}
+	return entries
+}
+
+// indexMatches verifica si el índice en disco tiene exactamente n entradas
+func indexMatches(path string, n int) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Size() == int64(n*indexEntrySize)
+}
+
+// writeIndex reescribe por completo el índice de un segmento
+func writeIndex(path string, entries []indexEntry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		buf.Write(encodeIndexEntry(entry))
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("error al escribir el índice: %w", err)
+	}
+	return nil
+}
+
+// encodeIndexEntry serializa una entrada del índice
+func encodeIndexEntry(entry indexEntry) []byte {
+	b := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint64(b[0:8], uint64(entry.timestamp))
+	binary.BigEndian.PutUint64(b[8:16], uint64(entry.offset))
+	return b
+}
+
+// decodeRecord valida y decodifica el registro al inicio de data,
+// retornando su timestamp, su payload y su tamaño total
+func decodeRecord(data []byte) (int64, []byte, int, error) {
+	if len(data) < recordHeaderSize {
+		return 0, nil, 0, io.ErrUnexpectedEOF
+	}
+	length := int(binary.BigEndian.Uint32(data[0:4]))
+	if length > maxRecordSize {
+		return 0, nil, 0, fmt.Errorf("longitud de registro inválida: %d", length)
+	}
+	if len(data) < recordHeaderSize+length {
+		return 0, nil, 0, io.ErrUnexpectedEOF
+	}
+	payload := data[recordHeaderSize : recordHeaderSize+length]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[4:8]) {
+		return 0, nil, 0, errors.New("checksum inválido")
+	}
+	ts := int64(binary.BigEndian.Uint64(data[8:16]))
+	return ts, payload, recordHeaderSize + length, nil
+}
+
+// indexPath retorna la ruta del índice asociado a un segmento
+func indexPath(segmentPath string) string {
+	return strings.TrimSuffix(segmentPath, segmentExt) + indexExt
+}
+
+// quarantineSegment renombra un segmento ilegible y su índice con la
+// extensión .corrupt para que no se carguen ni se eliminen
+func quarantineSegment(segmentPath string) error {
+	if err := os.Rename(segmentPath, segmentPath+corruptExt); err != nil {
+		return fmt.Errorf("error al apartar el segmento dañado: %w", err)
+	}
+	idx := indexPath(segmentPath)
+	if err := os.Rename(idx, idx+corruptExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error al apartar el índice dañado: %w", err)
+	}
+	return nil
+}
+
+// segmentNameTimestamp retorna el timestamp con que se nombró un segmento
+func segmentNameTimestamp(segmentPath string) int64 {
+	ts, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(segmentPath), segmentExt), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return ts
+}
+
+// removeSegmentFiles elimina un segmento y su índice
+func removeSegmentFiles(segmentPath string) {
+	os.Remove(segmentPath)
+	os.Remove(indexPath(segmentPath))
+}
@@ -0,0 +1,333 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// testSample crea una muestra identificable por su porcentaje de CPU
+func testSample(base time.Time, i int) SystemMetrics {
+	return SystemMetrics{
+		Timestamp:  base.Add(time.Duration(i) * time.Second),
+		CPU:        CPUInfo{Percent: float64(i)},
+		Goroutines: i,
+	}
+}
+
+// openTestStore abre un almacenamiento en dir y lo cierra al terminar la prueba
+func openTestStore(t *testing.T, dir string, maxSegmentBytes int64) *SegmentStore {
+	t.Helper()
+	store, err := OpenSegmentStore(SegmentStoreOptions{Dir: dir, MaxSegmentBytes: maxSegmentBytes})
+	if err != nil {
+		t.Fatalf("OpenSegmentStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// appendSamples agrega las muestras [from, to) al almacenamiento
+func appendSamples(t *testing.T, store *SegmentStore, base time.Time, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		if err := store.Append(testSample(base, i)); err != nil {
+			t.Fatalf("Append(%d): %v", i, err)
+		}
+	}
+}
+
+// checkSamples verifica que las muestras sean exactamente las [from, to)
+func checkSamples(t *testing.T, got []SystemMetrics, base time.Time, from, to int) {
+	t.Helper()
+	if len(got) != to-from {
+		t.Fatalf("se leyeron %d muestras, se esperaban %d", len(got), to-from)
+	}
+	for i, m := range got {
+		want := testSample(base, from+i)
+		if !m.Timestamp.Equal(want.Timestamp) || m.CPU.Percent != want.CPU.Percent || m.Goroutines != want.Goroutines {
+			t.Fatalf("muestra %d = {%v %g %d}, se esperaba {%v %g %d}", i,
+				m.Timestamp, m.CPU.Percent, m.Goroutines, want.Timestamp, want.CPU.Percent, want.Goroutines)
+		}
+	}
+}
+
+// segmentFiles retorna los archivos .seg del directorio en orden
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestSegmentStoreRoundTrip(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxSegmentBytes int64
+		minSegments     int
+	}{
+		{"un segmento", 1 << 20, 1},
+		{"varios segmentos", 1 << 10, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+			store := openTestStore(t, dir, tt.maxSegmentBytes)
+			appendSamples(t, store, base, 0, 50)
+
+			all, err := store.Range(time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatalf("Range: %v", err)
+			}
+			checkSamples(t, all, base, 0, 50)
+
+			// Los extremos del rango son inclusivos
+			sub, err := store.Range(testSample(base, 10).Timestamp, testSample(base, 19).Timestamp)
+			if err != nil {
+				t.Fatalf("Range: %v", err)
+			}
+			checkSamples(t, sub, base, 10, 20)
+
+			if got := len(segmentFiles(t, dir)); got < tt.minSegments {
+				t.Errorf("se crearon %d segmentos, se esperaban al menos %d", got, tt.minSegments)
+			}
+
+			// Al reabrir se conservan las muestras y se sigue agregando al final
+			if err := store.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			reopened := openTestStore(t, dir, tt.maxSegmentBytes)
+			appendSamples(t, reopened, base, 50, 60)
+			all, err = reopened.Range(time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatalf("Range tras reabrir: %v", err)
+			}
+			checkSamples(t, all, base, 0, 60)
+		})
+	}
+}
+
+func TestSegmentStoreRepairsTornTail(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(t *testing.T, segPath string)
+		want   int // Muestras que sobreviven de las 20 escritas
+	}{
+		{"registro cortado", func(t *testing.T, segPath string) {
+			info, _ := os.Stat(segPath)
+			if err := os.Truncate(segPath, info.Size()-5); err != nil {
+				t.Fatal(err)
+			}
+		}, 19},
+		{"cabecera de registro cortada", func(t *testing.T, segPath string) {
+			appendBytes(t, segPath, []byte{0, 0, 0})
+		}, 20},
+		{"basura al final", func(t *testing.T, segPath string) {
+			appendBytes(t, segPath, []byte("esto no es un registro válido"))
+		}, 20},
+		{"checksum del último registro", func(t *testing.T, segPath string) {
+			data, err := os.ReadFile(segPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[len(data)-2] ^= 0xff
+			if err := os.WriteFile(segPath, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, 19},
+		{"índice ausente", func(t *testing.T, segPath string) {
+			if err := os.Remove(indexPath(segPath)); err != nil {
+				t.Fatal(err)
+			}
+		}, 20},
+		{"índice incompleto", func(t *testing.T, segPath string) {
+			if err := os.Truncate(indexPath(segPath), 5*indexEntrySize+3); err != nil {
+				t.Fatal(err)
+			}
+		}, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			base := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+			store := openTestStore(t, dir, 1<<20)
+			appendSamples(t, store, base, 0, 20)
+			if err := store.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			segs := segmentFiles(t, dir)
+			if len(segs) != 1 {
+				t.Fatalf("se esperaba un segmento, hay %d", len(segs))
+			}
+			tt.damage(t, segs[0])
+
+			reopened := openTestStore(t, dir, 1<<20)
+			got, err := reopened.Range(time.Time{}, time.Time{})
+			if err != nil {
+				t.Fatalf("Range tras reparar: %v", err)
+			}
+			checkSamples(t, got, base, 0, tt.want)
+
+			// Las muestras nuevas se leen a continuación de las reparadas
+			appendSamples(t, reopened, base, 20, 25)
+			got, err = reopened.Range(testSample(base, 20).Timestamp, time.Time{})
+			if err != nil {
+				t.Fatalf("Range tras agregar: %v", err)
+			}
+			checkSamples(t, got, base, 20, 25)
+
+			if !indexMatches(indexPath(segs[0]), tt.want+5) {
+				t.Errorf("el índice no quedó con %d entradas", tt.want+5)
+			}
+		})
+	}
+}
+
+func TestSegmentStoreQuarantinesInvalidHeader(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "00000000000000000001"+segmentExt)
+	if err := os.WriteFile(bad, []byte("NOESUNSEGMENTO"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "00000000000000000002"+segmentExt)
+	if err := os.WriteFile(empty, []byte(segmentMagic[:3]), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := openTestStore(t, dir, 1<<20)
+	if got, _ := store.Range(time.Time{}, time.Time{}); len(got) != 0 {
+		t.Errorf("se leyeron %d muestras, se esperaban 0", len(got))
+	}
+	if _, err := os.Stat(bad + corruptExt); err != nil {
+		t.Errorf("el segmento con cabecera inválida no se renombró: %v", err)
+	}
+	if _, err := os.Stat(empty); !os.IsNotExist(err) {
+		t.Errorf("el segmento sin cabecera completa no se eliminó: %v", err)
+	}
+}
+
+// appendBytes agrega bytes al final de un archivo
+func appendBytes(t *testing.T, path string, data []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSegmentStoreAppendUndoesPartialWrites(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	store := openTestStore(t, dir, 1<<20)
+	appendSamples(t, store, base, 0, 5)
+
+	// Una escritura cortada a mitad de registro se trunca
+	store.mu.Lock()
+	if _, err := store.active.Write([]byte{0, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	store.discardPartial(store.segments[0])
+	store.mu.Unlock()
+	appendSamples(t, store, base, 5, 10)
+
+	// Si el índice no se puede escribir ni truncar, el segmento se sella
+	store.mu.Lock()
+	store.activeIndex.Close()
+	store.mu.Unlock()
+	if err := store.Append(testSample(base, 10)); err == nil {
+		t.Fatal("Append con el índice cerrado: se esperaba un error")
+	}
+	appendSamples(t, store, base, 10, 15)
+
+	got, err := store.Range(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Range: %v", err)
+	}
+	checkSamples(t, got, base, 0, 15)
+	if n := len(segmentFiles(t, dir)); n != 2 {
+		t.Errorf("hay %d segmentos, se esperaban 2 tras sellar el primero", n)
+	}
+
+	// Al reabrir se descarta la cola del segmento sellado
+	store.Close()
+	reopened := openTestStore(t, dir, 1<<20)
+	got, err = reopened.Range(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Range tras reabrir: %v", err)
+	}
+	checkSamples(t, got, base, 0, 15)
+}
+
+func TestSegmentStoreAppendAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	store := openTestStore(t, dir, 1<<20)
+	appendSamples(t, store, base, 0, 3)
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Tras Close no se reabre ni se crea ningún segmento
+	if err := store.Append(testSample(base, 3)); !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("Append tras Close = %v, se esperaba ErrStoreClosed", err)
+	}
+	if err := store.Append(testSample(base, 1<<20)); !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("Append tras Close = %v, se esperaba ErrStoreClosed", err)
+	}
+	if n := len(segmentFiles(t, dir)); n != 1 {
+		t.Errorf("hay %d segmentos, se esperaba 1", n)
+	}
+}
+
+func TestSegmentStoreClockStepBack(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	store := openTestStore(t, dir, 1<<20)
+
+	// El reloj retrocede 30 segundos tras la muestra 39
+	appendSamples(t, store, base, 30, 40)
+	appendSamples(t, store, base, 0, 10)
+	if n := len(segmentFiles(t, dir)); n != 2 {
+		t.Fatalf("hay %d segmentos, se esperaban 2 tras el retroceso", n)
+	}
+
+	check := func(store *SegmentStore) {
+		t.Helper()
+		got, err := store.Range(time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("Range: %v", err)
+		}
+		checkSamples(t, got[:10], base, 0, 10)
+		checkSamples(t, got[10:], base, 30, 40)
+
+		sub, err := store.Range(testSample(base, 5).Timestamp, testSample(base, 34).Timestamp)
+		if err != nil {
+			t.Fatalf("Range: %v", err)
+		}
+		checkSamples(t, sub[:5], base, 5, 10)
+		checkSamples(t, sub[5:], base, 30, 35)
+	}
+	check(store)
+
+	// Al reabrir se respeta el orden de creación y no se reutiliza el
+	// último segmento para una muestra anterior a su final
+	store.Close()
+	reopened := openTestStore(t, dir, 1<<20)
+	check(reopened)
+	appendSamples(t, reopened, base, 5, 6)
+	if n := len(segmentFiles(t, dir)); n != 3 {
+		t.Errorf("hay %d segmentos, se esperaban 3", n)
+	}
+}
@@ -0,0 +1,186 @@
+package metrics
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// DefaultSketchAccuracy es el error relativo de los cuantiles calculados con Sketch
+const DefaultSketchAccuracy = 0.01
+
+// minSketchValue es el menor valor absoluto distinguible de cero en el sketch
+const minSketchValue = 1e-9
+
+// Sketch resume una distribución en cubetas de tamaño logarítmico (estilo
+// DDSketch). Cualquier cuantil se estima con un error relativo acotado por
+// la precisión elegida, usa memoria proporcional al rango de los valores y
+// no a su cantidad, y dos sketches con la misma precisión pueden combinarse.
+type Sketch struct {
+	gamma    float64
+	logGamma float64
+	positive map[int]uint64
+	negative map[int]uint64
+	zeros    uint64
+	count    uint64
+	min      float64
+	max      float64
+}
+
+// NewSketch crea un sketch con el error relativo indicado (por ejemplo 0.01)
+func NewSketch(relativeAccuracy float64) *Sketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = DefaultSketchAccuracy
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &Sketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		positive: make(map[int]uint64),
+		negative: make(map[int]uint64),
+		min:      math.Inf(1),
+		max:      math.Inf(-1),
+	}
+}
+
+// Add agrega un valor al sketch. Los valores NaN se ignoran.
+func (s *Sketch) Add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+
+	s.count++
+	s.min = math.Min(s.min, v)
+	s.max = math.Max(s.max, v)
+
+	switch {
+	case v > minSketchValue:
+		s.positive[s.key(v)]++
+	case v < -minSketchValue:
+		s.negative[s.key(-v)]++
+	default:
+		s.zeros++
+	}
+}
+
+// Merge agrega al sketch los valores de otro con la misma precisión
+func (s *Sketch) Merge(other *Sketch) error {
+	if other.gamma != s.gamma {
+		return errors.New("no se pueden combinar sketches con precisiones distintas")
+	}
+
+	for k, n := range other.positive {
+		s.positive[k] += n
+	}
+	for k, n := range other.negative {
+		s.negative[k] += n
+	}
+	s.zeros += other.zeros
+	s.count += other.count
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+	return nil
+}
+
+// Count retorna la cantidad de valores agregados
+func (s *Sketch) Count() uint64 {
+	return s.count
+}
+
+// Quantile estima el cuantil q (entre 0 y 1) de los valores agregados
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	rank := uint64(q * float64(s.count-1))
+	var seen uint64
+	for _, b := range s.buckets() {
+		seen += b.count
+		if seen > rank {
+			return math.Max(s.min, math.Min(s.max, b.value))
+		}
+	}
+	return s.max
+}
+
+// Histogram reparte los valores en n cubetas de igual ancho entre el mínimo
+// y el máximo. Cada cubeta del sketch se asigna según su valor representativo.
+func (s *Sketch) Histogram(n int) []HistogramBucket {
+	if s.count == 0 || n <= 0 {
+		return nil
+	}
+
+	width := (s.max - s.min) / float64(n)
+	if width == 0 {
+		return []HistogramBucket{{Lower: s.min, Upper: s.max, Count: s.count}}
+	}
+
+	histogram := make([]HistogramBucket, n)
+	for i := range histogram {
+		histogram[i].Lower = s.min + float64(i)*width
+		histogram[i].Upper = s.min + float64(i+1)*width
+	}
+	histogram[n-1].Upper = s.max
+
+	for _, b := range s.buckets() {
+		i := int((b.value - s.min) / width)
+		if i < 0 {
+			i = 0
+		} else if i >= n {
+			i = n - 1
+		}
+		histogram[i].Count += b.count
+	}
+	return histogram
+}
+
+// sketchBucket es una cubeta del sketch con su valor representativo
+type sketchBucket struct {
+	value float64
+	count uint64
+}
+
+// buckets retorna las cubetas no vacías ordenadas de menor a mayor valor
+func (s *Sketch) buckets() []sketchBucket {
+	buckets := make([]sketchBucket, 0, len(s.negative)+len(s.positive)+1)
+
+	keys := sortedKeys(s.negative)
+	for i := len(keys) - 1; i >= 0; i-- {
+		buckets = append(buckets, sketchBucket{value: -s.value(keys[i]), count: s.negative[keys[i]]})
+	}
+	if s.zeros > 0 {
+		buckets = append(buckets, sketchBucket{value: 0, count: s.zeros})
+	}
+	for _, k := range sortedKeys(s.positive) {
+		buckets = append(buckets, sketchBucket{value: s.value(k), count: s.positive[k]})
+	}
+	return buckets
+}
+
+// key retorna la cubeta de un valor positivo: (gamma^(k-1), gamma^k]
+func (s *Sketch) key(v float64) int {
+	return int(math.Ceil(math.Log(v) / s.logGamma))
+}
+
+// value retorna el valor representativo de una cubeta, cuyo error relativo
+// respecto de cualquier valor de la cubeta está acotado por la precisión
+func (s *Sketch) value(k int) float64 {
+	return 2 * math.Pow(s.gamma, float64(k)) / (s.gamma + 1)
+}
+
+// sortedKeys retorna las claves de un mapa de cubetas en orden ascendente
+func sortedKeys(m map[int]uint64) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// exactQuantile retorna el cuantil q con el mismo rango que usa Sketch.Quantile
+func exactQuantile(sorted []float64, q float64) float64 {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func TestSketchQuantileErrorBound(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name     string
+		accuracy float64
+		gen      func() float64
+	}{
+		{"uniforme", 0.01, func() float64 { return rng.Float64() * 100 }},
+		{"exponencial", 0.01, func() float64 { return rng.ExpFloat64() * 1e6 }},
+		{"lognormal", 0.02, func() float64 { return math.Exp(rng.NormFloat64() * 3) }},
+		{"negativos y positivos", 0.01, func() float64 { return rng.NormFloat64() * 50 }},
+		{"con ceros", 0.005, func() float64 { return float64(rng.Intn(3)) * rng.Float64() }},
+		{"constante", 0.01, func() float64 { return 42 }},
+	}
+	quantiles := []float64{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99, 0.999, 1}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sketch := NewSketch(tt.accuracy)
+			values := make([]float64, 10000)
+			for i := range values {
+				values[i] = tt.gen()
+				sketch.Add(values[i])
+			}
+			sort.Float64s(values)
+
+			if got := sketch.Count(); got != uint64(len(values)) {
+				t.Errorf("Count() = %d, se esperaba %d", got, len(values))
+			}
+			for _, q := range quantiles {
+				want := exactQuantile(values, q)
+				got := sketch.Quantile(q)
+				if math.Abs(got-want) > tt.accuracy*math.Abs(want)+minSketchValue {
+					t.Errorf("Quantile(%g) = %g, exacto %g: error relativo %g mayor que %g",
+						q, got, want, math.Abs(got-want)/math.Abs(want), tt.accuracy)
+				}
+			}
+		})
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	a, b, all := NewSketch(0.01), NewSketch(0.01), NewSketch(0.01)
+	for i := 0; i < 5000; i++ {
+		v := rng.ExpFloat64() * 100
+		all.Add(v)
+		if i%2 == 0 {
+			a.Add(v)
+		} else {
+			b.Add(v)
+		}
+	}
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+		if got, want := a.Quantile(q), all.Quantile(q); got != want {
+			t.Errorf("Quantile(%g) combinado = %g, se esperaba %g", q, got, want)
+		}
+	}
+	if err := a.Merge(NewSketch(0.05)); err == nil {
+		t.Error("Merge con otra precisión: se esperaba un error")
+	}
+}
+
+func TestSketchEdgeCases(t *testing.T) {
+	empty := NewSketch(0.01)
+	if got := empty.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile de un sketch vacío = %g, se esperaba 0", got)
+	}
+	if got := empty.Histogram(10); got != nil {
+		t.Errorf("Histogram de un sketch vacío = %v, se esperaba nil", got)
+	}
+
+	s := NewSketch(0.01)
+	s.Add(math.NaN())
+	for i := 1; i <= 100; i++ {
+		s.Add(float64(i))
+	}
+	if got := s.Count(); got != 100 {
+		t.Errorf("Count() = %d, se esperaba 100 (NaN ignorado)", got)
+	}
+	if s.Quantile(0) != 1 || s.Quantile(1) != 100 {
+		t.Errorf("Quantile(0), Quantile(1) = %g, %g; se esperaban el mínimo y el máximo", s.Quantile(0), s.Quantile(1))
+	}
+
+	histogram := s.Histogram(10)
+	var total uint64
+	for _, b := range histogram {
+		total += b.Count
+	}
+	if len(histogram) != 10 || total != 100 || histogram[0].Lower != 1 || histogram[9].Upper != 100 {
+		t.Errorf("Histogram(10) = %v, se esperaban 10 cubetas entre 1 y 100 con 100 valores", histogram)
+	}
+}
@@ -9,6 +9,18 @@ type MetricsStatistics struct {
 	CPU          StatInfo  `json:"cpu"`
 	Memory       StatInfo  `json:"memory"`
 	Goroutines   StatInfo  `json:"goroutines"`
+	Disk         DiskStats `json:"disk"`
+	Network      NetworkStats `json:"network"`
+	Runtime      RuntimeStats `json:"runtime"`
+}
+
+// DiskStats contiene estadísticas de las tasas de E/S de disco agregadas
+type DiskStats struct {
+	ReadBytesPerSec  StatInfo `json:"read_bytes_per_sec"`
+	WriteBytesPerSec StatInfo `json:"write_bytes_per_sec"`
+	ReadOpsPerSec    StatInfo `json:"read_ops_per_sec"`
+	WriteOpsPerSec   StatInfo `json:"write_ops_per_sec"`
+	IOTimeMsPerSec   StatInfo `json:"io_time_ms_per_sec"`
 }
 
 // TimeRange representa un rango de tiempo
@@ -17,11 +29,46 @@ type TimeRange struct {
 	End   time.Time `json:"end"`
 }
 
-// StatInfo contiene estadísticas básicas (min, max, mean, std dev)
+// NetworkStats contiene estadísticas de las tasas de red agregadas
+type NetworkStats struct {
+	RxBytesPerSec   StatInfo `json:"rx_bytes_per_sec"`
+	TxBytesPerSec   StatInfo `json:"tx_bytes_per_sec"`
+	RxPacketsPerSec StatInfo `json:"rx_packets_per_sec"`
+	TxPacketsPerSec StatInfo `json:"tx_packets_per_sec"`
+	ErrorsPerSec    StatInfo `json:"errors_per_sec"`
+	DropsPerSec     StatInfo `json:"drops_per_sec"`
+}
+
+// RuntimeStats contiene estadísticas de las métricas del runtime de Go
+type RuntimeStats struct {
+	HeapBytes              StatInfo `json:"heap_bytes"`
+	HeapObjects            StatInfo `json:"heap_objects"`
+	AllocBytesPerSec       StatInfo `json:"alloc_bytes_per_sec"`
+	GCPauseP99Seconds      StatInfo `json:"gc_pause_p99_seconds"`
+	SchedLatencyP99Seconds StatInfo `json:"sched_latency_p99_seconds"`
+	MutexWaitPerSec        StatInfo `json:"mutex_wait_seconds_per_sec"`
+}
+
+// StatInfo contiene estadísticas básicas (min, max, mean, std dev) y,
+// según StatsOptions, varianza muestral, percentiles e histograma
 type StatInfo struct {
-	Min    float64 `json:"min"`
-	Max    float64 `json:"max"`
-	Mean   float64 `json:"mean"`
-	StdDev float64 `json:"std_dev"`
+	Min       float64           `json:"min"`
+	Max       float64           `json:"max"`
+	Mean      float64           `json:"mean"`
+	StdDev    float64           `json:"std_dev"`
+	Variance  *float64          `json:"variance,omitempty"` // Varianza muestral (n-1)
+	Median    *float64          `json:"median,omitempty"`
+	P50       *float64          `json:"p50,omitempty"`
+	P90       *float64          `json:"p90,omitempty"`
+	P95       *float64          `json:"p95,omitempty"`
+	P99       *float64          `json:"p99,omitempty"`
+	Histogram []HistogramBucket `json:"histogram,omitempty"`
+}
+
+// StatsOptions selecciona las estadísticas opcionales de StatInfo
+type StatsOptions struct {
+	Variance         bool // Incluir la varianza muestral
+	Percentiles      bool // Incluir mediana y percentiles 50, 90, 95 y 99
+	HistogramBuckets int  // Cubetas del histograma (0 = sin histograma)
 }
 
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store define el backend donde se guarda el historial de métricas.
+// Las muestras se agregan en orden cronológico.
+type Store interface {
+	// Append agrega una muestra al final del historial
+	Append(m SystemMetrics) error
+	// Range retorna las muestras con timestamp dentro de [from, to].
+	// Un tiempo cero indica que ese extremo no tiene límite.
+	Range(from, to time.Time) ([]SystemMetrics, error)
+	// Close libera los recursos del backend
+	Close() error
+}
+
+// MemoryStore guarda las últimas muestras en memoria
+type MemoryStore struct {
+	mu         sync.RWMutex
+	samples    []SystemMetrics
+	maxHistory int
+}
+
+// NewMemoryStore crea un almacenamiento en memoria con capacidad máxima
+func NewMemoryStore(maxHistory int) *MemoryStore {
+	if maxHistory <= 0 {
+		maxHistory = 100
+	}
+	return &MemoryStore{
+		samples:    make([]SystemMetrics, 0, maxHistory),
+		maxHistory: maxHistory,
+	}
+}
+
+// Append agrega una muestra descartando la más antigua si se supera la capacidad
+func (s *MemoryStore) Append(m SystemMetrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = append(s.samples, m)
+	if len(s.samples) > s.maxHistory {
+		s.samples = s.samples[1:]
+	}
+	return nil
+}
+
+// Range retorna una copia de las muestras dentro del rango
+func (s *MemoryStore) Range(from, to time.Time) ([]SystemMetrics, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start, end := rangeBounds(s.samples, from, to)
+	result := make([]SystemMetrics, end-start)
+	copy(result, s.samples[start:end])
+	return result, nil
+}
+
+// Close no hace nada para el almacenamiento en memoria
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// rangeBounds ubica por búsqueda binaria los índices [start, end) de las
+// muestras ordenadas que caen dentro de [from, to]
+func rangeBounds(samples []SystemMetrics, from, to time.Time) (int, int) {
+	start := 0
+	if !from.IsZero() {
+		start = sort.Search(len(samples), func(i int) bool {
+			return !samples[i].Timestamp.Before(from)
+		})
+	}
+	end := len(samples)
+	if !to.IsZero() {
+		end = sort.Search(len(samples), func(i int) bool {
+			return samples[i].Timestamp.After(to)
+		})
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import "log"
+
+// Subscribe registra un suscriptor que recibe cada muestra en cuanto el
+// recolector la produce. Si el suscriptor no consume a tiempo y su buffer
+// se llena, las muestras nuevas se descartan para él sin bloquear la
+// recolección. La función retornada cancela la suscripción; el canal se
+// cierra al cancelarla o al detener el recolector.
+func (c *Collector) Subscribe(buffer int) (<-chan SystemMetrics, func()) {
+	if buffer <= 0 {
+		buffer = 1
+	}
+	ch := make(chan SystemMetrics, buffer)
+
+	c.subMu.Lock()
+	c.nextSubscriber++
+	id := c.nextSubscriber
+	if c.ctx.Err() != nil {
+		// El recolector ya se detuvo: no habrá más muestras
+		close(ch)
+	} else {
+		c.subscribers[id] = ch
+	}
+	c.subMu.Unlock()
+
+	unsubscribe := func() {
+		c.subMu.Lock()
+		defer c.subMu.Unlock()
+		if sub, ok := c.subscribers[id]; ok {
+			delete(c.subscribers, id)
+			close(sub)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// publish envía una muestra a todos los suscriptores sin bloquear
+func (c *Collector) publish(m SystemMetrics) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for id, ch := range c.subscribers {
+		select {
+		case ch <- m:
+		default:
+			log.Printf("Suscriptor %d lento: se descartó la muestra de %s", id, m.Timestamp.Format("15:04:05"))
+		}
+	}
+}
+
+// closeSubscribers cierra los canales de todos los suscriptores
+func (c *Collector) closeSubscribers() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	for id, ch := range c.subscribers {
+		delete(c.subscribers, id)
+		close(ch)
+	}
+}
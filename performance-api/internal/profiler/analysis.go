@@ -0,0 +1,140 @@
+package profiler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/google/pprof/profile"
+)
+
+// ErrInvalidProfile indica que la operación pedida no aplica a los perfiles indicados
+var ErrInvalidProfile = errors.New("solicitud de perfil inválida")
+
+// unknownFunction nombra las ubicaciones sin información de símbolos
+const unknownFunction = "<desconocida>"
+
+// ParseProfile decodifica un perfil en formato protobuf de pprof
+func ParseProfile(data *ProfileData) (*profile.Profile, error) {
+	if data.IsText() {
+		return nil, fmt.Errorf("%w: el perfil %s está en formato de texto y no puede analizarse", ErrInvalidProfile, data.Name)
+	}
+	if data.Name == TraceName {
+		return nil, fmt.Errorf("%w: %s es una traza de ejecución y no un perfil de pprof", ErrInvalidProfile, data.ID)
+	}
+	p, err := profile.Parse(bytes.NewReader(data.Data))
+	if err != nil {
+		return nil, fmt.Errorf("error al decodificar el perfil %s: %w", data.Name, err)
+	}
+	return p, nil
+}
+
+// sampleIndex retorna el índice del tipo de muestra indicado. Vacío elige el
+// tipo por defecto del perfil (el último, como hace go tool pprof).
+func sampleIndex(p *profile.Profile, sampleType string) (int, error) {
+	if len(p.SampleType) == 0 {
+		return 0, fmt.Errorf("%w: el perfil no tiene tipos de muestra", ErrInvalidProfile)
+	}
+	if sampleType == "" {
+		sampleType = p.DefaultSampleType
+	}
+	if sampleType == "" {
+		return len(p.SampleType) - 1, nil
+	}
+	for i, st := range p.SampleType {
+		if st.Type == sampleType {
+			return i, nil
+		}
+	}
+	names := make([]string, len(p.SampleType))
+	for i, st := range p.SampleType {
+		names[i] = st.Type
+	}
+	return 0, fmt.Errorf("%w: tipo de muestra %q no existe en el perfil (disponibles: %v)", ErrInvalidProfile, sampleType, names)
+}
+
+// functionKey identifica una función del perfil
+type functionKey struct {
+	Name string
+	File string
+}
+
+// functionTotals acumula los valores propios (flat) y acumulados (cum) de una función
+type functionTotals struct {
+	Line int64 // Primera línea de la función vista en el perfil
+	Flat int64
+	Cum  int64
+}
+
+// frame es una función de una traza, con la línea ejecutada
+type frame struct {
+	key  functionKey
+	line int64
+}
+
+// sampleFrames retorna las funciones de una muestra desde la hoja hasta la
+// raíz, expandiendo las funciones inlineadas de cada ubicación
+func sampleFrames(s *profile.Sample) []frame {
+	frames := make([]frame, 0, len(s.Location))
+	for _, loc := range s.Location {
+		if len(loc.Line) == 0 {
+			name := unknownFunction
+			if loc.Address != 0 {
+				name = fmt.Sprintf("0x%x", loc.Address)
+			}
+			frames = append(frames, frame{key: functionKey{Name: name}})
+			continue
+		}
+		// Line[0] es la función más interna cuando hay inlining
+		for _, line := range loc.Line {
+			key := functionKey{Name: unknownFunction}
+			if line.Function != nil {
+				key = functionKey{Name: line.Function.Name, File: line.Function.Filename}
+			}
+			frames = append(frames, frame{key: key, line: line.Line})
+		}
+	}
+	return frames
+}
+
+// aggregateFunctions calcula flat y cum por función para un tipo de muestra.
+// Las funciones recursivas cuentan una sola vez por muestra en cum.
+func aggregateFunctions(p *profile.Profile, index int) (map[functionKey]*functionTotals, int64) {
+	totals := make(map[functionKey]*functionTotals)
+	var total int64
+
+	get := func(f frame) *functionTotals {
+		t, ok := totals[f.key]
+		if !ok {
+			t = &functionTotals{Line: f.line}
+			totals[f.key] = t
+		} else if f.line > 0 && (t.Line == 0 || f.line < t.Line) {
+			t.Line = f.line
+		}
+		return t
+	}
+
+	for _, s := range p.Sample {
+		value := s.Value[index]
+		if value == 0 {
+			continue
+		}
+		total += value
+
+		frames := sampleFrames(s)
+		if len(frames) == 0 {
+			continue
+		}
+		get(frames[0]).Flat += value
+
+		seen := make(map[functionKey]bool, len(frames))
+		for _, f := range frames {
+			if seen[f.key] {
+				continue
+			}
+			seen[f.key] = true
+			get(f).Cum += value
+		}
+	}
+	return totals, total
+}
@@ -0,0 +1,402 @@
+package profiler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Extensiones de los archivos del archivo de perfiles: cada perfil se
+// guarda como <id>.pb.gz (o <id>.trace si es una traza de ejecución)
+// junto a sus metadatos en <id>.json
+const (
+	profileDataExt = ".pb.gz"
+	traceDataExt   = ".trace"
+	profileMetaExt = ".json"
+)
+
+// ErrProfileNotFound indica que no existe un perfil con el ID o tipo pedido
+var ErrProfileNotFound = errors.New("perfil no encontrado")
+
+// ArchiveOptions configura el archivo de perfiles
+type ArchiveOptions struct {
+	Dir       string        // Directorio de los perfiles (vacío = solo en memoria)
+	MaxBytes  int64         // Tamaño total máximo; se descartan los más antiguos (0 = sin límite)
+	Retention time.Duration // Antigüedad máxima de los perfiles (0 = sin límite)
+}
+
+// ProfileInfo contiene los metadatos de un perfil archivado
+type ProfileInfo struct {
+	ID              string            `json:"id"`
+	Name            string            `json:"name"`
+	Timestamp       time.Time         `json:"timestamp"`
+	DurationSeconds float64           `json:"duration_seconds"`
+	Size            int64             `json:"size"`
+	Labels          map[string]string `json:"labels,omitempty"`
+}
+
+// ArchiveFilter selecciona perfiles al listar el archivo
+type ArchiveFilter struct {
+	Name   string            // Tipo de perfil (vacío = todos)
+	From   time.Time         // Inicio del rango (cero = sin límite)
+	To     time.Time         // Fin del rango (cero = sin límite)
+	Labels map[string]string // Etiquetas que deben coincidir
+	Limit  int               // Máximo de perfiles, conservando los más recientes (0 = sin límite)
+	Local  bool              // Excluye los perfiles de objetivos remotos (con etiqueta target)
+}
+
+// Archive guarda cada perfil capturado con un ID y aplica retención por
+// tamaño total y antigüedad
+type Archive struct {
+	mu       sync.RWMutex
+	opts     ArchiveOptions
+	entries  []ProfileInfo          // Ordenados por timestamp
+	byID     map[string]ProfileInfo // Las mismas entradas indexadas por ID
+	reserved map[string]bool        // IDs asignados a perfiles que se están escribiendo
+	data     map[string][]byte      // Contenido de los perfiles cuando no hay directorio
+	size     int64
+}
+
+// OpenArchive abre el archivo de perfiles y carga los perfiles guardados
+// en el directorio. Sin directorio los perfiles se guardan en memoria.
+func OpenArchive(opts ArchiveOptions) (*Archive, error) {
+	a := &Archive{opts: opts, byID: make(map[string]ProfileInfo), reserved: make(map[string]bool)}
+	if opts.Dir == "" {
+		a.data = make(map[string][]byte)
+		return a, nil
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error al crear el directorio de perfiles: %w", err)
+	}
+	if err := a.load(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.enforceRetention(time.Now())
+	a.mu.Unlock()
+	return a, nil
+}
+
+// Add archiva un perfil y le asigna un ID. Los archivos se escriben sin
+// retener el lock; el ID queda reservado mientras tanto.
+func (a *Archive) Add(profile *ProfileData) error {
+	a.mu.Lock()
+	profile.ID = a.newID(profile.Name, profile.Timestamp)
+	a.reserved[profile.ID] = true
+	a.mu.Unlock()
+
+	info := profile.Info()
+	if err := a.write(info, profile.Data); err != nil {
+		a.mu.Lock()
+		delete(a.reserved, info.ID)
+		a.mu.Unlock()
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.reserved, info.ID)
+	if a.opts.Dir == "" {
+		a.data[info.ID] = profile.Data
+	}
+	i := sort.Search(len(a.entries), func(i int) bool { return a.entries[i].Timestamp.After(info.Timestamp) })
+	a.entries = append(a.entries, ProfileInfo{})
+	copy(a.entries[i+1:], a.entries[i:])
+	a.entries[i] = info
+	a.byID[info.ID] = info
+	a.size += info.Size
+
+	a.enforceRetention(time.Now())
+	return nil
+}
+
+// write guarda el contenido y los metadatos de un perfil en el directorio
+func (a *Archive) write(info ProfileInfo, data []byte) error {
+	if a.opts.Dir == "" {
+		return nil
+	}
+	meta, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("error al serializar los metadatos del perfil: %w", err)
+	}
+	// Los metadatos se escriben al final: un perfil sin metadatos se descarta al abrir
+	if err := writeFileAtomic(a.path(info.ID, dataExt(info.Name)), data); err != nil {
+		return fmt.Errorf("error al guardar el perfil: %w", err)
+	}
+	if err := writeFileAtomic(a.path(info.ID, profileMetaExt), meta); err != nil {
+		os.Remove(a.path(info.ID, dataExt(info.Name)))
+		return fmt.Errorf("error al guardar los metadatos del perfil: %w", err)
+	}
+	return nil
+}
+
+// Get retorna un perfil archivado por ID
+func (a *Archive) Get(id string) (*ProfileData, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	info, ok := a.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrProfileNotFound, id)
+	}
+	return a.read(info)
+}
+
+// Latest retorna el perfil más reciente del tipo indicado
+func (a *Archive) Latest(name string) (*ProfileData, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		if a.entries[i].Name == name {
+			return a.read(a.entries[i])
+		}
+	}
+	return nil, fmt.Errorf("%w: no hay perfiles de tipo %q", ErrProfileNotFound, name)
+}
+
+// Lookup retorna un perfil por ID o, si ref es un tipo de perfil (por
+// ejemplo "heap"), el más reciente de ese tipo
+func (a *Archive) Lookup(ref string) (*ProfileData, error) {
+	profile, err := a.Get(ref)
+	if errors.Is(err, ErrProfileNotFound) {
+		if latest, latestErr := a.Latest(ref); latestErr == nil {
+			return latest, nil
+		}
+	}
+	return profile, err
+}
+
+// List retorna los metadatos de los perfiles que cumplen el filtro, del más
+// reciente al más antiguo
+func (a *Archive) List(filter ArchiveFilter) []ProfileInfo {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	list := make([]ProfileInfo, 0)
+	for i := len(a.entries) - 1; i >= 0; i-- {
+		info := a.entries[i]
+		if !filter.matches(info) {
+			continue
+		}
+		list = append(list, info)
+		if filter.Limit > 0 && len(list) == filter.Limit {
+			break
+		}
+	}
+	return list
+}
+
+// Names retorna los tipos de perfil presentes en el archivo
+func (a *Archive) Names() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	for _, info := range a.entries {
+		if !seen[info.Name] {
+			seen[info.Name] = true
+			names = append(names, info.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Delete elimina un perfil archivado
+func (a *Archive) Delete(id string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	info, ok := a.byID[id]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrProfileNotFound, id)
+	}
+	// Las entradas están ordenadas por timestamp: buscar desde el primero con el del perfil
+	i := sort.Search(len(a.entries), func(i int) bool { return !a.entries[i].Timestamp.Before(info.Timestamp) })
+	for ; i < len(a.entries); i++ {
+		if a.entries[i].ID == id {
+			a.remove(i)
+			break
+		}
+	}
+	return nil
+}
+
+// matches indica si un perfil cumple el filtro
+func (f ArchiveFilter) matches(info ProfileInfo) bool {
+	if f.Name != "" && info.Name != f.Name {
+		return false
+	}
+	if !f.From.IsZero() && info.Timestamp.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && info.Timestamp.After(f.To) {
+		return false
+	}
+	if f.Local && info.Labels[TargetLabel] != "" {
+		return false
+	}
+	for k, v := range f.Labels {
+		if info.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// read carga el contenido de un perfil
+func (a *Archive) read(info ProfileInfo) (*ProfileData, error) {
+	var data []byte
+	if a.opts.Dir == "" {
+		data = a.data[info.ID]
+	} else {
+		var err error
+		data, err = os.ReadFile(a.path(info.ID, dataExt(info.Name)))
+		if err != nil {
+			return nil, fmt.Errorf("error al leer el perfil %s: %w", info.ID, err)
+		}
+	}
+
+	return &ProfileData{
+		ID:              info.ID,
+		Name:            info.Name,
+		Timestamp:       info.Timestamp,
+		DurationSeconds: info.DurationSeconds,
+		Labels:          info.Labels,
+		Data:            data,
+	}, nil
+}
+
+// remove elimina la entrada i y sus archivos
+func (a *Archive) remove(i int) {
+	info := a.entries[i]
+	if a.opts.Dir == "" {
+		delete(a.data, info.ID)
+	} else {
+		os.Remove(a.path(info.ID, profileMetaExt))
+		os.Remove(a.path(info.ID, dataExt(info.Name)))
+	}
+	a.size -= info.Size
+	a.entries = append(a.entries[:i], a.entries[i+1:]...)
+	delete(a.byID, info.ID)
+}
+
+// enforceRetention descarta los perfiles más antiguos que la retención y,
+// si se supera el tamaño máximo, los más antiguos hasta volver al límite
+func (a *Archive) enforceRetention(now time.Time) {
+	if a.opts.Retention > 0 {
+		cutoff := now.Add(-a.opts.Retention)
+		for len(a.entries) > 0 && a.entries[0].Timestamp.Before(cutoff) {
+			a.remove(0)
+		}
+	}
+	if a.opts.MaxBytes > 0 {
+		// Se conserva siempre el perfil más reciente aunque supere el límite
+		for len(a.entries) > 1 && a.size > a.opts.MaxBytes {
+			a.remove(0)
+		}
+	}
+}
+
+// load lee los metadatos de los perfiles guardados en el directorio y
+// elimina los archivos incompletos
+func (a *Archive) load() error {
+	files, err := os.ReadDir(a.opts.Dir)
+	if err != nil {
+		return fmt.Errorf("error al leer el directorio de perfiles: %w", err)
+	}
+
+	known := make(map[string]bool)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), profileMetaExt) {
+			continue
+		}
+		metaPath := filepath.Join(a.opts.Dir, f.Name())
+		meta, err := os.ReadFile(metaPath)
+		if err != nil {
+			return fmt.Errorf("error al leer los metadatos %s: %w", f.Name(), err)
+		}
+		var info ProfileInfo
+		if err := json.Unmarshal(meta, &info); err != nil || info.ID == "" {
+			os.Remove(metaPath)
+			continue
+		}
+		stat, err := os.Stat(a.path(info.ID, dataExt(info.Name)))
+		if err != nil {
+			// Metadatos sin perfil
+			os.Remove(metaPath)
+			continue
+		}
+		info.Size = stat.Size()
+		known[info.ID] = true
+		a.entries = append(a.entries, info)
+		a.byID[info.ID] = info
+		a.size += info.Size
+	}
+
+	// Perfiles sin metadatos (escritura interrumpida) o temporales
+	for _, f := range files {
+		name := f.Name()
+		orphan := false
+		for _, ext := range []string{profileDataExt, traceDataExt} {
+			if strings.HasSuffix(name, ext) && !known[strings.TrimSuffix(name, ext)] {
+				orphan = true
+			}
+		}
+		if orphan || strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(a.opts.Dir, name))
+		}
+	}
+
+	sort.Slice(a.entries, func(i, j int) bool { return a.entries[i].Timestamp.Before(a.entries[j].Timestamp) })
+	return nil
+}
+
+// newID genera un ID único legible a partir del tipo y el momento de captura
+func (a *Archive) newID(name string, ts time.Time) string {
+	base := name + "-" + ts.UTC().Format("20060102T150405.000000000Z")
+	id := base
+	for n := 2; a.exists(id); n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return id
+}
+
+// exists indica si ya hay un perfil con el ID indicado o si está reservado
+func (a *Archive) exists(id string) bool {
+	_, ok := a.byID[id]
+	return ok || a.reserved[id]
+}
+
+// path retorna la ruta de un archivo del perfil
+func (a *Archive) path(id, ext string) string {
+	return filepath.Join(a.opts.Dir, id+ext)
+}
+
+// dataExt retorna la extensión del contenido de un perfil según su tipo
+func dataExt(name string) string {
+	if name == TraceName {
+		return traceDataExt
+	}
+	return profileDataExt
+}
+
+// writeFileAtomic escribe un archivo completo o nada, usando un temporal y rename
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,88 @@
+package profiler
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestArchiveAddGetDelete(t *testing.T) {
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{"en memoria", ""},
+		{"en disco", t.TempDir()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := OpenArchive(ArchiveOptions{Dir: tt.dir})
+			if err != nil {
+				t.Fatalf("OpenArchive: %v", err)
+			}
+
+			// Perfiles concurrentes con el mismo timestamp reciben IDs distintos
+			ts := time.Now()
+			const n = 16
+			profiles := make([]*ProfileData, n)
+			var wg sync.WaitGroup
+			for i := range profiles {
+				profiles[i] = &ProfileData{Name: "heap", Timestamp: ts, Data: []byte{byte(i)}}
+				wg.Add(1)
+				go func(p *ProfileData) {
+					defer wg.Done()
+					if err := archive.Add(p); err != nil {
+						t.Errorf("Add: %v", err)
+					}
+				}(profiles[i])
+			}
+			wg.Wait()
+
+			ids := make(map[string]bool)
+			for i, p := range profiles {
+				if ids[p.ID] {
+					t.Fatalf("ID repetido %q", p.ID)
+				}
+				ids[p.ID] = true
+				got, err := archive.Get(p.ID)
+				if err != nil {
+					t.Fatalf("Get(%q): %v", p.ID, err)
+				}
+				if len(got.Data) != 1 || got.Data[0] != byte(i) {
+					t.Errorf("Get(%q).Data = %v, se esperaba [%d]", p.ID, got.Data, i)
+				}
+			}
+			if got := len(archive.List(ArchiveFilter{})); got != n {
+				t.Errorf("List retornó %d perfiles, se esperaban %d", got, n)
+			}
+
+			deleted := profiles[n/2].ID
+			if err := archive.Delete(deleted); err != nil {
+				t.Fatalf("Delete(%q): %v", deleted, err)
+			}
+			if _, err := archive.Get(deleted); !errors.Is(err, ErrProfileNotFound) {
+				t.Errorf("Get tras Delete: error %v, se esperaba ErrProfileNotFound", err)
+			}
+			if err := archive.Delete(deleted); !errors.Is(err, ErrProfileNotFound) {
+				t.Errorf("Delete repetido: error %v, se esperaba ErrProfileNotFound", err)
+			}
+			if got := len(archive.List(ArchiveFilter{})); got != n-1 {
+				t.Errorf("List retornó %d perfiles tras Delete, se esperaban %d", got, n-1)
+			}
+
+			if tt.dir != "" {
+				reopened, err := OpenArchive(ArchiveOptions{Dir: tt.dir})
+				if err != nil {
+					t.Fatalf("OpenArchive al reabrir: %v", err)
+				}
+				if _, err := reopened.Get(profiles[0].ID); err != nil {
+					t.Errorf("Get tras reabrir: %v", err)
+				}
+				if got := len(reopened.List(ArchiveFilter{})); got != n-1 {
+					t.Errorf("List retornó %d perfiles tras reabrir, se esperaban %d", got, n-1)
+				}
+			}
+		})
+	}
+}
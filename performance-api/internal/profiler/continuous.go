@@ -0,0 +1,165 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ContinuousSource es el valor de la etiqueta "source" de los perfiles
+// capturados por el perfilamiento continuo
+const ContinuousSource = "continuous"
+
+// ContinuousOptions configura el perfilamiento continuo
+type ContinuousOptions struct {
+	Interval    time.Duration // Frecuencia de captura
+	CPUDuration time.Duration // Duración de cada perfil de CPU
+	Types       []string      // Tipos de perfil a capturar (cpu, heap, allocs, goroutine, mutex, block, threadcreate)
+	Retention   time.Duration // Antigüedad máxima de los perfiles continuos (0 = la del archivo)
+}
+
+// Scheduler captura perfiles periódicamente y los guarda en el archivo con
+// la etiqueta source=continuous
+type Scheduler struct {
+	profiler  *Profiler
+	opts      ContinuousOptions
+	cpu       bool     // Capturar perfil de CPU en cada ronda
+	snapshots []string // Tipos de perfil instantáneo de cada ronda
+	labels    map[string]string
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewScheduler crea el planificador de perfilamiento continuo
+func NewScheduler(p *Profiler, opts ContinuousOptions) (*Scheduler, error) {
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("el intervalo del perfilamiento continuo debe ser positivo")
+	}
+
+	s := &Scheduler{
+		profiler: p,
+		opts:     opts,
+		labels:   map[string]string{"source": ContinuousSource},
+	}
+	for _, name := range opts.Types {
+		switch {
+		case name == "cpu":
+			s.cpu = true
+		case p.snapshotFunc(name) != nil:
+			s.snapshots = append(s.snapshots, name)
+		default:
+			return nil, fmt.Errorf("tipo de perfil continuo desconocido %q", name)
+		}
+	}
+	if s.cpu && (opts.CPUDuration < time.Second || opts.CPUDuration > MaxCPUProfileDuration || opts.CPUDuration >= opts.Interval) {
+		return nil, fmt.Errorf("la duración del perfil de CPU continuo debe estar entre 1s y %v y ser menor que el intervalo", MaxCPUProfileDuration)
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s, nil
+}
+
+// Start captura perfiles cada intervalo hasta que se llame a Stop
+func (s *Scheduler) Start() {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	// Capturar inmediatamente
+	s.capture()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.capture()
+		}
+	}
+}
+
+// Stop detiene el planificador; un perfil de CPU en curso se descarta
+func (s *Scheduler) Stop() {
+	s.cancel()
+}
+
+// capture toma una ronda de perfiles: primero el de CPU y al terminar las
+// instantáneas, para que todos cubran el mismo momento
+func (s *Scheduler) capture() {
+	if s.cpu {
+		_, err := s.profiler.GetCPUProfile(s.ctx, int(s.opts.CPUDuration.Seconds()), s.labels, false)
+		var busy *CPUBusyError
+		switch {
+		case errors.As(err, &busy):
+			// No esperar: el perfilamiento continuo cede ante los perfiles pedidos
+			log.Printf("Perfil de CPU continuo omitido: %v", err)
+		case s.ctx.Err() != nil:
+			return
+		case err != nil:
+			log.Printf("Error en el perfil de CPU continuo: %v", err)
+		}
+	}
+
+	for _, name := range s.snapshots {
+		if _, err := s.profiler.snapshotFunc(name)(0, s.labels); err != nil {
+			log.Printf("Error en el perfil %s continuo: %v", name, err)
+		}
+	}
+
+	s.prune()
+}
+
+// prune elimina los perfiles continuos más antiguos que la retención
+func (s *Scheduler) prune() {
+	if s.opts.Retention <= 0 {
+		return
+	}
+	archive := s.profiler.archive
+	expired := archive.List(ArchiveFilter{
+		To:     time.Now().Add(-s.opts.Retention),
+		Labels: s.labels,
+	})
+	for _, info := range expired {
+		if err := archive.Delete(info.ID); err != nil && !errors.Is(err, ErrProfileNotFound) {
+			log.Printf("Error al eliminar el perfil continuo %s: %v", info.ID, err)
+		}
+	}
+}
+
+// snapshotFunc retorna la función que captura un perfil instantáneo por tipo
+func (p *Profiler) snapshotFunc(name string) func(debug int, labels map[string]string) (*ProfileData, error) {
+	switch name {
+	case "heap":
+		return p.GetHeapProfile
+	case "allocs":
+		return p.GetAllocsProfile
+	case "goroutine":
+		return p.GetGoroutineProfile
+	case "block":
+		return p.GetBlockProfile
+	case "mutex":
+		return p.GetMutexProfile
+	case "threadcreate":
+		return p.GetThreadcreateProfile
+	}
+	return nil
+}
+
+// CanCapture indica si el tipo de perfil se puede capturar con Capture
+func (p *Profiler) CanCapture(name string) bool {
+	return name == "cpu" || p.snapshotFunc(name) != nil
+}
+
+// Capture captura y archiva un perfil por tipo; el de CPU dura cpuDuration
+// y falla con CPUBusyError si hay otro en curso
+func (p *Profiler) Capture(ctx context.Context, name string, cpuDuration time.Duration, labels map[string]string) (*ProfileData, error) {
+	if name == "cpu" {
+		return p.GetCPUProfile(ctx, int(cpuDuration.Seconds()), labels, false)
+	}
+	snapshot := p.snapshotFunc(name)
+	if snapshot == nil {
+		return nil, fmt.Errorf("%w: tipo de perfil desconocido %q", ErrInvalidProfile, name)
+	}
+	return snapshot(0, labels)
+}
@@ -0,0 +1,181 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// MaxCPUProfileDuration es la duración máxima de una sesión de perfil de CPU
+const MaxCPUProfileDuration = 300 * time.Second
+
+var (
+	// ErrCPUProfileBusy indica que ya hay un perfil de CPU en curso
+	ErrCPUProfileBusy = errors.New("ya hay un perfil de CPU en curso")
+
+	// ErrNoCPUSession indica que no hay un perfil de CPU en curso
+	ErrNoCPUSession = errors.New("no hay un perfil de CPU en curso")
+)
+
+// CPUSession describe la sesión de perfil de CPU activa
+type CPUSession struct {
+	StartedAt time.Time         `json:"started_at"`
+	Deadline  time.Time         `json:"deadline"` // Fin programado de la sesión
+	Manual    bool              `json:"manual"`   // Iniciada con start y terminada con stop
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// ETA retorna el tiempo que falta para el fin programado de la sesión
+func (s CPUSession) ETA() time.Duration {
+	return max(time.Until(s.Deadline), 0)
+}
+
+// CPUBusyError indica que otra sesión de perfil de CPU está activa y cuándo
+// termina, para que el cliente pueda reintentar
+type CPUBusyError struct {
+	Active *CPUSession // Nulo si el perfil lo inició otro componente (por ejemplo /debug/pprof)
+}
+
+// Error describe la sesión que ocupa el perfilador
+func (e *CPUBusyError) Error() string {
+	if e.Active == nil {
+		return ErrCPUProfileBusy.Error()
+	}
+	return fmt.Sprintf("%s (termina en %.0f segundos)", ErrCPUProfileBusy, e.Active.ETA().Seconds())
+}
+
+// Unwrap permite comparar el error con ErrCPUProfileBusy
+func (e *CPUBusyError) Unwrap() error {
+	return ErrCPUProfileBusy
+}
+
+// cpuSession es una sesión de perfil de CPU en curso
+type cpuSession struct {
+	info     CPUSession
+	buf      bytes.Buffer
+	stop     chan struct{} // Se cierra para terminar la sesión antes de tiempo
+	stopOnce sync.Once
+	done     chan struct{} // Se cierra cuando la sesión terminó y result está listo
+	result   *ProfileData
+	err      error
+}
+
+// CPUSession retorna la sesión de perfil de CPU activa, si la hay
+func (p *Profiler) CPUSession() (CPUSession, bool) {
+	p.cpuMu.Lock()
+	defer p.cpuMu.Unlock()
+	if p.cpu == nil {
+		return CPUSession{}, false
+	}
+	return p.cpu.info, true
+}
+
+// StartCPUProfile inicia una sesión de perfil de CPU que termina con
+// StopCPUProfile o, como máximo, al cumplirse limit
+func (p *Profiler) StartCPUProfile(limit time.Duration, labels map[string]string) (CPUSession, error) {
+	s, err := p.startCPUSession(context.Background(), limit, labels, true, false)
+	if err != nil {
+		return CPUSession{}, err
+	}
+	go p.runCPUSession(context.Background(), s)
+	return s.info, nil
+}
+
+// StopCPUProfile termina la sesión de perfil de CPU activa, iniciada con
+// StartCPUProfile o por una solicitud con duración, y retorna el perfil
+func (p *Profiler) StopCPUProfile() (*ProfileData, error) {
+	p.cpuMu.Lock()
+	s := p.cpu
+	p.cpuMu.Unlock()
+	if s == nil {
+		return nil, ErrNoCPUSession
+	}
+
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+	return s.result, s.err
+}
+
+// startCPUSession inicia el perfil de CPU. Si hay otra sesión activa retorna
+// CPUBusyError o, con wait, espera a que termine mientras ctx siga vigente.
+func (p *Profiler) startCPUSession(ctx context.Context, limit time.Duration, labels map[string]string, manual, wait bool) (*cpuSession, error) {
+	if limit <= 0 || limit > MaxCPUProfileDuration {
+		limit = MaxCPUProfileDuration
+	}
+
+	for {
+		p.cpuMu.Lock()
+		active := p.cpu
+		if active == nil {
+			now := time.Now()
+			s := &cpuSession{
+				info: CPUSession{
+					StartedAt: now,
+					Deadline:  now.Add(limit),
+					Manual:    manual,
+					Labels:    labels,
+				},
+				stop: make(chan struct{}),
+				done: make(chan struct{}),
+			}
+			if err := pprof.StartCPUProfile(&s.buf); err != nil {
+				p.cpuMu.Unlock()
+				// El perfil de CPU lo inició otro componente del proceso
+				return nil, &CPUBusyError{}
+			}
+			p.cpu = s
+			p.cpuMu.Unlock()
+			return s, nil
+		}
+		p.cpuMu.Unlock()
+
+		if !wait {
+			info := active.info
+			return nil, &CPUBusyError{Active: &info}
+		}
+		select {
+		case <-active.done:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("espera del perfil de CPU cancelada: %w", ctx.Err())
+		}
+	}
+}
+
+// runCPUSession espera el fin de la sesión (plazo, stop o cancelación de
+// ctx), detiene el perfil y lo archiva salvo que se haya cancelado
+func (p *Profiler) runCPUSession(ctx context.Context, s *cpuSession) {
+	timer := time.NewTimer(time.Until(s.info.Deadline))
+	defer timer.Stop()
+
+	cancelled := false
+	select {
+	case <-timer.C:
+	case <-s.stop:
+	case <-ctx.Done():
+		cancelled = true
+	}
+	pprof.StopCPUProfile()
+	end := time.Now()
+
+	p.cpuMu.Lock()
+	p.cpu = nil
+	p.cpuMu.Unlock()
+
+	if cancelled {
+		s.err = fmt.Errorf("perfil de CPU cancelado: %w", ctx.Err())
+	} else {
+		s.result = &ProfileData{
+			Name:            "cpu",
+			Timestamp:       end,
+			DurationSeconds: end.Sub(s.info.StartedAt).Seconds(),
+			Labels:          s.info.Labels,
+			Data:            s.buf.Bytes(),
+		}
+		p.store(s.result)
+	}
+	close(s.done)
+}
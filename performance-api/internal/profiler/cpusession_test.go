@@ -0,0 +1,108 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCPUProfileManualSession(t *testing.T) {
+	p := NewProfiler()
+	session, err := p.StartCPUProfile(10*time.Second, map[string]string{"caso": "manual"})
+	if err != nil {
+		t.Fatalf("StartCPUProfile: %v", err)
+	}
+	if !session.Manual || session.Deadline.Sub(session.StartedAt) != 10*time.Second {
+		t.Errorf("sesión = %+v, se esperaba manual con 10s de plazo", session)
+	}
+
+	// Mientras la sesión está activa las demás reciben CPUBusyError con su ETA
+	for name, start := range map[string]func() error{
+		"start": func() error { _, err := p.StartCPUProfile(time.Second, nil); return err },
+		"get":   func() error { _, err := p.GetCPUProfile(context.Background(), 1, nil, false); return err },
+	} {
+		err := start()
+		var busy *CPUBusyError
+		if !errors.As(err, &busy) || !errors.Is(err, ErrCPUProfileBusy) || busy.Active == nil {
+			t.Fatalf("%s = %v, se esperaba CPUBusyError con la sesión activa", name, err)
+		}
+		if eta := busy.Active.ETA(); eta <= 8*time.Second || eta > 10*time.Second {
+			t.Errorf("%s: ETA = %v, se esperaba cerca de 10s", name, eta)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	profile, err := p.StopCPUProfile()
+	if err != nil {
+		t.Fatalf("StopCPUProfile: %v", err)
+	}
+	if profile.Name != "cpu" || len(profile.Data) == 0 || profile.DurationSeconds >= 10 || profile.Labels["caso"] != "manual" {
+		t.Errorf("perfil = {%s %d bytes %gs %v}, se esperaba un perfil de CPU corto con la etiqueta caso",
+			profile.Name, len(profile.Data), profile.DurationSeconds, profile.Labels)
+	}
+	if _, err := ParseProfile(profile); err != nil {
+		t.Errorf("el perfil no es válido: %v", err)
+	}
+	archived := p.Archive().List(ArchiveFilter{Name: "cpu"})
+	if len(archived) != 1 || archived[0].ID != profile.ID {
+		t.Errorf("archivados = %v, se esperaba el perfil detenido", archived)
+	}
+
+	if _, ok := p.CPUSession(); ok {
+		t.Error("la sesión sigue activa tras StopCPUProfile")
+	}
+	if _, err := p.StopCPUProfile(); !errors.Is(err, ErrNoCPUSession) {
+		t.Errorf("StopCPUProfile sin sesión = %v, se esperaba ErrNoCPUSession", err)
+	}
+}
+
+func TestCPUProfileCancel(t *testing.T) {
+	p := NewProfiler()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.GetCPUProfile(ctx, 30, nil, false)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("GetCPUProfile = %v, se esperaba la cancelación", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("el perfil terminó tras %v, se esperaba al cancelar", elapsed)
+	}
+	if got := len(p.Archive().List(ArchiveFilter{Name: "cpu"})); got != 0 {
+		t.Errorf("se archivaron %d perfiles cancelados", got)
+	}
+	if _, ok := p.CPUSession(); ok {
+		t.Error("la sesión sigue activa tras cancelarla")
+	}
+}
+
+func TestCPUProfileWaitsForActiveSession(t *testing.T) {
+	p := NewProfiler()
+	if _, err := p.StartCPUProfile(10*time.Second, nil); err != nil {
+		t.Fatalf("StartCPUProfile: %v", err)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := p.GetCPUProfile(context.Background(), 1, nil, true)
+		result <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := p.StopCPUProfile(); err != nil {
+		t.Fatalf("StopCPUProfile: %v", err)
+	}
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("GetCPUProfile con espera: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("la solicitud en espera no se atendió tras terminar la sesión")
+	}
+	if got := len(p.Archive().List(ArchiveFilter{Name: "cpu"})); got != 2 {
+		t.Errorf("se archivaron %d perfiles, se esperaban 2", got)
+	}
+}
@@ -0,0 +1,163 @@
+package profiler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/pprof/profile"
+)
+
+// FunctionDelta contiene el cambio de una función entre dos perfiles
+type FunctionDelta struct {
+	Function   string `json:"function"`
+	File       string `json:"file,omitempty"`
+	BaseFlat   int64  `json:"base_flat"`
+	TargetFlat int64  `json:"target_flat"`
+	DeltaFlat  int64  `json:"delta_flat"`
+	BaseCum    int64  `json:"base_cum"`
+	TargetCum  int64  `json:"target_cum"`
+	DeltaCum   int64  `json:"delta_cum"`
+}
+
+// ProfileDiff contiene la comparación de dos perfiles del mismo tipo
+type ProfileDiff struct {
+	Base        ProfileInfo     `json:"base"`
+	Target      ProfileInfo     `json:"target"`
+	SampleType  string          `json:"sample_type"`
+	Unit        string          `json:"unit"`
+	BaseTotal   int64           `json:"base_total"`
+	TargetTotal int64           `json:"target_total"`
+	DeltaTotal  int64           `json:"delta_total"`
+	Functions   []FunctionDelta `json:"functions"`
+}
+
+// DiffOptions configura la comparación de perfiles
+type DiffOptions struct {
+	SampleType string // Tipo de muestra (vacío = el tipo por defecto del perfil)
+	SortBy     string // "flat" (por defecto) o "cum": ordena por el cambio absoluto
+	Limit      int    // Máximo de funciones (0 = todas)
+}
+
+// Diff compara dos perfiles archivados (por ID o tipo) y retorna el cambio
+// por función junto con un perfil pprof que combina el objetivo con la base
+// negada, equivalente a go tool pprof -diff_base
+func (p *Profiler) Diff(baseRef, targetRef string, opts DiffOptions) (*ProfileDiff, *profile.Profile, error) {
+	baseData, err := p.archive.Lookup(baseRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	targetData, err := p.archive.Lookup(targetRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	if baseData.Name != targetData.Name {
+		return nil, nil, fmt.Errorf("%w: no se pueden comparar perfiles de tipos distintos (%s y %s)", ErrInvalidProfile, baseData.Name, targetData.Name)
+	}
+
+	base, err := ParseProfile(baseData)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := ParseProfile(targetData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	index, err := sampleIndex(target, opts.SampleType)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseIndex, err := sampleIndex(base, target.SampleType[index].Type)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	diff := &ProfileDiff{
+		Base:       baseData.Info(),
+		Target:     targetData.Info(),
+		SampleType: target.SampleType[index].Type,
+		Unit:       target.SampleType[index].Unit,
+		Functions:  make([]FunctionDelta, 0),
+	}
+
+	baseTotals, baseTotal := aggregateFunctions(base, baseIndex)
+	targetTotals, targetTotal := aggregateFunctions(target, index)
+	diff.BaseTotal = baseTotal
+	diff.TargetTotal = targetTotal
+	diff.DeltaTotal = targetTotal - baseTotal
+
+	keys := make(map[functionKey]bool, len(targetTotals))
+	for key := range baseTotals {
+		keys[key] = true
+	}
+	for key := range targetTotals {
+		keys[key] = true
+	}
+	for key := range keys {
+		delta := FunctionDelta{Function: key.Name, File: key.File}
+		if t, ok := baseTotals[key]; ok {
+			delta.BaseFlat, delta.BaseCum = t.Flat, t.Cum
+		}
+		if t, ok := targetTotals[key]; ok {
+			delta.TargetFlat, delta.TargetCum = t.Flat, t.Cum
+		}
+		delta.DeltaFlat = delta.TargetFlat - delta.BaseFlat
+		delta.DeltaCum = delta.TargetCum - delta.BaseCum
+		if delta.DeltaFlat == 0 && delta.DeltaCum == 0 {
+			continue
+		}
+		diff.Functions = append(diff.Functions, delta)
+	}
+
+	sortDeltas(diff.Functions, opts.SortBy)
+	if opts.Limit > 0 && len(diff.Functions) > opts.Limit {
+		diff.Functions = diff.Functions[:opts.Limit]
+	}
+
+	merged, err := diffProfile(base, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	return diff, merged, nil
+}
+
+// diffProfile combina el perfil objetivo con la base negada. Las muestras de
+// la base llevan la etiqueta pprof::base, como las que genera -diff_base.
+func diffProfile(base, target *profile.Profile) (*profile.Profile, error) {
+	base = base.Copy()
+	base.Scale(-1)
+	for _, s := range base.Sample {
+		if s.Label == nil {
+			s.Label = make(map[string][]string)
+		}
+		s.Label["pprof::base"] = []string{"true"}
+	}
+
+	merged, err := profile.Merge([]*profile.Profile{base, target.Copy()})
+	if err != nil {
+		return nil, fmt.Errorf("%w: los perfiles no son compatibles: %v", ErrInvalidProfile, err)
+	}
+	return merged, nil
+}
+
+// sortDeltas ordena por el cambio absoluto (flat o cum) de mayor a menor
+func sortDeltas(deltas []FunctionDelta, by string) {
+	abs := func(v int64) int64 {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+	value := func(d FunctionDelta) int64 { return abs(d.DeltaFlat) }
+	if by == "cum" {
+		value = func(d FunctionDelta) int64 { return abs(d.DeltaCum) }
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		vi, vj := value(deltas[i]), value(deltas[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return deltas[i].Function < deltas[j].Function
+	})
+}
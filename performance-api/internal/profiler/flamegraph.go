@@ -0,0 +1,375 @@
+package profiler
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// FoldedStack es una traza de llamadas desde la raíz hasta la hoja con su valor
+type FoldedStack struct {
+	Frames []string
+	Value  int64
+}
+
+// FoldedStacks contiene las trazas de un perfil en formato "folded"
+type FoldedStacks struct {
+	Profile    ProfileInfo
+	SampleType string
+	Unit       string
+	Total      int64
+	Stacks     []FoldedStack // Ordenadas alfabéticamente por traza
+}
+
+// FlameGraphOptions configura el SVG del flame graph
+type FlameGraphOptions struct {
+	Icicle bool   // Raíz arriba y trazas hacia abajo en lugar de hacia arriba
+	Title  string // Título del gráfico (vacío = tipo y ID del perfil)
+	Width  int    // Ancho en píxeles (0 = 1200)
+}
+
+// Folded decodifica un perfil archivado (por ID o tipo) y agrupa sus
+// muestras por traza de llamadas
+func (p *Profiler) Folded(ref, sampleType string) (*FoldedStacks, error) {
+	data, err := p.archive.Lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	prof, err := ParseProfile(data)
+	if err != nil {
+		return nil, err
+	}
+	index, err := sampleIndex(prof, sampleType)
+	if err != nil {
+		return nil, err
+	}
+
+	folded := &FoldedStacks{
+		Profile:    data.Info(),
+		SampleType: prof.SampleType[index].Type,
+		Unit:       prof.SampleType[index].Unit,
+	}
+
+	values := make(map[string]int64)
+	for _, s := range prof.Sample {
+		value := s.Value[index]
+		if value == 0 {
+			continue
+		}
+		frames := sampleFrames(s)
+		if len(frames) == 0 {
+			frames = []frame{{key: functionKey{Name: unknownFunction}}}
+		}
+		names := make([]string, len(frames))
+		for i, f := range frames {
+			// Las trazas folded van de la raíz a la hoja; ";" separa los marcos
+			names[len(frames)-1-i] = strings.ReplaceAll(f.key.Name, ";", ":")
+		}
+		values[strings.Join(names, ";")] += value
+		folded.Total += value
+	}
+
+	stacks := make([]string, 0, len(values))
+	for stack := range values {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+	for _, stack := range stacks {
+		folded.Stacks = append(folded.Stacks, FoldedStack{Frames: strings.Split(stack, ";"), Value: values[stack]})
+	}
+	return folded, nil
+}
+
+// WriteFolded escribe las trazas en el formato de Brendan Gregg: una línea
+// por traza con los marcos separados por ";" seguidos del valor
+func (f *FoldedStacks) WriteFolded(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range f.Stacks {
+		if _, err := fmt.Fprintf(bw, "%s %d\n", strings.Join(s.Frames, ";"), s.Value); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// flameNode es un marco del flame graph con sus hijos
+type flameNode struct {
+	name     string
+	value    int64
+	children map[string]*flameNode
+}
+
+// child retorna el hijo con el nombre indicado, creándolo si no existe
+func (n *flameNode) child(name string) *flameNode {
+	if n.children == nil {
+		n.children = make(map[string]*flameNode)
+	}
+	c, ok := n.children[name]
+	if !ok {
+		c = &flameNode{name: name}
+		n.children[name] = c
+	}
+	return c
+}
+
+// Dimensiones del flame graph
+const (
+	flameFrameHeight = 16
+	flameFontSize    = 12
+	flamePadX        = 10
+	flamePadTop      = 50
+	flamePadBottom   = 30
+	flameMinWidth    = 0.1 // Ancho mínimo en píxeles para dibujar un marco
+	flameDefaultW    = 1200
+)
+
+// flameRect es un marco ya ubicado en el gráfico
+type flameRect struct {
+	name  string
+	value int64
+	x     float64 // Posición relativa al total (0 a 1)
+	w     float64 // Ancho relativo al total (0 a 1)
+	depth int
+}
+
+// WriteFlameGraph escribe un flame graph SVG interactivo y autocontenido:
+// clic para ampliar un marco, búsqueda con expresiones regulares y detalle
+// del valor al pasar el puntero
+func (f *FoldedStacks) WriteFlameGraph(w io.Writer, opts FlameGraphOptions) error {
+	width := opts.Width
+	if width <= 0 {
+		width = flameDefaultW
+	}
+	title := opts.Title
+	if title == "" {
+		kind := "Flame Graph"
+		if opts.Icicle {
+			kind = "Icicle Graph"
+		}
+		title = fmt.Sprintf("%s: %s (%s)", kind, f.Profile.ID, f.SampleType)
+	}
+
+	root := &flameNode{name: "all"}
+	for _, s := range f.Stacks {
+		root.value += s.Value
+		node := root
+		for _, frame := range s.Frames {
+			node = node.child(frame)
+			node.value += s.Value
+		}
+	}
+
+	// Ubicar los marcos con los hijos en orden alfabético, como flamegraph.pl
+	var rects []flameRect
+	maxDepth := 0
+	minFraction := flameMinWidth / float64(width-2*flamePadX)
+	var layout func(n *flameNode, x float64, depth int)
+	layout = func(n *flameNode, x float64, depth int) {
+		if root.value == 0 {
+			return
+		}
+		fraction := float64(n.value) / float64(root.value)
+		if fraction < minFraction {
+			return
+		}
+		rects = append(rects, flameRect{name: n.name, value: n.value, x: x, w: fraction, depth: depth})
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			c := n.children[name]
+			layout(c, x, depth+1)
+			x += float64(c.value) / float64(root.value)
+		}
+	}
+	layout(root, 0, 0)
+
+	height := flamePadTop + (maxDepth+1)*flameFrameHeight + flamePadBottom
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, `<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg" data-icicle="%t" data-total="%d" data-unit="%s" data-pad="%d" data-font="%d">
+<style>
+text { font-family: Verdana, sans-serif; font-size: %dpx; fill: #000; }
+#title { text-anchor: middle; font-size: 17px; }
+#details, #matched { font-size: 12px; }
+#search, #reset { cursor: pointer; }
+#reset { display: none; }
+g.f { cursor: pointer; }
+g.f:hover rect { stroke: #000; stroke-width: 0.5; }
+g.hidden { display: none; }
+g.parent rect { opacity: 0.5; }
+</style>
+<rect x="0" y="0" width="100%%" height="100%%" fill="#f8f8f8"/>
+<text id="title" x="%d" y="24">%s</text>
+<text id="reset" x="%d" y="24">Restablecer zoom</text>
+<text id="search" x="%d" y="24" text-anchor="end">Buscar</text>
+<text id="details" x="%d" y="%d"> </text>
+<text id="matched" x="%d" y="%d" text-anchor="end"> </text>
+<g id="frames">
+`, width, height, width, height, opts.Icicle, root.value, html.EscapeString(f.Unit), flamePadX, flameFontSize, flameFontSize,
+		width/2, html.EscapeString(title),
+		flamePadX, width-flamePadX,
+		flamePadX, height-10, width-flamePadX, height-10)
+
+	plot := float64(width - 2*flamePadX)
+	for _, r := range rects {
+		y := flamePadTop + (maxDepth-r.depth)*flameFrameHeight
+		if opts.Icicle {
+			y = flamePadTop + r.depth*flameFrameHeight
+		}
+		name := html.EscapeString(r.name)
+		fmt.Fprintf(bw, `<g class="f" data-x="%.9f" data-w="%.9f" data-d="%d" data-v="%d"><title>%s</title>`+
+			`<rect x="%.2f" y="%d" width="%.2f" height="%d" rx="2" ry="2" fill="%s"/>`+
+			`<text x="%.2f" y="%d">%s</text></g>`+"\n",
+			r.x, r.w, r.depth, r.value, name,
+			flamePadX+r.x*plot, y, r.w*plot, flameFrameHeight-1, flameColor(r.name),
+			flamePadX+r.x*plot+3, y+flameFrameHeight-4, html.EscapeString(fitLabel(r.name, r.w*plot)))
+	}
+
+	fmt.Fprintf(bw, "</g>\n<script type=\"text/ecmascript\"><![CDATA[\n%s\n]]></script>\n</svg>\n", flameScript)
+	return bw.Flush()
+}
+
+// fitLabel recorta el nombre de un marco para que quepa en su ancho,
+// contando runas para no cortar un carácter UTF-8 por la mitad
+func fitLabel(name string, widthPx float64) string {
+	chars := int((widthPx - 6) / (flameFontSize * 0.59))
+	if chars < 3 {
+		return ""
+	}
+	if utf8.RuneCountInString(name) <= chars {
+		return name
+	}
+	return string([]rune(name)[:chars-2]) + ".."
+}
+
+// flameColor asigna un color cálido estable a cada función
+func flameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	r := 205 + v%50
+	g := (v >> 8) % 230
+	b := (v >> 16) % 55
+	return fmt.Sprintf("rgb(%d,%d,%d)", r, g, b)
+}
+
+// flameScript implementa la interacción del flame graph dentro del SVG
+const flameScript = `
+(function () {
+  var svg = document.documentElement;
+  var frames = Array.prototype.slice.call(document.querySelectorAll("g.f"));
+  var details = document.getElementById("details");
+  var matched = document.getElementById("matched");
+  var reset = document.getElementById("reset");
+  var search = document.getElementById("search");
+  var total = +svg.getAttribute("data-total");
+  var unit = svg.getAttribute("data-unit");
+  var width = +svg.getAttribute("width");
+  var padX = +svg.getAttribute("data-pad");
+  var fontSize = +svg.getAttribute("data-font");
+  var plot = width - 2 * padX;
+  var eps = 1e-9;
+
+  function describe(g) {
+    var v = +g.getAttribute("data-v");
+    var name = g.querySelector("title").textContent;
+    return name + " (" + v.toLocaleString() + " " + unit + ", " + (100 * v / total).toFixed(2) + "%)";
+  }
+
+  function fit(g, w) {
+    var text = g.querySelector("text");
+    var name = g.querySelector("title").textContent;
+    var chars = Math.floor((w - 6) / (fontSize * 0.59));
+    text.textContent = chars < 3 ? "" : (name.length <= chars ? name : name.substring(0, chars - 2) + "..");
+  }
+
+  function place(g, x, w) {
+    var rect = g.querySelector("rect");
+    var text = g.querySelector("text");
+    rect.setAttribute("x", padX + x * plot);
+    rect.setAttribute("width", w * plot);
+    text.setAttribute("x", padX + x * plot + 3);
+    fit(g, w * plot);
+  }
+
+  function zoom(target) {
+    var x = +target.getAttribute("data-x");
+    var w = +target.getAttribute("data-w");
+    var d = +target.getAttribute("data-d");
+    frames.forEach(function (g) {
+      var fx = +g.getAttribute("data-x");
+      var fw = +g.getAttribute("data-w");
+      var fd = +g.getAttribute("data-d");
+      g.classList.remove("hidden", "parent");
+      if (fd < d && fx <= x + eps && fx + fw >= x + w - eps) {
+        g.classList.add("parent");
+        place(g, 0, 1);
+      } else if (fd >= d && fx >= x - eps && fx + fw <= x + w + eps) {
+        place(g, (fx - x) / w, fw / w);
+      } else {
+        g.classList.add("hidden");
+      }
+    });
+    reset.style.display = d > 0 ? "block" : "none";
+  }
+
+  function unzoom() {
+    frames.forEach(function (g) {
+      g.classList.remove("hidden", "parent");
+      place(g, +g.getAttribute("data-x"), +g.getAttribute("data-w"));
+    });
+    reset.style.display = "none";
+  }
+
+  function find(pattern) {
+    var re;
+    try { re = new RegExp(pattern); } catch (e) { return; }
+    var ranges = [];
+    frames.forEach(function (g) {
+      var rect = g.querySelector("rect");
+      if (!rect.hasAttribute("data-fill")) rect.setAttribute("data-fill", rect.getAttribute("fill"));
+      var hit = pattern !== "" && re.test(g.querySelector("title").textContent);
+      rect.setAttribute("fill", hit ? "rgb(230,0,230)" : rect.getAttribute("data-fill"));
+      if (hit) ranges.push([+g.getAttribute("data-x"), +g.getAttribute("data-w")]);
+    });
+    // Sumar los intervalos sin contar dos veces los marcos anidados
+    ranges.sort(function (a, b) { return a[0] - b[0] || b[1] - a[1]; });
+    var covered = 0, end = -1;
+    ranges.forEach(function (r) {
+      if (r[0] >= end - eps) { covered += r[1]; end = r[0] + r[1]; }
+      else if (r[0] + r[1] > end) { covered += r[0] + r[1] - end; end = r[0] + r[1]; }
+    });
+    matched.textContent = pattern === "" ? " " : "Coincidencias: " + (100 * covered).toFixed(2) + "%";
+  }
+
+  frames.forEach(function (g) {
+    g.addEventListener("click", function () { zoom(g); });
+    g.addEventListener("mouseover", function () { details.textContent = describe(g); });
+    g.addEventListener("mouseout", function () { details.textContent = " "; });
+  });
+  reset.addEventListener("click", unzoom);
+  search.addEventListener("click", function () {
+    var pattern = prompt("Buscar funciones (expresión regular):", "");
+    if (pattern !== null) find(pattern);
+  });
+  document.addEventListener("keydown", function (e) {
+    if ((e.ctrlKey || e.metaKey) && e.key === "f") {
+      e.preventDefault();
+      var pattern = prompt("Buscar funciones (expresión regular):", "");
+      if (pattern !== null) find(pattern);
+    }
+  });
+})();
+`
@@ -0,0 +1,35 @@
+package profiler
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFitLabel(t *testing.T) {
+	charWidth := flameFontSize * 0.59
+	width := func(chars int) float64 { return 6 + float64(chars)*charWidth + 0.1 }
+
+	tests := []struct {
+		name  string
+		label string
+		width float64
+		want  string
+	}{
+		{"cabe completo", "main.main", width(20), "main.main"},
+		{"recorte ascii", "runtime.gcBgMarkWorker", width(10), "runtime..."},
+		{"recorte utf8", "pkg.función·año", width(10), "pkg.func.."},
+		{"runas multibyte", "ñññññññññññ", width(6), "ññññ.."},
+		{"demasiado angosto", "main.main", width(2), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fitLabel(tt.label, tt.width)
+			if got != tt.want {
+				t.Errorf("fitLabel(%q) = %q, se esperaba %q", tt.label, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("fitLabel(%q) = %q no es UTF-8 válido", tt.label, got)
+			}
+		})
+	}
+}
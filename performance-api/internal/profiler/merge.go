@@ -0,0 +1,83 @@
+package profiler
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// DefaultMergeWindow es el rango que se combina cuando la consulta no indica su inicio
+const DefaultMergeWindow = time.Hour
+
+// MaxMergedProfiles limita cuántos perfiles se cargan en memoria para una
+// combinación; se conservan los más recientes
+const MaxMergedProfiles = 100
+
+// Merge combina en un solo perfil todos los perfiles archivados de un tipo
+// que cumplen el filtro, hasta MaxMergedProfiles, sumando sus muestras. El
+// resultado no se archiva.
+// Los perfiles de objetivos remotos son de otros binarios, así que solo se
+// incluyen si el filtro pide un objetivo con la etiqueta target.
+func (p *Profiler) Merge(filter ArchiveFilter) (*ProfileData, []ProfileInfo, error) {
+	if filter.Name == "" {
+		return nil, nil, fmt.Errorf("%w: se debe indicar el tipo de perfil a combinar", ErrInvalidProfile)
+	}
+	if filter.Labels[TargetLabel] == "" {
+		filter.Local = true
+	}
+	if filter.Limit <= 0 || filter.Limit > MaxMergedProfiles {
+		filter.Limit = MaxMergedProfiles
+	}
+	infos := p.archive.List(filter)
+	if len(infos) == 0 {
+		return nil, nil, fmt.Errorf("%w: no hay perfiles de tipo %q en el rango indicado", ErrProfileNotFound, filter.Name)
+	}
+
+	profiles := make([]*profile.Profile, 0, len(infos))
+	var duration float64
+	for _, info := range infos {
+		data, err := p.archive.Get(info.ID)
+		if err != nil {
+			// Eliminado por la retención mientras se combinaba
+			continue
+		}
+		prof, err := ParseProfile(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		profiles = append(profiles, prof)
+		duration += info.DurationSeconds
+	}
+	if len(profiles) == 0 {
+		return nil, nil, fmt.Errorf("%w: no hay perfiles de tipo %q en el rango indicado", ErrProfileNotFound, filter.Name)
+	}
+
+	merged, err := profile.Merge(profiles)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: no se pudieron combinar los perfiles: %v", ErrInvalidProfile, err)
+	}
+	var buf bytes.Buffer
+	if err := merged.Write(&buf); err != nil {
+		return nil, nil, fmt.Errorf("error al codificar el perfil combinado: %w", err)
+	}
+
+	return &ProfileData{
+		Name:            filter.Name + "-merged",
+		Timestamp:       latestTimestamp(infos),
+		DurationSeconds: duration,
+		Data:            buf.Bytes(),
+	}, infos, nil
+}
+
+// latestTimestamp retorna el momento de captura más reciente
+func latestTimestamp(infos []ProfileInfo) time.Time {
+	var latest time.Time
+	for _, info := range infos {
+		if info.Timestamp.After(latest) {
+			latest = info.Timestamp
+		}
+	}
+	return latest
+}
@@ -0,0 +1,40 @@
+package profiler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMergeCapsProfiles(t *testing.T) {
+	valid := heapProfileBytes(t)
+	p := NewProfiler()
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < MaxMergedProfiles+5; i++ {
+		p.store(&ProfileData{Name: "heap", Timestamp: base.Add(time.Duration(i) * time.Second), Data: valid})
+	}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"sin límite", 0, MaxMergedProfiles},
+		{"límite mayor que el máximo", MaxMergedProfiles * 2, MaxMergedProfiles},
+		{"límite menor", 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, sources, err := p.Merge(ArchiveFilter{Name: "heap", Limit: tt.limit})
+			if err != nil {
+				t.Fatalf("Merge: %v", err)
+			}
+			if len(sources) != tt.want {
+				t.Fatalf("se combinaron %d perfiles, se esperaban %d", len(sources), tt.want)
+			}
+			// Se conservan los más recientes
+			if want := base.Add(time.Duration(MaxMergedProfiles+4) * time.Second); !merged.Timestamp.Equal(want) {
+				t.Errorf("Timestamp = %v, se esperaba %v", merged.Timestamp, want)
+			}
+		})
+	}
+}
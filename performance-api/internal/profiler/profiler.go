@@ -2,7 +2,9 @@ package profiler
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"log"
 	"runtime"
 	"runtime/pprof"
 	"sync"
@@ -11,146 +13,184 @@ import (
 
 // Profiler gestiona el perfilamiento de funciones
 type Profiler struct {
-	mu sync.RWMutex
-	profiles map[string]*ProfileData
+	archive *Archive
+
+	ratesMu   sync.Mutex
+	blockRate int // Última tasa de bloqueos fijada con SetRates
+
+	cpuMu sync.Mutex
+	cpu   *cpuSession // Sesión de perfil de CPU activa
+
+	targets *Targets // Servicios remotos de los que se obtienen perfiles
 }
 
 // ProfileData contiene información de un perfil
 type ProfileData struct {
-	Name      string    `json:"name"`
-	Timestamp time.Time `json:"timestamp"`
-	Data      string    `json:"data"`
+	ID              string            `json:"id,omitempty"` // Vacío si el perfil no se archivó
+	Name            string            `json:"name"`
+	Timestamp       time.Time         `json:"timestamp"`
+	DurationSeconds float64           `json:"duration_seconds,omitempty"`
+	Debug           int               `json:"debug"` // 0 = protobuf comprimido de pprof; 1 o 2 = texto legible
+	Labels          map[string]string `json:"labels,omitempty"`
+	Data            []byte            `json:"data"`
 }
 
-// NewProfiler crea una nueva instancia del perfilador
-func NewProfiler() *Profiler {
-	return &Profiler{
-		profiles: make(map[string]*ProfileData),
+// IsText indica si el perfil está en formato de texto legible
+func (d *ProfileData) IsText() bool {
+	return d.Debug > 0
+}
+
+// Info retorna los metadatos del perfil
+func (d *ProfileData) Info() ProfileInfo {
+	return ProfileInfo{
+		ID:              d.ID,
+		Name:            d.Name,
+		Timestamp:       d.Timestamp,
+		DurationSeconds: d.DurationSeconds,
+		Size:            int64(len(d.Data)),
+		Labels:          d.Labels,
 	}
 }
 
-// GetCPUProfile obtiene el perfil de CPU actual
-func (p *Profiler) GetCPUProfile(seconds int) (*ProfileData, error) {
-	var buf bytes.Buffer
-	
-	// Iniciar perfil de CPU
-	if err := pprof.StartCPUProfile(&buf); err != nil {
-		return nil, fmt.Errorf("error al iniciar CPU profile: %w", err)
+// NewProfiler crea una nueva instancia del perfilador con archivo en memoria
+func NewProfiler() *Profiler {
+	archive, _ := OpenArchive(ArchiveOptions{MaxBytes: 64 << 20}) // Sin directorio no puede fallar
+	return NewProfilerWithArchive(archive)
+}
+
+// NewProfilerWithArchive crea un perfilador que guarda cada captura en el archivo indicado
+func NewProfilerWithArchive(archive *Archive) *Profiler {
+	p := &Profiler{
+		archive: archive,
 	}
-	
-	// Esperar el tiempo especificado
-	time.Sleep(time.Duration(seconds) * time.Second)
-	
-	// Detener perfil de CPU
-	pprof.StopCPUProfile()
-	
-	profileData := &ProfileData{
-		Name:      "cpu",
-		Timestamp: time.Now(),
-		Data:      buf.String(),
+	p.targets = newTargets(p)
+	return p
+}
+
+// Archive retorna el archivo de perfiles
+func (p *Profiler) Archive() *Archive {
+	return p.archive
+}
+
+// Targets retorna el registro de servicios remotos
+func (p *Profiler) Targets() *Targets {
+	return p.targets
+}
+
+// GetCPUProfile obtiene un perfil de CPU de los segundos indicados. Solo
+// puede haber un perfil de CPU a la vez: si hay otro en curso retorna
+// CPUBusyError o, con wait, espera a que termine. Si ctx se cancela el
+// perfil se detiene y no se archiva.
+func (p *Profiler) GetCPUProfile(ctx context.Context, seconds int, labels map[string]string, wait bool) (*ProfileData, error) {
+	session, err := p.startCPUSession(ctx, time.Duration(seconds)*time.Second, labels, false, wait)
+	if err != nil {
+		return nil, err
 	}
-	
-	p.mu.Lock()
-	p.profiles["cpu"] = profileData
-	p.mu.Unlock()
-	
-	return profileData, nil
+
+	p.runCPUSession(ctx, session)
+
+	return session.result, session.err
 }
 
-// GetHeapProfile obtiene el perfil de memoria heap
-func (p *Profiler) GetHeapProfile() (*ProfileData, error) {
+// GetHeapProfile obtiene el perfil de memoria heap. Con debug > 0 se
+// obtiene en formato de texto en lugar de protobuf.
+func (p *Profiler) GetHeapProfile(debug int, labels map[string]string) (*ProfileData, error) {
 	var buf bytes.Buffer
 	
 	// Forzar garbage collection antes de obtener el perfil
 	runtime.GC()
 	
 	// Obtener perfil de heap
-	if err := pprof.WriteHeapProfile(&buf); err != nil {
+	if err := pprof.Lookup("heap").WriteTo(&buf, debug); err != nil {
 		return nil, fmt.Errorf("error al obtener heap profile: %w", err)
 	}
 	
 	profileData := &ProfileData{
 		Name:      "heap",
 		Timestamp: time.Now(),
-		Data:      buf.String(),
+		Debug:     debug,
+		Labels:    labels,
+		Data:      buf.Bytes(),
 	}
 	
-	p.mu.Lock()
-	p.profiles["heap"] = profileData
-	p.mu.Unlock()
+	p.store(profileData)
 	
 	return profileData, nil
 }
 
-// GetGoroutineProfile obtiene el perfil de goroutines
-func (p *Profiler) GetGoroutineProfile() (*ProfileData, error) {
-	var buf bytes.Buffer
-	
-	profile := pprof.Lookup("goroutine")
-	if profile == nil {
-		return nil, fmt.Errorf("no se pudo obtener el perfil de goroutines")
-	}
-	
-	if err := profile.WriteTo(&buf, 0); err != nil {
-		return nil, fmt.Errorf("error al escribir goroutine profile: %w", err)
-	}
-	
-	profileData := &ProfileData{
-		Name:      "goroutine",
-		Timestamp: time.Now(),
-		Data:      buf.String(),
-	}
-	
-	p.mu.Lock()
-	p.profiles["goroutine"] = profileData
-	p.mu.Unlock()
-	
-	return profileData, nil
+// GetGoroutineProfile obtiene el perfil de goroutines. Con debug > 0 se obtiene en
+// formato de texto (2 = trazas completas de cada goroutine).
+func (p *Profiler) GetGoroutineProfile(debug int, labels map[string]string) (*ProfileData, error) {
+	return p.lookupProfile("goroutine", debug, labels)
+}
+
+// GetBlockProfile obtiene el perfil de bloqueos. Solo registra eventos si
+// la tasa de bloqueos (block_profile_rate) es mayor que cero.
+func (p *Profiler) GetBlockProfile(debug int, labels map[string]string) (*ProfileData, error) {
+	return p.lookupProfile("block", debug, labels)
+}
+
+// GetMutexProfile obtiene el perfil de contención de mutex. Solo registra
+// eventos si la fracción de mutex (mutex_profile_fraction) es mayor que cero.
+func (p *Profiler) GetMutexProfile(debug int, labels map[string]string) (*ProfileData, error) {
+	return p.lookupProfile("mutex", debug, labels)
+}
+
+// GetAllocsProfile obtiene el perfil de todas las asignaciones desde el
+// inicio del programa (mismos datos que heap, con alloc_space por defecto)
+func (p *Profiler) GetAllocsProfile(debug int, labels map[string]string) (*ProfileData, error) {
+	return p.lookupProfile("allocs", debug, labels)
+}
+
+// GetThreadcreateProfile obtiene el perfil de creación de hilos del sistema operativo
+func (p *Profiler) GetThreadcreateProfile(debug int, labels map[string]string) (*ProfileData, error) {
+	return p.lookupProfile("threadcreate", debug, labels)
 }
 
-// GetBlockProfile obtiene el perfil de bloqueos
-func (p *Profiler) GetBlockProfile() (*ProfileData, error) {
+// lookupProfile obtiene un perfil instantáneo del runtime por nombre
+func (p *Profiler) lookupProfile(name string, debug int, labels map[string]string) (*ProfileData, error) {
 	var buf bytes.Buffer
-	
-	profile := pprof.Lookup("block")
+
+	profile := pprof.Lookup(name)
 	if profile == nil {
-		return nil, fmt.Errorf("no se pudo obtener el perfil de bloqueos")
+		return nil, fmt.Errorf("no se pudo obtener el perfil %s", name)
 	}
-	
-	if err := profile.WriteTo(&buf, 0); err != nil {
-		return nil, fmt.Errorf("error al escribir block profile: %w", err)
+
+	if err := profile.WriteTo(&buf, debug); err != nil {
+		return nil, fmt.Errorf("error al escribir %s profile: %w", name, err)
 	}
-	
+
 	profileData := &ProfileData{
-		Name:      "block",
+		Name:      name,
 		Timestamp: time.Now(),
-		Data:      buf.String(),
+		Debug:     debug,
+		Labels:    labels,
+		Data:      buf.Bytes(),
 	}
-	
-	p.mu.Lock()
-	p.profiles["block"] = profileData
-	p.mu.Unlock()
-	
+
+	p.store(profileData)
+
 	return profileData, nil
 }
 
-// GetProfile obtiene un perfil guardado por nombre
+// GetProfile obtiene el último perfil guardado de un tipo
 func (p *Profiler) GetProfile(name string) (*ProfileData, bool) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	profile, exists := p.profiles[name]
-	return profile, exists
+	profile, err := p.archive.Latest(name)
+	return profile, err == nil
 }
 
-// ListProfiles retorna la lista de perfiles disponibles
+// ListProfiles retorna la lista de tipos de perfil disponibles
 func (p *Profiler) ListProfiles() []string {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-	
-	profiles := make([]string, 0, len(p.profiles))
-	for name := range p.profiles {
-		profiles = append(profiles, name)
-	}
-	return profiles
+	return p.archive.Names()
 }
 
+// store archiva un perfil en formato protobuf. Los formatos de texto son
+// solo una vista legible y no se archivan.
+func (p *Profiler) store(profile *ProfileData) {
+	if profile.IsText() {
+		return
+	}
+	if err := p.archive.Add(profile); err != nil {
+		log.Printf("Error al archivar el perfil %s: %v", profile.Name, err)
+	}
+}
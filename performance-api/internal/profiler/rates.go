@@ -0,0 +1,66 @@
+package profiler
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// RateSettings contiene las tasas de muestreo de los perfiles del runtime
+type RateSettings struct {
+	BlockProfileRate     int `json:"block_profile_rate"`     // Nanosegundos de bloqueo por muestra (0 = desactivado)
+	MutexProfileFraction int `json:"mutex_profile_fraction"` // Se registra 1 de cada N contenciones (0 = desactivado)
+	MemProfileRate       int `json:"mem_profile_rate"`       // Bytes asignados por muestra (0 = desactivado)
+}
+
+// RateUpdate indica las tasas a modificar; los campos nulos no cambian
+type RateUpdate struct {
+	BlockProfileRate     *int `json:"block_profile_rate,omitempty"`
+	MutexProfileFraction *int `json:"mutex_profile_fraction,omitempty"`
+	MemProfileRate       *int `json:"mem_profile_rate,omitempty"`
+}
+
+// Rates retorna las tasas de muestreo actuales
+func (p *Profiler) Rates() RateSettings {
+	p.ratesMu.Lock()
+	defer p.ratesMu.Unlock()
+	return p.rates()
+}
+
+// SetRates modifica las tasas de muestreo del runtime y retorna las nuevas.
+// El cambio de MemProfileRate solo afecta a las asignaciones posteriores.
+func (p *Profiler) SetRates(update RateUpdate) (RateSettings, error) {
+	for name, value := range map[string]*int{
+		"block_profile_rate":     update.BlockProfileRate,
+		"mutex_profile_fraction": update.MutexProfileFraction,
+		"mem_profile_rate":       update.MemProfileRate,
+	} {
+		if value != nil && *value < 0 {
+			return RateSettings{}, fmt.Errorf("%w: %s no puede ser negativo (%d)", ErrInvalidProfile, name, *value)
+		}
+	}
+
+	p.ratesMu.Lock()
+	defer p.ratesMu.Unlock()
+
+	if update.BlockProfileRate != nil {
+		// El runtime no permite leer la tasa de bloqueos: se recuerda la última
+		runtime.SetBlockProfileRate(*update.BlockProfileRate)
+		p.blockRate = *update.BlockProfileRate
+	}
+	if update.MutexProfileFraction != nil {
+		runtime.SetMutexProfileFraction(*update.MutexProfileFraction)
+	}
+	if update.MemProfileRate != nil {
+		runtime.MemProfileRate = *update.MemProfileRate
+	}
+	return p.rates(), nil
+}
+
+// rates lee las tasas actuales; requiere ratesMu
+func (p *Profiler) rates() RateSettings {
+	return RateSettings{
+		BlockProfileRate:     p.blockRate,
+		MutexProfileFraction: runtime.SetMutexProfileFraction(-1), // Un valor negativo solo consulta
+		MemProfileRate:       runtime.MemProfileRate,
+	}
+}
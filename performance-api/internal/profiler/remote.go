@@ -0,0 +1,380 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// TargetLabel es la etiqueta con el nombre del objetivo en los perfiles
+// obtenidos de un servicio remoto
+const TargetLabel = "target"
+
+// maxRemoteProfileBytes limita el tamaño de un perfil descargado
+const maxRemoteProfileBytes = 64 << 20
+
+// maxRemoteErrorBytes limita el cuerpo de una respuesta de error que se
+// registra en el log; nunca se retorna al cliente de la API
+const maxRemoteErrorBytes = 512
+
+var (
+	// ErrTargetNotFound indica que no existe un objetivo con el nombre indicado
+	ErrTargetNotFound = errors.New("objetivo remoto no encontrado")
+
+	// ErrTargetExists indica que ya hay un objetivo con el nombre indicado
+	ErrTargetExists = errors.New("ya existe un objetivo remoto con ese nombre")
+
+	// ErrRemoteFetch indica que el servicio remoto no entregó un perfil válido
+	ErrRemoteFetch = errors.New("error al obtener el perfil remoto")
+
+	// ErrTargetNotAllowed indica que el host del objetivo no está en la lista permitida
+	ErrTargetNotAllowed = errors.New("host de objetivo remoto no permitido")
+)
+
+// remoteTypes son los tipos de perfil que se pueden pedir a net/http/pprof
+var remoteTypes = map[string]bool{
+	"cpu": true, "heap": true, "goroutine": true, "allocs": true,
+	"block": true, "mutex": true, "threadcreate": true,
+}
+
+// targetNamePattern restringe los nombres de objetivo a los válidos en una ruta
+var targetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// TargetOptions describe un servicio de Go que expone net/http/pprof
+type TargetOptions struct {
+	Name        string        // Identificador del objetivo, usado en la etiqueta target
+	URL         string        // URL base del servicio (por ejemplo http://localhost:6060)
+	Types       []string      // Tipos de perfil de las capturas programadas
+	Interval    time.Duration // Frecuencia de las capturas programadas (0 = solo bajo demanda)
+	CPUDuration time.Duration // Duración del perfil de CPU de las capturas programadas
+}
+
+// TargetInfo describe un objetivo registrado y el resultado de su última captura
+type TargetInfo struct {
+	Name            string     `json:"name"`
+	URL             string     `json:"url"`
+	Types           []string   `json:"types"`
+	IntervalSeconds float64    `json:"interval_seconds,omitempty"` // 0 = solo bajo demanda
+	CPUSeconds      float64    `json:"cpu_seconds"`
+	LastFetch       *time.Time `json:"last_fetch,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	Fetches         int        `json:"fetches"` // Perfiles obtenidos con éxito
+}
+
+// target es el estado interno de un objetivo registrado
+type target struct {
+	opts   TargetOptions
+	base   *url.URL
+	mu     sync.Mutex
+	info   TargetInfo
+	cancel context.CancelFunc // Detiene las capturas programadas
+}
+
+// Targets gestiona los servicios remotos de los que se obtienen perfiles y
+// los guarda en el archivo del perfilador con la etiqueta target
+type Targets struct {
+	profiler *Profiler
+	client   *http.Client
+
+	mu           sync.RWMutex
+	targets      map[string]*target
+	allowedHosts map[string]bool // Hosts que se pueden registrar con CheckHost
+}
+
+// newTargets crea el registro de objetivos remotos del perfilador
+func newTargets(p *Profiler) *Targets {
+	return &Targets{
+		profiler: p,
+		client: &http.Client{
+			// Las redirecciones podrían llevar a un host no permitido
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		targets:      make(map[string]*target),
+		allowedHosts: make(map[string]bool),
+	}
+}
+
+// AllowHosts define los hosts ("host" o "host:puerto") que acepta CheckHost
+func (t *Targets) AllowHosts(hosts []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.allowedHosts = make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		t.allowedHosts[strings.ToLower(host)] = true
+	}
+}
+
+// CheckHost verifica que la URL de un objetivo apunte a un host permitido.
+// Se usa para los objetivos registrados por la API; los de la
+// configuración no se restringen.
+func (t *Targets) CheckHost(rawURL string) error {
+	base, err := url.Parse(rawURL)
+	if err != nil || base.Host == "" {
+		return fmt.Errorf("%w: URL de objetivo inválida %q (usa http://host:puerto)", ErrInvalidProfile, rawURL)
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.allowedHosts[strings.ToLower(base.Host)] || t.allowedHosts[strings.ToLower(base.Hostname())] {
+		return nil
+	}
+	return fmt.Errorf("%w: %s (agrégalo a profiles.target_hosts)", ErrTargetNotAllowed, base.Host)
+}
+
+// Add registra un objetivo remoto e inicia sus capturas programadas, si tiene intervalo
+func (t *Targets) Add(opts TargetOptions) (TargetInfo, error) {
+	if !targetNamePattern.MatchString(opts.Name) {
+		return TargetInfo{}, fmt.Errorf("%w: nombre de objetivo inválido %q (usa letras, números, '.', '_' o '-')", ErrInvalidProfile, opts.Name)
+	}
+	base, err := url.Parse(opts.URL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return TargetInfo{}, fmt.Errorf("%w: URL de objetivo inválida %q (usa http://host:puerto)", ErrInvalidProfile, opts.URL)
+	}
+	if len(opts.Types) == 0 {
+		opts.Types = []string{"cpu", "heap", "goroutine"}
+	}
+	for _, name := range opts.Types {
+		if !remoteTypes[name] {
+			return TargetInfo{}, fmt.Errorf("%w: tipo de perfil remoto desconocido %q", ErrInvalidProfile, name)
+		}
+	}
+	if opts.CPUDuration == 0 {
+		opts.CPUDuration = 10 * time.Second
+	}
+	if opts.CPUDuration < time.Second || opts.CPUDuration > MaxCPUProfileDuration {
+		return TargetInfo{}, fmt.Errorf("%w: la duración del perfil de CPU remoto debe estar entre 1s y %v", ErrInvalidProfile, MaxCPUProfileDuration)
+	}
+	if opts.Interval < 0 || (opts.Interval > 0 && opts.Interval <= opts.CPUDuration) {
+		return TargetInfo{}, fmt.Errorf("%w: el intervalo del objetivo debe ser mayor que la duración del perfil de CPU", ErrInvalidProfile)
+	}
+
+	tg := &target{
+		opts: opts,
+		base: base,
+		info: TargetInfo{
+			Name:            opts.Name,
+			URL:             opts.URL,
+			Types:           opts.Types,
+			IntervalSeconds: opts.Interval.Seconds(),
+			CPUSeconds:      opts.CPUDuration.Seconds(),
+		},
+	}
+
+	t.mu.Lock()
+	if _, ok := t.targets[opts.Name]; ok {
+		t.mu.Unlock()
+		return TargetInfo{}, fmt.Errorf("%w: %s", ErrTargetExists, opts.Name)
+	}
+	t.targets[opts.Name] = tg
+	if opts.Interval > 0 {
+		var ctx context.Context
+		ctx, tg.cancel = context.WithCancel(context.Background())
+		go t.schedule(ctx, tg)
+	}
+	t.mu.Unlock()
+
+	return tg.snapshot(), nil
+}
+
+// Remove elimina un objetivo y detiene sus capturas programadas; los
+// perfiles ya archivados se conservan
+func (t *Targets) Remove(name string) error {
+	t.mu.Lock()
+	tg, ok := t.targets[name]
+	delete(t.targets, name)
+	t.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTargetNotFound, name)
+	}
+	if tg.cancel != nil {
+		tg.cancel()
+	}
+	return nil
+}
+
+// Get retorna la información de un objetivo
+func (t *Targets) Get(name string) (TargetInfo, error) {
+	tg, err := t.lookup(name)
+	if err != nil {
+		return TargetInfo{}, err
+	}
+	return tg.snapshot(), nil
+}
+
+// List retorna los objetivos registrados ordenados por nombre
+func (t *Targets) List() []TargetInfo {
+	t.mu.RLock()
+	list := make([]TargetInfo, 0, len(t.targets))
+	for _, tg := range t.targets {
+		list = append(list, tg.snapshot())
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Stop detiene las capturas programadas de todos los objetivos
+func (t *Targets) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tg := range t.targets {
+		if tg.cancel != nil {
+			tg.cancel()
+		}
+	}
+}
+
+// Fetch obtiene un perfil del objetivo y lo archiva con la etiqueta target.
+// seconds solo se usa en el perfil de CPU (0 = la duración del objetivo).
+func (t *Targets) Fetch(ctx context.Context, name, profileType string, seconds int, labels map[string]string) (*ProfileData, error) {
+	tg, err := t.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if !remoteTypes[profileType] {
+		return nil, fmt.Errorf("%w: tipo de perfil remoto desconocido %q", ErrInvalidProfile, profileType)
+	}
+
+	duration := tg.opts.CPUDuration
+	if seconds > 0 {
+		duration = time.Duration(seconds) * time.Second
+	}
+	if duration > MaxCPUProfileDuration {
+		return nil, fmt.Errorf("%w: la duración del perfil de CPU remoto no puede superar %v", ErrInvalidProfile, MaxCPUProfileDuration)
+	}
+	return t.fetchAndStore(ctx, tg, profileType, duration, labels)
+}
+
+// fetchAndStore obtiene un perfil del objetivo, actualiza su estado y lo
+// archiva con la etiqueta target
+func (t *Targets) fetchAndStore(ctx context.Context, tg *target, profileType string, duration time.Duration, labels map[string]string) (*ProfileData, error) {
+	tagged := make(map[string]string, len(labels)+1)
+	for k, v := range labels {
+		tagged[k] = v
+	}
+	tagged[TargetLabel] = tg.opts.Name
+
+	data, err := t.fetch(ctx, tg, profileType, duration)
+	now := time.Now()
+	tg.mu.Lock()
+	tg.info.LastFetch = &now
+	if err != nil {
+		tg.info.LastError = err.Error()
+	} else {
+		tg.info.LastError = ""
+		tg.info.Fetches++
+	}
+	tg.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	profileData := &ProfileData{
+		Name:      profileType,
+		Timestamp: now,
+		Labels:    tagged,
+		Data:      data,
+	}
+	if profileType == "cpu" {
+		profileData.DurationSeconds = duration.Seconds()
+	}
+	t.profiler.store(profileData)
+	return profileData, nil
+}
+
+// fetch descarga un perfil de net/http/pprof y verifica que sea un perfil de pprof
+func (t *Targets) fetch(ctx context.Context, tg *target, profileType string, duration time.Duration) ([]byte, error) {
+	endpoint := tg.base.JoinPath("debug", "pprof", profileType)
+	timeout := 30 * time.Second
+	if profileType == "cpu" {
+		endpoint = tg.base.JoinPath("debug", "pprof", "profile")
+		endpoint.RawQuery = "seconds=" + strconv.Itoa(int(duration.Seconds()))
+		timeout += duration
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w de %s: %v", ErrRemoteFetch, tg.opts.Name, err)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w de %s: %v", ErrRemoteFetch, tg.opts.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxRemoteErrorBytes))
+		log.Printf("Respuesta %s de %s (%s): %s", resp.Status, tg.opts.Name, endpoint.Redacted(), strings.TrimSpace(string(msg)))
+		return nil, fmt.Errorf("%w de %s: respuesta %s", ErrRemoteFetch, tg.opts.Name, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteProfileBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w de %s: %v", ErrRemoteFetch, tg.opts.Name, err)
+	}
+	if len(data) > maxRemoteProfileBytes {
+		return nil, fmt.Errorf("%w de %s: el perfil supera %d bytes", ErrRemoteFetch, tg.opts.Name, maxRemoteProfileBytes)
+	}
+	if _, err := profile.Parse(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%w de %s: la respuesta no es un perfil de pprof: %v", ErrRemoteFetch, tg.opts.Name, err)
+	}
+	return data, nil
+}
+
+// schedule captura los perfiles del objetivo cada intervalo hasta que se cancele ctx
+func (t *Targets) schedule(ctx context.Context, tg *target) {
+	ticker := time.NewTicker(tg.opts.Interval)
+	defer ticker.Stop()
+
+	labels := map[string]string{"source": "scheduled"}
+	for {
+		for _, name := range tg.opts.Types {
+			if _, err := t.fetchAndStore(ctx, tg, name, tg.opts.CPUDuration, labels); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("Error en la captura programada de %s: %v", tg.opts.Name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lookup busca un objetivo por nombre
+func (t *Targets) lookup(name string) (*target, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	tg, ok := t.targets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTargetNotFound, name)
+	}
+	return tg, nil
+}
+
+// snapshot retorna una copia de la información del objetivo
+func (tg *target) snapshot() TargetInfo {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	return tg.info
+}
@@ -0,0 +1,224 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// heapProfileBytes retorna un perfil de heap válido en protobuf
+func heapProfileBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// addTarget registra un objetivo hacia srv sin capturas programadas
+func addTarget(t *testing.T, p *Profiler, srv *httptest.Server) {
+	t.Helper()
+	if _, err := p.Targets().Add(TargetOptions{Name: "svc", URL: srv.URL, Types: []string{"heap"}}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	t.Cleanup(p.Targets().Stop)
+}
+
+func TestTargetsFetch(t *testing.T) {
+	valid := heapProfileBytes(t)
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string // Vacío = la captura debe archivarse
+	}{
+		{"perfil válido", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(valid)
+		}, ""},
+		{"respuesta de error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "secreto interno", http.StatusInternalServerError)
+		}, "500"},
+		{"no es un perfil", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html>hola</html>"))
+		}, "no es un perfil de pprof"},
+		{"perfil demasiado grande", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(make([]byte, maxRemoteProfileBytes+1))
+		}, "supera"},
+		{"redirección", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+		}, "302"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				tt.handler(w, r)
+			}))
+			defer srv.Close()
+
+			p := NewProfiler()
+			addTarget(t, p, srv)
+			data, err := p.Targets().Fetch(context.Background(), "svc", "heap", 0, map[string]string{"env": "test"})
+
+			if len(paths) != 1 || paths[0] != "/debug/pprof/heap" {
+				t.Errorf("solicitudes = %v, se esperaba una a /debug/pprof/heap", paths)
+			}
+			archived := p.Archive().List(ArchiveFilter{Labels: map[string]string{TargetLabel: "svc"}})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Fetch: %v", err)
+				}
+				if data.Labels[TargetLabel] != "svc" || data.Labels["env"] != "test" {
+					t.Errorf("Labels = %v, se esperaban target y env", data.Labels)
+				}
+				if len(archived) != 1 || archived[0].Name != "heap" {
+					t.Errorf("archivados = %v, se esperaba un perfil heap con la etiqueta target", archived)
+				}
+				if info, _ := p.Targets().Get("svc"); info.Fetches != 1 || info.LastError != "" {
+					t.Errorf("Fetches = %d, LastError = %q; se esperaban 1 y vacío", info.Fetches, info.LastError)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrRemoteFetch) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Fetch = %v, se esperaba ErrRemoteFetch con %q", err, tt.wantErr)
+			}
+			if strings.Contains(err.Error(), "secreto") {
+				t.Errorf("el error incluye el cuerpo remoto: %v", err)
+			}
+			if len(archived) != 0 {
+				t.Errorf("se archivaron %d perfiles, se esperaba ninguno", len(archived))
+			}
+			if info, _ := p.Targets().Get("svc"); info.LastError == "" {
+				t.Error("LastError vacío tras una captura fallida")
+			}
+		})
+	}
+}
+
+func TestTargetsCheckHost(t *testing.T) {
+	targets := NewProfiler().Targets()
+	targets.AllowHosts([]string{"metrics.internal", "LOCALHOST:6060"})
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"http://metrics.internal:6060", true},
+		{"https://metrics.internal", true},
+		{"http://localhost:6060", true},
+		{"http://localhost:7070", false},
+		{"http://169.254.169.254", false},
+		{"http://metrics.internal.evil.com", false},
+		{"no es una url", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := targets.CheckHost(tt.url)
+			if tt.allowed && err != nil {
+				t.Errorf("CheckHost(%q) = %v, se esperaba nil", tt.url, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("CheckHost(%q): se esperaba un error", tt.url)
+			}
+		})
+	}
+	if err := targets.CheckHost("http://10.0.0.1"); !errors.Is(err, ErrTargetNotAllowed) {
+		t.Errorf("CheckHost = %v, se esperaba ErrTargetNotAllowed", err)
+	}
+}
+
+func TestTargetsRemoveStopsSchedule(t *testing.T) {
+	valid := heapProfileBytes(t)
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(valid)
+	}))
+	defer srv.Close()
+
+	p := NewProfiler()
+	_, err := p.Targets().Add(TargetOptions{
+		Name: "svc", URL: srv.URL, Types: []string{"heap"},
+		Interval: 1100 * time.Millisecond, CPUDuration: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// La primera captura programada es inmediata
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("tiempo agotado esperando la captura programada")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := p.Targets().Remove("svc"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if got := requests.Load(); got != 1 {
+		t.Errorf("se hicieron %d solicitudes, se esperaba 1 tras Remove", got)
+	}
+	if err := p.Targets().Remove("svc"); !errors.Is(err, ErrTargetNotFound) {
+		t.Errorf("Remove repetido = %v, se esperaba ErrTargetNotFound", err)
+	}
+}
+
+func TestMergeKeepsTargetsApart(t *testing.T) {
+	valid := heapProfileBytes(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(valid)
+	}))
+	defer srv.Close()
+
+	p := NewProfiler()
+	addTarget(t, p, srv)
+	p.store(&ProfileData{Name: "heap", Timestamp: time.Now(), Data: valid})
+	for i := 0; i < 2; i++ {
+		if _, err := p.Targets().Fetch(context.Background(), "svc", "heap", 0, nil); err != nil {
+			t.Fatalf("Fetch: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   int
+	}{
+		{"solo locales por defecto", nil, 1},
+		{"un objetivo", map[string]string{TargetLabel: "svc"}, 2},
+		{"objetivo desconocido", map[string]string{TargetLabel: "otro"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, sources, err := p.Merge(ArchiveFilter{Name: "heap", Labels: tt.labels})
+			if tt.want == 0 {
+				if !errors.Is(err, ErrProfileNotFound) {
+					t.Errorf("Merge = %v, se esperaba ErrProfileNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Merge: %v", err)
+			}
+			if len(sources) != tt.want {
+				t.Fatalf("se combinaron %d perfiles, se esperaban %d", len(sources), tt.want)
+			}
+			for _, info := range sources {
+				if info.Labels[TargetLabel] != tt.labels[TargetLabel] {
+					t.Errorf("se combinó un perfil con target %q", info.Labels[TargetLabel])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package profiler
+
+import "sort"
+
+// TopEntry contiene los valores de una función en un perfil
+type TopEntry struct {
+	Function    string  `json:"function"`
+	File        string  `json:"file,omitempty"`
+	Line        int64   `json:"line,omitempty"`
+	Flat        int64   `json:"flat"`
+	FlatPercent float64 `json:"flat_percent"`
+	SumPercent  float64 `json:"sum_percent"` // Porcentaje flat acumulado hasta esta función
+	Cum         int64   `json:"cum"`
+	CumPercent  float64 `json:"cum_percent"`
+}
+
+// TopReport contiene las funciones con mayor consumo de un perfil
+type TopReport struct {
+	Profile    ProfileInfo `json:"profile"`
+	SampleType string      `json:"sample_type"`
+	Unit       string      `json:"unit"`
+	Total      int64       `json:"total"`
+	Functions  []TopEntry  `json:"functions"`
+}
+
+// TopOptions configura el resumen de funciones
+type TopOptions struct {
+	SampleType string // Tipo de muestra (vacío = el tipo por defecto del perfil)
+	SortBy     string // "flat" (por defecto) o "cum"
+	Limit      int    // Máximo de funciones (0 = todas)
+}
+
+// Top decodifica un perfil archivado (por ID o tipo) y retorna las
+// funciones ordenadas por su valor propio o acumulado, como go tool pprof -top
+func (p *Profiler) Top(ref string, opts TopOptions) (*TopReport, error) {
+	data, err := p.archive.Lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	prof, err := ParseProfile(data)
+	if err != nil {
+		return nil, err
+	}
+	index, err := sampleIndex(prof, opts.SampleType)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, total := aggregateFunctions(prof, index)
+	report := &TopReport{
+		Profile:    data.Info(),
+		SampleType: prof.SampleType[index].Type,
+		Unit:       prof.SampleType[index].Unit,
+		Total:      total,
+		Functions:  make([]TopEntry, 0, len(totals)),
+	}
+
+	for key, t := range totals {
+		report.Functions = append(report.Functions, TopEntry{
+			Function:    key.Name,
+			File:        key.File,
+			Line:        t.Line,
+			Flat:        t.Flat,
+			FlatPercent: percent(t.Flat, total),
+			Cum:         t.Cum,
+			CumPercent:  percent(t.Cum, total),
+		})
+	}
+
+	value := func(e TopEntry) int64 { return e.Flat }
+	if opts.SortBy == "cum" {
+		value = func(e TopEntry) int64 { return e.Cum }
+	}
+	sort.Slice(report.Functions, func(i, j int) bool {
+		vi, vj := value(report.Functions[i]), value(report.Functions[j])
+		if vi != vj {
+			return vi > vj
+		}
+		return report.Functions[i].Function < report.Functions[j].Function
+	})
+
+	if opts.Limit > 0 && len(report.Functions) > opts.Limit {
+		report.Functions = report.Functions[:opts.Limit]
+	}
+
+	var sum int64
+	for i := range report.Functions {
+		sum += report.Functions[i].Flat
+		report.Functions[i].SumPercent = percent(sum, total)
+	}
+	return report, nil
+}
+
+// percent calcula value como porcentaje de total
+func percent(value, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) / float64(total) * 100
+}
@@ -0,0 +1,467 @@
+package profiler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/trace"
+	"sort"
+	"strings"
+	"time"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// TraceName es el tipo con el que se archivan las trazas de ejecución
+const TraceName = "trace"
+
+// ErrTraceInProgress indica que ya hay una traza de ejecución en curso
+var ErrTraceInProgress = errors.New("ya hay una traza de ejecución en curso")
+
+// Parámetros del resumen de trazas
+const (
+	traceTimelinePoints = 100 // Puntos de la serie de goroutines por estado
+	traceTopBlocking    = 20  // Bloqueos más largos reportados
+	traceStackDepth     = 8   // Marcos de las trazas de llamadas reportadas
+)
+
+// GoroutineStates contiene la cantidad de goroutines en cada estado
+type GoroutineStates struct {
+	OffsetSeconds float64 `json:"offset_seconds"`
+	Running       int     `json:"running"`
+	Runnable      int     `json:"runnable"`
+	Waiting       int     `json:"waiting"`
+	Syscall       int     `json:"syscall"`
+}
+
+// TraceGoroutines resume la actividad de las goroutines durante la traza
+type TraceGoroutines struct {
+	Created     int               `json:"created"`
+	Ended       int               `json:"ended"`
+	MaxRunning  int               `json:"max_running"`
+	MaxRunnable int               `json:"max_runnable"`
+	Timeline    []GoroutineStates `json:"timeline"`
+}
+
+// GCCycle es un ciclo del recolector de basura
+type GCCycle struct {
+	Seq             uint64  `json:"seq"`
+	OffsetSeconds   float64 `json:"offset_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// TraceGC resume la actividad del recolector de basura durante la traza
+type TraceGC struct {
+	Cycles          int       `json:"cycles"`
+	TotalSeconds    float64   `json:"total_seconds"`
+	STWPauses       int       `json:"stw_pauses"`
+	STWTotalSeconds float64   `json:"stw_total_seconds"`
+	STWMaxSeconds   float64   `json:"stw_max_seconds"`
+	HeapAllocMax    uint64    `json:"heap_alloc_max_bytes"`
+	HeapGoal        uint64    `json:"heap_goal_bytes"`
+	CycleList       []GCCycle `json:"cycle_list"`
+}
+
+// ProcUtilization es el tiempo que un P ejecutó goroutines
+type ProcUtilization struct {
+	Proc        uint64  `json:"proc"`
+	BusySeconds float64 `json:"busy_seconds"`
+	Utilization float64 `json:"utilization"` // Fracción de la duración de la traza (0 a 1)
+}
+
+// TraceProcs resume el uso de los Ps (procesadores lógicos del planificador)
+type TraceProcs struct {
+	GOMAXPROCS  int               `json:"gomaxprocs"`
+	Utilization float64           `json:"utilization"` // Promedio sobre GOMAXPROCS (0 a 1)
+	PerProc     []ProcUtilization `json:"per_proc"`
+}
+
+// BlockingEvent es un periodo en que una goroutine estuvo bloqueada
+type BlockingEvent struct {
+	Goroutine       uint64   `json:"goroutine"`
+	Reason          string   `json:"reason"`
+	OffsetSeconds   float64  `json:"offset_seconds"`
+	DurationSeconds float64  `json:"duration_seconds"`
+	Stack           []string `json:"stack,omitempty"`
+}
+
+// BlockingTotals acumula los bloqueos de un mismo motivo
+type BlockingTotals struct {
+	Count        int     `json:"count"`
+	TotalSeconds float64 `json:"total_seconds"`
+}
+
+// TraceSummary es el resumen de una traza de ejecución
+type TraceSummary struct {
+	Trace            ProfileInfo               `json:"trace"`
+	Version          string                    `json:"version"`
+	DurationSeconds  float64                   `json:"duration_seconds"`
+	Events           int                       `json:"events"`
+	Goroutines       TraceGoroutines           `json:"goroutines"`
+	GC               TraceGC                   `json:"gc"`
+	Procs            TraceProcs                `json:"procs"`
+	LongestBlocking  []BlockingEvent           `json:"longest_blocking"`
+	BlockingByReason map[string]BlockingTotals `json:"blocking_by_reason"`
+}
+
+// GetTrace captura una traza de ejecución durante los segundos indicados y
+// la archiva. Se cancela sin archivar si ctx termina antes.
+func (p *Profiler) GetTrace(ctx context.Context, seconds int, labels map[string]string) (*ProfileData, error) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTraceInProgress, err)
+	}
+
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		trace.Stop()
+		return nil, fmt.Errorf("traza de ejecución cancelada: %w", ctx.Err())
+	case <-timer.C:
+	}
+	trace.Stop()
+
+	traceData := &ProfileData{
+		Name:            TraceName,
+		Timestamp:       time.Now(),
+		DurationSeconds: float64(seconds),
+		Labels:          labels,
+		Data:            buf.Bytes(),
+	}
+
+	p.store(traceData)
+
+	return traceData, nil
+}
+
+// TraceSummary resume una traza archivada (por ID, o "trace" para la más reciente)
+func (p *Profiler) TraceSummary(ref string) (*TraceSummary, error) {
+	data, err := p.archive.Lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeTrace(data)
+}
+
+// SummarizeTrace decodifica una traza de ejecución con el lector de
+// golang.org/x/exp/trace y calcula su resumen
+func SummarizeTrace(data *ProfileData) (*TraceSummary, error) {
+	if data.Name != TraceName {
+		return nil, fmt.Errorf("%w: %s no es una traza de ejecución", ErrInvalidProfile, data.Name)
+	}
+	reader, err := exptrace.NewReader(bytes.NewReader(data.Data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+
+	a := newTraceAnalyzer()
+	a.s.Version = traceVersion(data.Data)
+	for {
+		e, err := reader.ReadEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+		}
+		a.apply(e)
+	}
+	a.finish()
+
+	a.s.Trace = data.Info()
+	return a.s, nil
+}
+
+// traceVersion retorna la versión de Go indicada en la cabecera de la traza
+func traceVersion(data []byte) string {
+	var minor int
+	if _, err := fmt.Sscanf(string(data[:min(len(data), 16)]), "go 1.%d trace", &minor); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("go1.%d", minor)
+}
+
+// traceRun es la ejecución en curso de una goroutine en un P
+type traceRun struct {
+	proc  exptrace.ProcID
+	start exptrace.Time
+}
+
+// traceBlock es un bloqueo en curso de una goroutine
+type traceBlock struct {
+	start  exptrace.Time
+	reason string
+	stack  exptrace.Stack
+}
+
+// traceAnalyzer recorre los eventos de la traza en orden temporal y
+// acumula el resumen. Los eventos que no usa se ignoran.
+type traceAnalyzer struct {
+	s        *TraceSummary
+	started  bool
+	start    exptrace.Time
+	last     exptrace.Time
+	counts   map[exptrace.GoState]int
+	runs     map[exptrace.GoID]traceRun
+	busy     map[exptrace.ProcID]time.Duration
+	blocks   map[exptrace.GoID]traceBlock
+	samples  []GoroutineStates // Estado tras cada evento, reducido al final a la serie
+	gc       *GCCycle          // Ciclo del GC en curso
+	gcStart  exptrace.Time
+	gcSeq    uint64
+	stwStart exptrace.Time
+	inSTW    bool
+}
+
+func newTraceAnalyzer() *traceAnalyzer {
+	s := &TraceSummary{BlockingByReason: make(map[string]BlockingTotals)}
+	s.Goroutines.Timeline = make([]GoroutineStates, 0)
+	s.GC.CycleList = make([]GCCycle, 0)
+	s.Procs.PerProc = make([]ProcUtilization, 0)
+	s.LongestBlocking = make([]BlockingEvent, 0)
+	return &traceAnalyzer{
+		s:      s,
+		counts: make(map[exptrace.GoState]int),
+		runs:   make(map[exptrace.GoID]traceRun),
+		busy:   make(map[exptrace.ProcID]time.Duration),
+		blocks: make(map[exptrace.GoID]traceBlock),
+	}
+}
+
+// apply actualiza el estado con un evento
+func (a *traceAnalyzer) apply(e exptrace.Event) {
+	now := e.Time()
+	if !a.started {
+		a.started, a.start = true, now
+	}
+	a.last = now
+	a.s.Events++
+
+	switch e.Kind() {
+	case exptrace.EventMetric:
+		m := e.Metric()
+		switch m.Name {
+		case "/sched/gomaxprocs:threads":
+			a.s.Procs.GOMAXPROCS = int(m.Value.Uint64())
+		case "/memory/classes/heap/objects:bytes":
+			a.s.GC.HeapAllocMax = max(a.s.GC.HeapAllocMax, m.Value.Uint64())
+		case "/gc/heap/goal:bytes":
+			a.s.GC.HeapGoal = m.Value.Uint64()
+		}
+
+	case exptrace.EventRangeBegin, exptrace.EventRangeActive:
+		name := e.Range().Name
+		switch {
+		case name == "GC concurrent mark phase":
+			// Un rango activo al iniciar la traza comenzó antes de ella
+			a.beginGC(now)
+		case strings.HasPrefix(name, "stop-the-world"):
+			a.stwStart, a.inSTW = now, true
+		}
+	case exptrace.EventRangeEnd:
+		name := e.Range().Name
+		switch {
+		case name == "GC concurrent mark phase":
+			a.endGC(now)
+		case strings.HasPrefix(name, "stop-the-world") && a.inSTW:
+			pause := now.Sub(a.stwStart).Seconds()
+			a.s.GC.STWPauses++
+			a.s.GC.STWTotalSeconds += pause
+			a.s.GC.STWMaxSeconds = max(a.s.GC.STWMaxSeconds, pause)
+			a.inSTW = false
+		}
+
+	case exptrace.EventStateTransition:
+		st := e.StateTransition()
+		switch st.Resource.Kind {
+		case exptrace.ResourceProc:
+			if _, to := st.Proc(); to.Executing() {
+				a.touchProc(st.Resource.Proc())
+			}
+		case exptrace.ResourceGoroutine:
+			a.transition(e, st)
+		}
+	}
+}
+
+// transition aplica el cambio de estado de una goroutine
+func (a *traceAnalyzer) transition(e exptrace.Event, st exptrace.StateTransition) {
+	g := st.Resource.Goroutine()
+	from, to := st.Goroutine()
+	now := e.Time()
+
+	if from == exptrace.GoNotExist && to != exptrace.GoNotExist {
+		a.s.Goroutines.Created++
+	}
+	if to == exptrace.GoNotExist && from != exptrace.GoUndetermined {
+		a.s.Goroutines.Ended++
+	}
+	if from != to {
+		if from != exptrace.GoUndetermined && from != exptrace.GoNotExist && a.counts[from] > 0 {
+			a.counts[from]--
+		}
+		if to != exptrace.GoNotExist {
+			a.counts[to]++
+		}
+		gs := &a.s.Goroutines
+		gs.MaxRunning = max(gs.MaxRunning, a.counts[exptrace.GoRunning])
+		gs.MaxRunnable = max(gs.MaxRunnable, a.counts[exptrace.GoRunnable])
+	}
+
+	// Tiempo de ejecución por P
+	if run, ok := a.runs[g]; ok && to != exptrace.GoRunning {
+		a.busy[run.proc] += now.Sub(run.start)
+		delete(a.runs, g)
+	}
+	if to == exptrace.GoRunning && e.Proc() != exptrace.NoProc {
+		a.touchProc(e.Proc())
+		a.runs[g] = traceRun{proc: e.Proc(), start: now}
+	}
+
+	// Bloqueos
+	if from == exptrace.GoWaiting && to != exptrace.GoWaiting {
+		a.unblock(g, now)
+	}
+	if to == exptrace.GoWaiting && from != exptrace.GoWaiting {
+		a.blocks[g] = traceBlock{start: now, reason: st.Reason, stack: st.Stack}
+	}
+
+	a.samples = append(a.samples, GoroutineStates{
+		OffsetSeconds: now.Sub(a.start).Seconds(),
+		Running:       a.counts[exptrace.GoRunning],
+		Runnable:      a.counts[exptrace.GoRunnable],
+		Waiting:       a.counts[exptrace.GoWaiting],
+		Syscall:       a.counts[exptrace.GoSyscall],
+	})
+}
+
+// finish cierra las ejecuciones y el ciclo del GC abiertos y calcula los
+// totales y la serie de goroutines
+func (a *traceAnalyzer) finish() {
+	s := a.s
+	duration := a.last.Sub(a.start)
+	s.DurationSeconds = duration.Seconds()
+
+	for _, run := range a.runs {
+		a.busy[run.proc] += a.last.Sub(run.start)
+	}
+	a.endGC(a.last)
+
+	var totalBusy time.Duration
+	for proc, busy := range a.busy {
+		totalBusy += busy
+		util := 0.0
+		if duration > 0 {
+			util = float64(busy) / float64(duration)
+		}
+		s.Procs.PerProc = append(s.Procs.PerProc, ProcUtilization{Proc: uint64(proc), BusySeconds: busy.Seconds(), Utilization: util})
+	}
+	sort.Slice(s.Procs.PerProc, func(i, j int) bool { return s.Procs.PerProc[i].Proc < s.Procs.PerProc[j].Proc })
+	if s.Procs.GOMAXPROCS == 0 {
+		s.Procs.GOMAXPROCS = len(s.Procs.PerProc)
+	}
+	if duration > 0 && s.Procs.GOMAXPROCS > 0 {
+		s.Procs.Utilization = float64(totalBusy) / float64(duration) / float64(s.Procs.GOMAXPROCS)
+	}
+
+	// Serie de goroutines por estado en traceTimelinePoints puntos equidistantes
+	if len(a.samples) == 0 {
+		return
+	}
+	step := s.DurationSeconds / traceTimelinePoints
+	if step == 0 {
+		s.Goroutines.Timeline = append(s.Goroutines.Timeline, a.samples[len(a.samples)-1])
+		return
+	}
+	var current GoroutineStates
+	next := 0
+	for k := 1; k <= traceTimelinePoints; k++ {
+		at := float64(k) * step
+		for next < len(a.samples) && a.samples[next].OffsetSeconds <= at {
+			current = a.samples[next]
+			next++
+		}
+		point := current
+		point.OffsetSeconds = at
+		s.Goroutines.Timeline = append(s.Goroutines.Timeline, point)
+	}
+}
+
+// touchProc registra un P aunque no llegue a ejecutar goroutines
+func (a *traceAnalyzer) touchProc(proc exptrace.ProcID) {
+	if _, ok := a.busy[proc]; !ok {
+		a.busy[proc] = 0
+	}
+}
+
+// unblock cierra el bloqueo en curso de una goroutine
+func (a *traceAnalyzer) unblock(g exptrace.GoID, now exptrace.Time) {
+	b, ok := a.blocks[g]
+	if !ok {
+		return
+	}
+	delete(a.blocks, g)
+
+	duration := now.Sub(b.start).Seconds()
+	totals := a.s.BlockingByReason[b.reason]
+	totals.Count++
+	totals.TotalSeconds += duration
+	a.s.BlockingByReason[b.reason] = totals
+
+	// Conservar solo los bloqueos más largos, ordenados de mayor a menor
+	list := a.s.LongestBlocking
+	if len(list) == traceTopBlocking && duration <= list[len(list)-1].DurationSeconds {
+		return
+	}
+	event := BlockingEvent{
+		Goroutine:       uint64(g),
+		Reason:          b.reason,
+		OffsetSeconds:   b.start.Sub(a.start).Seconds(),
+		DurationSeconds: duration,
+		Stack:           traceStack(b.stack, traceStackDepth),
+	}
+	i := sort.Search(len(list), func(i int) bool { return list[i].DurationSeconds < duration })
+	list = append(list, BlockingEvent{})
+	copy(list[i+1:], list[i:])
+	list[i] = event
+	if len(list) > traceTopBlocking {
+		list = list[:traceTopBlocking]
+	}
+	a.s.LongestBlocking = list
+}
+
+// traceStack retorna una traza de llamadas como texto "función (archivo:línea)",
+// hasta max marcos
+func traceStack(stack exptrace.Stack, max int) []string {
+	var frames []string
+	for f := range stack.Frames() {
+		if len(frames) == max {
+			break
+		}
+		frames = append(frames, fmt.Sprintf("%s (%s:%d)", f.Func, f.File, f.Line))
+	}
+	return frames
+}
+
+// beginGC abre un ciclo del recolector de basura
+func (a *traceAnalyzer) beginGC(start exptrace.Time) {
+	a.endGC(start)
+	a.gcSeq++
+	a.gc = &GCCycle{Seq: a.gcSeq, OffsetSeconds: start.Sub(a.start).Seconds()}
+	a.gcStart = start
+}
+
+// endGC cierra el ciclo del recolector de basura en curso
+func (a *traceAnalyzer) endGC(end exptrace.Time) {
+	if a.gc == nil {
+		return
+	}
+	a.gc.DurationSeconds = end.Sub(a.gcStart).Seconds()
+	a.s.GC.Cycles++
+	a.s.GC.TotalSeconds += a.gc.DurationSeconds
+	a.s.GC.CycleList = append(a.s.GC.CycleList, *a.gc)
+	a.gc = nil
+}
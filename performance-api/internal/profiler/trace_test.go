@@ -0,0 +1,104 @@
+package profiler
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordTrace captura una traza en el proceso con goroutines que se
+// bloquean en un canal y un ciclo forzado del GC
+func recordTrace(t *testing.T, goroutines int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatalf("trace.Start: %v", err)
+	}
+
+	ch := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-ch
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	runtime.GC()
+	close(ch)
+	wg.Wait()
+
+	trace.Stop()
+	return buf.Bytes()
+}
+
+func TestSummarizeTrace(t *testing.T) {
+	const goroutines = 8
+	data := recordTrace(t, goroutines)
+
+	summary, err := SummarizeTrace(&ProfileData{Name: TraceName, Timestamp: time.Now(), Data: data})
+	if err != nil {
+		t.Fatalf("SummarizeTrace: %v", err)
+	}
+
+	if want := "go1."; !strings.HasPrefix(summary.Version, want) {
+		t.Errorf("Version = %q, se esperaba el prefijo %q", summary.Version, want)
+	}
+	if summary.Events == 0 || summary.DurationSeconds <= 0 {
+		t.Errorf("Events = %d, DurationSeconds = %g; se esperaban valores positivos", summary.Events, summary.DurationSeconds)
+	}
+	if summary.Goroutines.Created < goroutines {
+		t.Errorf("Goroutines.Created = %d, se esperaban al menos %d", summary.Goroutines.Created, goroutines)
+	}
+	if summary.Goroutines.Ended < goroutines {
+		t.Errorf("Goroutines.Ended = %d, se esperaban al menos %d", summary.Goroutines.Ended, goroutines)
+	}
+	if got := len(summary.Goroutines.Timeline); got != traceTimelinePoints {
+		t.Errorf("len(Timeline) = %d, se esperaban %d", got, traceTimelinePoints)
+	}
+	if summary.GC.Cycles < 1 || summary.GC.STWPauses < 1 {
+		t.Errorf("GC.Cycles = %d, GC.STWPauses = %d; se esperaba al menos un ciclo con pausas", summary.GC.Cycles, summary.GC.STWPauses)
+	}
+	if summary.Procs.GOMAXPROCS != runtime.GOMAXPROCS(0) {
+		t.Errorf("Procs.GOMAXPROCS = %d, se esperaba %d", summary.Procs.GOMAXPROCS, runtime.GOMAXPROCS(0))
+	}
+	if len(summary.Procs.PerProc) == 0 {
+		t.Error("Procs.PerProc vacío")
+	}
+
+	var blocked *BlockingTotals
+	for reason, totals := range summary.BlockingByReason {
+		if strings.Contains(reason, "chan receive") {
+			blocked = &totals
+		}
+	}
+	if blocked == nil || blocked.Count < goroutines {
+		t.Errorf("BlockingByReason = %v, se esperaban al menos %d bloqueos en chan receive", summary.BlockingByReason, goroutines)
+	}
+	if len(summary.LongestBlocking) == 0 || len(summary.LongestBlocking[0].Stack) == 0 {
+		t.Errorf("LongestBlocking = %v, se esperaba al menos un bloqueo con traza de llamadas", summary.LongestBlocking)
+	}
+}
+
+func TestSummarizeTraceRejectsInvalidData(t *testing.T) {
+	tests := []struct {
+		name string
+		data *ProfileData
+	}{
+		{"otro tipo", &ProfileData{Name: "heap", Data: []byte("go 1.22 trace\x00\x00\x00")}},
+		{"cabecera inválida", &ProfileData{Name: TraceName, Data: []byte("no es una traza")}},
+		{"vacía", &ProfileData{Name: TraceName}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SummarizeTrace(tt.data); err == nil {
+				t.Error("se esperaba un error")
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+// killProcessGroup no está disponible fuera de sistemas Unix: la
+// cancelación termina solo el proceso lanzado
+func killProcessGroup(cmd *exec.Cmd) {}
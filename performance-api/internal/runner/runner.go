@@ -0,0 +1,448 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Estados de una ejecución
+const (
+	RunRunning   = "running"
+	RunCompleted = "completed" // El comando terminó (con cualquier código de salida)
+	RunFailed    = "failed"    // El comando no pudo iniciarse o superó la duración máxima
+)
+
+// maxOutputBytes limita la salida capturada de cada comando
+const maxOutputBytes = 64 << 10
+
+// waitDelay es el tiempo que se espera a que los descendientes cierren la
+// salida tras terminar el comando o vencer la duración máxima
+const waitDelay = 5 * time.Second
+
+// Errores del ejecutor
+var (
+	ErrRunNotFound       = errors.New("ejecución no encontrada")
+	ErrCommandNotAllowed = errors.New("comando no permitido")
+	ErrArgNotAllowed     = errors.New("argumento no permitido")
+	ErrTooManyRuns       = errors.New("demasiadas ejecuciones en curso")
+)
+
+// Command describe un comando permitido
+type Command struct {
+	Path string   `json:"path"`           // Ejecutable a lanzar
+	Args []string `json:"args,omitempty"` // Argumentos fijos antepuestos a los de la solicitud
+	Dir  string   `json:"dir,omitempty"`  // Directorio de trabajo
+
+	// ArgPatterns son expresiones regulares: cada argumento de la solicitud
+	// debe coincidir completo con alguna. Sin patrones no se aceptan argumentos.
+	ArgPatterns []string `json:"arg_patterns,omitempty"`
+	MaxArgs     int      `json:"max_args,omitempty"` // Máximo de argumentos de la solicitud (0 = sin límite)
+}
+
+// Options configura el ejecutor
+type Options struct {
+	Commands       map[string]Command // Lista de comandos permitidos por nombre
+	SampleInterval time.Duration      // Frecuencia de muestreo de CPU y memoria
+	MaxDuration    time.Duration      // Tiempo máximo de una ejecución
+	MaxRuns        int                // Ejecuciones conservadas en memoria
+	MaxConcurrent  int                // Ejecuciones en curso a la vez (como máximo MaxRuns)
+}
+
+// Request es la solicitud de una ejecución
+type Request struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// Run es el registro de una ejecución medida
+type Run struct {
+	ID              string     `json:"id"`
+	Command         string     `json:"command"`
+	Args            []string   `json:"args"`
+	Status          string     `json:"status"`
+	PID             int        `json:"pid,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	WallTimeSeconds float64    `json:"wall_time_seconds"`
+	UserTimeSeconds float64    `json:"user_time_seconds"`
+	SysTimeSeconds  float64    `json:"sys_time_seconds"`
+	PeakRSS         uint64     `json:"peak_rss"`
+	ExitCode        *int       `json:"exit_code,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	Stdout          string     `json:"stdout,omitempty"`
+	Stderr          string     `json:"stderr,omitempty"`
+	Samples         []Sample   `json:"samples,omitempty"`
+}
+
+// Sample es una medición del proceso (y sus descendientes) durante la ejecución
+type Sample struct {
+	Offset     float64 `json:"offset_seconds"` // Segundos desde el inicio
+	CPUPercent float64 `json:"cpu_percent"`    // 100 equivale a un núcleo completo
+	RSS        uint64  `json:"rss"`
+	Threads    int32   `json:"threads"`
+	Processes  int     `json:"processes"`
+}
+
+// Runner lanza comandos permitidos y mide su consumo hasta que terminan
+type Runner struct {
+	mu       sync.RWMutex
+	opts     Options
+	patterns map[string][]*regexp.Regexp // Patrones de argumentos por comando
+	runs     map[string]*Run
+	order    []string
+	nextID   int
+	running  int // Ejecuciones sin terminar
+}
+
+// NewRunner crea un ejecutor con la lista de comandos permitidos
+func NewRunner(opts Options) (*Runner, error) {
+	if opts.SampleInterval <= 0 {
+		opts.SampleInterval = 100 * time.Millisecond
+	}
+	if opts.MaxDuration <= 0 {
+		opts.MaxDuration = 10 * time.Minute
+	}
+	if opts.MaxRuns <= 0 {
+		opts.MaxRuns = 50
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = 4
+	}
+	// Las ejecuciones en curso no se descartan, así que no pueden superar MaxRuns
+	opts.MaxConcurrent = min(opts.MaxConcurrent, opts.MaxRuns)
+	patterns := make(map[string][]*regexp.Regexp, len(opts.Commands))
+	for name, cmd := range opts.Commands {
+		for _, pattern := range cmd.ArgPatterns {
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("comando %s: patrón de argumentos inválido %q: %w", name, pattern, err)
+			}
+			patterns[name] = append(patterns[name], re)
+		}
+	}
+	return &Runner{
+		opts:     opts,
+		patterns: patterns,
+		runs:     make(map[string]*Run),
+	}, nil
+}
+
+// checkArgs valida los argumentos de la solicitud contra los patrones del comando
+func (r *Runner) checkArgs(name string, cmd Command, args []string) error {
+	if cmd.MaxArgs > 0 && len(args) > cmd.MaxArgs {
+		return fmt.Errorf("%w: %s acepta como máximo %d argumentos", ErrArgNotAllowed, name, cmd.MaxArgs)
+	}
+	for _, arg := range args {
+		allowed := false
+		for _, re := range r.patterns[name] {
+			if re.MatchString(arg) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %q para %s", ErrArgNotAllowed, arg, name)
+		}
+	}
+	return nil
+}
+
+// Start lanza el comando solicitado y retorna su registro. La medición
+// continúa en segundo plano; done se cierra cuando la ejecución termina.
+// Retorna ErrTooManyRuns si ya hay MaxConcurrent ejecuciones en curso.
+func (r *Runner) Start(req Request) (*Run, <-chan struct{}, error) {
+	cmdConfig, ok := r.opts.Commands[req.Command]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q", ErrCommandNotAllowed, req.Command)
+	}
+	if err := r.checkArgs(req.Command, cmdConfig, req.Args); err != nil {
+		return nil, nil, err
+	}
+
+	args := append(append([]string{}, cmdConfig.Args...), req.Args...)
+	run := &Run{
+		Command:   req.Command,
+		Args:      args,
+		Status:    RunRunning,
+		StartedAt: time.Now(),
+	}
+
+	r.mu.Lock()
+	if r.running >= r.opts.MaxConcurrent {
+		r.mu.Unlock()
+		return nil, nil, fmt.Errorf("%w: el máximo es %d", ErrTooManyRuns, r.opts.MaxConcurrent)
+	}
+	r.running++
+	r.nextID++
+	run.ID = strconv.Itoa(r.nextID)
+	r.runs[run.ID] = run
+	r.order = append(r.order, run.ID)
+	r.evict()
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.opts.MaxDuration)
+	cmd := exec.CommandContext(ctx, cmdConfig.Path, args...)
+	cmd.Dir = cmdConfig.Dir
+	// Al vencer la duración máxima se termina todo el grupo de procesos (por
+	// ejemplo el binario que compila "go run"), y Wait no espera más de
+	// waitDelay a que los descendientes cierren la salida
+	killProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	stdout := &limitedBuffer{limit: maxOutputBytes}
+	stderr := &limitedBuffer{limit: maxOutputBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	done := make(chan struct{})
+	if err := cmd.Start(); err != nil {
+		cancel()
+		r.finish(run, func() {
+			run.Status = RunFailed
+			run.Error = fmt.Sprintf("error al iniciar el comando: %v", err)
+		})
+		close(done)
+		return r.snapshot(run), done, nil
+	}
+
+	r.mu.Lock()
+	run.PID = cmd.Process.Pid
+	r.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		r.measure(run, cmd, ctx)
+	}()
+
+	return r.snapshot(run), done, nil
+}
+
+// Get retorna una copia del registro de una ejecución
+func (r *Runner) Get(id string) (*Run, error) {
+	r.mu.RLock()
+	run, ok := r.runs[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrRunNotFound
+	}
+	return r.snapshot(run), nil
+}
+
+// List retorna las ejecuciones guardadas, sin sus series de tiempo
+func (r *Runner) List() []Run {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Run, 0, len(r.order))
+	for _, id := range r.order {
+		run := *r.runs[id]
+		run.Samples = nil
+		run.Stdout = ""
+		run.Stderr = ""
+		list = append(list, run)
+	}
+	return list
+}
+
+// Commands retorna los nombres de los comandos permitidos
+func (r *Runner) Commands() []string {
+	names := make([]string, 0, len(r.opts.Commands))
+	for name := range r.opts.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// measure muestrea el proceso hasta que termina y completa el registro
+func (r *Runner) measure(run *Run, cmd *exec.Cmd, ctx context.Context) {
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- cmd.Wait() }()
+
+	sampler := newTreeSampler(int32(cmd.Process.Pid))
+	ticker := time.NewTicker(r.opts.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-waitErr:
+			r.finish(run, func() {
+				state := cmd.ProcessState
+				run.Status = RunCompleted
+				run.UserTimeSeconds = state.UserTime().Seconds()
+				run.SysTimeSeconds = state.SystemTime().Seconds()
+				if peak := maxRSS(state); peak > run.PeakRSS {
+					run.PeakRSS = peak
+				}
+				code := state.ExitCode()
+				run.ExitCode = &code
+				if ctx.Err() == context.DeadlineExceeded {
+					run.Status = RunFailed
+					run.Error = fmt.Sprintf("el comando superó la duración máxima de %s", r.opts.MaxDuration)
+				} else if err != nil && code < 0 {
+					run.Error = err.Error()
+				}
+				run.Stdout = cmd.Stdout.(*limitedBuffer).String()
+				run.Stderr = cmd.Stderr.(*limitedBuffer).String()
+			})
+			return
+		case now := <-ticker.C:
+			sample, ok := sampler.sample(now)
+			if !ok {
+				continue
+			}
+			r.mu.Lock()
+			sample.Offset = now.Sub(run.StartedAt).Seconds()
+			run.Samples = append(run.Samples, sample)
+			if sample.RSS > run.PeakRSS {
+				run.PeakRSS = sample.RSS
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// finish aplica los cambios finales a una ejecución bajo el candado
+func (r *Runner) finish(run *Run, update func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running--
+	now := time.Now()
+	run.FinishedAt = &now
+	run.WallTimeSeconds = now.Sub(run.StartedAt).Seconds()
+	update()
+}
+
+// snapshot retorna una copia del registro protegida por el candado
+func (r *Runner) snapshot(run *Run) *Run {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	copied := *run
+	copied.Samples = append([]Sample(nil), run.Samples...)
+	return &copied
+}
+
+// evict descarta las ejecuciones terminadas más antiguas si se supera el máximo
+func (r *Runner) evict() {
+	for i := 0; len(r.order) > r.opts.MaxRuns && i < len(r.order); {
+		id := r.order[i]
+		if r.runs[id].Status == RunRunning {
+			i++
+			continue
+		}
+		delete(r.runs, id)
+		r.order = append(r.order[:i], r.order[i+1:]...)
+	}
+}
+
+// treeSampler mide un proceso junto con todos sus descendientes (por
+// ejemplo, el binario que lanza "go run")
+type treeSampler struct {
+	root     int32
+	prevCPU  map[int32]float64
+	prevTime time.Time
+}
+
+// newTreeSampler crea un muestreador para el árbol de procesos de root
+func newTreeSampler(root int32) *treeSampler {
+	return &treeSampler{root: root, prevCPU: make(map[int32]float64)}
+}
+
+// sample suma CPU, RSS e hilos del proceso raíz y sus descendientes
+func (s *treeSampler) sample(now time.Time) (Sample, bool) {
+	root, err := process.NewProcess(s.root)
+	if err != nil {
+		return Sample{}, false
+	}
+
+	var sample Sample
+	cpu := make(map[int32]float64)
+	queue := []*process.Process{root}
+	for len(queue) > 0 {
+		proc := queue[0]
+		queue = queue[1:]
+
+		times, err := proc.Times()
+		if err != nil {
+			continue
+		}
+		sample.Processes++
+		cpu[proc.Pid] = times.User + times.System
+		if mem, err := proc.MemoryInfo(); err == nil {
+			sample.RSS += mem.RSS
+		}
+		if threads, err := proc.NumThreads(); err == nil {
+			sample.Threads += threads
+		}
+		if children, err := proc.Children(); err == nil {
+			queue = append(queue, children...)
+		}
+	}
+	if sample.Processes == 0 {
+		return Sample{}, false
+	}
+
+	if !s.prevTime.IsZero() {
+		if seconds := now.Sub(s.prevTime).Seconds(); seconds > 0 {
+			var delta float64
+			for pid, total := range cpu {
+				if prev, ok := s.prevCPU[pid]; ok && total >= prev {
+					delta += total - prev
+				} else if !ok {
+					// Proceso nuevo: todo su tiempo ocurrió desde la muestra anterior
+					delta += total
+				}
+			}
+			sample.CPUPercent = delta / seconds * 100
+		}
+	}
+	s.prevCPU = cpu
+	s.prevTime = now
+
+	return sample, true
+}
+
+// limitedBuffer guarda la salida de un comando hasta un límite de bytes
+type limitedBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write descarta los bytes que superan el límite sin reportar error
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+	} else {
+		b.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// String retorna la salida capturada
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.truncated {
+		return b.buf.String() + "\n[salida truncada]"
+	}
+	return b.buf.String()
+}
@@ -0,0 +1,72 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckArgs(t *testing.T) {
+	r, err := NewRunner(Options{Commands: map[string]Command{
+		"matrix":  {Path: "./matrix_mul", ArgPatterns: []string{"[0-9]{1,5}", "[A-Za-z0-9_]+\\.txt"}, MaxArgs: 3},
+		"sin_arg": {Path: "./matrix_mul"},
+		"alterna": {Path: "./matrix_mul", ArgPatterns: []string{"a|b"}},
+	}})
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		allowed bool
+	}{
+		{"argumentos válidos", "matrix", []string{"500", "A.txt", "B.txt"}, true},
+		{"sin argumentos", "matrix", nil, true},
+		{"número demasiado largo", "matrix", []string{"123456"}, false},
+		{"patrón anclado al inicio", "matrix", []string{"x500"}, false},
+		{"patrón anclado al final", "matrix", []string{"A.txt.sh"}, false},
+		{"separador de comandos", "matrix", []string{"foo;rm"}, false},
+		{"ruta", "matrix", []string{"../secreto.txt"}, false},
+		{"argumento vacío", "matrix", []string{""}, false},
+		{"demasiados argumentos", "matrix", []string{"1", "2", "3", "4"}, false},
+		{"sin patrones no hay argumentos", "sin_arg", []string{"1"}, false},
+		{"sin patrones y sin argumentos", "sin_arg", nil, true},
+		{"alternativa anclada completa", "alterna", []string{"b"}, true},
+		{"alternativa con prefijo", "alterna", []string{"ab"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.checkArgs(tt.command, r.opts.Commands[tt.command], tt.args)
+			if tt.allowed && err != nil {
+				t.Errorf("checkArgs(%q) = %v, se esperaba nil", tt.args, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrArgNotAllowed) {
+				t.Errorf("checkArgs(%q) = %v, se esperaba ErrArgNotAllowed", tt.args, err)
+			}
+		})
+	}
+}
+
+func TestStartRejectsUnknownCommand(t *testing.T) {
+	r, err := NewRunner(Options{Commands: map[string]Command{"eco": {Path: "echo"}}})
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	if _, _, err := r.Start(Request{Command: "rm", Args: []string{"-rf", "/"}}); !errors.Is(err, ErrCommandNotAllowed) {
+		t.Errorf("Start = %v, se esperaba ErrCommandNotAllowed", err)
+	}
+	if _, _, err := r.Start(Request{Command: "eco", Args: []string{"hola"}}); !errors.Is(err, ErrArgNotAllowed) {
+		t.Errorf("Start = %v, se esperaba ErrArgNotAllowed", err)
+	}
+	if got := len(r.List()); got != 0 {
+		t.Errorf("se registraron %d ejecuciones rechazadas", got)
+	}
+}
+
+func TestNewRunnerRejectsInvalidPattern(t *testing.T) {
+	_, err := NewRunner(Options{Commands: map[string]Command{"x": {Path: "x", ArgPatterns: []string{"("}}}})
+	if err == nil {
+		t.Error("NewRunner con un patrón inválido: se esperaba un error")
+	}
+}
@@ -0,0 +1,160 @@
+//go:build unix
+
+package runner
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// shellRunner crea un ejecutor con un comando "sh" que corre script
+func shellRunner(t *testing.T, script string, opts Options) *Runner {
+	t.Helper()
+	opts.Commands = map[string]Command{"sh": {Path: "/bin/sh", Args: []string{"-c", script}}}
+	r, err := NewRunner(opts)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	return r
+}
+
+// runToEnd lanza el comando sh y espera a que termine
+func runToEnd(t *testing.T, r *Runner) *Run {
+	t.Helper()
+	run, done, err := r.Start(Request{Command: "sh"})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(20 * time.Second):
+		t.Fatal("tiempo agotado esperando la ejecución")
+	}
+	run, err = r.Get(run.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	return run
+}
+
+func TestRunRecordsResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		script    string
+		exitCode  int
+		stdoutLen int
+		truncated bool
+	}{
+		{"código de salida", "echo hola; exit 3", 3, len("hola\n"), false},
+		{"salida truncada", "head -c 100000 /dev/zero", 0, maxOutputBytes, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := runToEnd(t, shellRunner(t, tt.script, Options{}))
+			if run.Status != RunCompleted || run.ExitCode == nil || *run.ExitCode != tt.exitCode {
+				t.Fatalf("Status = %s, ExitCode = %v; se esperaba completed con %d", run.Status, run.ExitCode, tt.exitCode)
+			}
+			stdout := strings.TrimSuffix(run.Stdout, "\n[salida truncada]")
+			if len(stdout) != tt.stdoutLen || (stdout != run.Stdout) != tt.truncated {
+				t.Errorf("stdout de %d bytes (truncada: %v), se esperaban %d (truncada: %v)",
+					len(stdout), stdout != run.Stdout, tt.stdoutLen, tt.truncated)
+			}
+			if run.PeakRSS == 0 || run.FinishedAt == nil || run.WallTimeSeconds <= 0 {
+				t.Errorf("PeakRSS = %d, FinishedAt = %v, WallTimeSeconds = %g; se esperaban valores registrados",
+					run.PeakRSS, run.FinishedAt, run.WallTimeSeconds)
+			}
+		})
+	}
+}
+
+func TestRunMaxDurationKillsProcessGroup(t *testing.T) {
+	// El nieto hereda la salida: sin terminar el grupo, Wait esperaría
+	// waitDelay y el nieto seguiría vivo
+	r := shellRunner(t, "sleep 30 & echo $!; wait", Options{MaxDuration: 300 * time.Millisecond})
+	start := time.Now()
+	run := runToEnd(t, r)
+
+	if run.Status != RunFailed || !strings.Contains(run.Error, "duración máxima") {
+		t.Errorf("Status = %s, Error = %q; se esperaba failed por la duración máxima", run.Status, run.Error)
+	}
+	if elapsed := time.Since(start); elapsed >= waitDelay {
+		t.Errorf("la ejecución tardó %v en terminar, se esperaba menos que waitDelay", elapsed)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(run.Stdout))
+	if err != nil {
+		t.Fatalf("stdout = %q, se esperaba el PID del nieto", run.Stdout)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		proc, err := process.NewProcess(int32(pid))
+		if err != nil {
+			break // Ya no existe
+		}
+		if status, err := proc.Status(); err == nil && len(status) > 0 && status[0] == process.Zombie {
+			break // Terminado, a la espera de que lo recoja init
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("el nieto %d sigue en ejecución", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestStartLimitsConcurrentRuns(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		limit int
+	}{
+		{"max_concurrent", Options{MaxConcurrent: 2}, 2},
+		{"acotado por max_runs", Options{MaxConcurrent: 4, MaxRuns: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.MaxDuration = 10 * time.Second
+			r := shellRunner(t, "exec sleep 10", tt.opts)
+			var stops []func()
+			for i := 0; i < tt.limit; i++ {
+				run, done, err := r.Start(Request{Command: "sh"})
+				if err != nil {
+					t.Fatalf("Start %d: %v", i, err)
+				}
+				stops = append(stops, func() {
+					if proc, err := process.NewProcess(int32(run.PID)); err == nil {
+						proc.Kill()
+					}
+					<-done
+				})
+			}
+			defer func() {
+				for _, stop := range stops {
+					stop()
+				}
+			}()
+
+			if _, _, err := r.Start(Request{Command: "sh"}); !errors.Is(err, ErrTooManyRuns) {
+				t.Fatalf("Start por encima del límite = %v, se esperaba ErrTooManyRuns", err)
+			}
+
+			// Al terminar una ejecución se libera su lugar
+			stops[0]()
+			stops = stops[1:]
+			run, done, err := r.Start(Request{Command: "sh"})
+			if err != nil {
+				t.Fatalf("Start tras liberar un lugar: %v", err)
+			}
+			stops = append(stops, func() {
+				if proc, err := process.NewProcess(int32(run.PID)); err == nil {
+					proc.Kill()
+				}
+				<-done
+			})
+		})
+	}
+}
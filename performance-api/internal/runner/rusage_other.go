@@ -0,0 +1,11 @@
+//go:build !unix
+
+package runner
+
+import "os"
+
+// maxRSS no está disponible fuera de sistemas Unix; el pico se obtiene
+// solo de las muestras tomadas durante la ejecución
+func maxRSS(state *os.ProcessState) uint64 {
+	return 0
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package runner
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSS retorna el pico de memoria residente en bytes reportado por wait4
+func maxRSS(state *os.ProcessState) uint64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || usage.Maxrss <= 0 {
+		return 0
+	}
+	// macOS reporta bytes; Linux y los BSD reportan kilobytes
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return uint64(usage.Maxrss)
+	}
+	return uint64(usage.Maxrss) * 1024
+}
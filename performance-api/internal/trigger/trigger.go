@@ -0,0 +1,196 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"performance-api/internal/metrics"
+	"performance-api/internal/profiler"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source es el valor de la etiqueta "source" de los perfiles capturados por
+// un disparador
+const Source = "trigger"
+
+// Nombres de las reglas, usados también como etiquetas de los perfiles
+const (
+	RuleCPUPercent = "cpu_percent"
+	RuleGoroutines = "goroutines"
+	RuleHeapGrowth = "heap_growth_percent"
+)
+
+// Options configura los umbrales que disparan la captura automática de
+// perfiles. Un umbral en cero desactiva su regla.
+type Options struct {
+	CPUPercent        float64       // Uso de CPU del sistema (%)
+	Goroutines        int           // Cantidad de goroutines de la API
+	HeapGrowthPercent float64       // Crecimiento del heap entre dos muestras (%)
+	Cooldown          time.Duration // Tiempo mínimo entre capturas de una misma regla
+	CPUDuration       time.Duration // Duración del perfil de CPU capturado
+	Types             []string      // Tipos de perfil capturados (cpu, heap, goroutine...)
+}
+
+// Trigger observa las muestras del recolector y captura perfiles cuando una
+// muestra supera un umbral
+type Trigger struct {
+	collector *metrics.Collector
+	profiler  *profiler.Profiler
+	opts      Options
+
+	mu        sync.Mutex
+	last      map[string]time.Time // Última captura por regla, para el cooldown
+	prevHeap  uint64               // Heap de la muestra anterior
+	capturing bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New crea un disparador de perfiles sobre el recolector y el perfilador
+func New(c *metrics.Collector, p *profiler.Profiler, opts Options) (*Trigger, error) {
+	if opts.CPUPercent < 0 || opts.Goroutines < 0 || opts.HeapGrowthPercent < 0 || opts.Cooldown < 0 {
+		return nil, fmt.Errorf("los umbrales y el cooldown de los disparadores no pueden ser negativos")
+	}
+	if opts.CPUPercent == 0 && opts.Goroutines == 0 && opts.HeapGrowthPercent == 0 {
+		return nil, fmt.Errorf("no hay umbrales configurados para los disparadores")
+	}
+	if len(opts.Types) == 0 {
+		return nil, fmt.Errorf("no hay tipos de perfil configurados para los disparadores")
+	}
+	for _, name := range opts.Types {
+		if !p.CanCapture(name) {
+			return nil, fmt.Errorf("tipo de perfil desconocido %q en los disparadores", name)
+		}
+		if name == "cpu" && (opts.CPUDuration < time.Second || opts.CPUDuration > profiler.MaxCPUProfileDuration) {
+			return nil, fmt.Errorf("la duración del perfil de CPU de los disparadores debe estar entre 1s y %v", profiler.MaxCPUProfileDuration)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Trigger{
+		collector: c,
+		profiler:  p,
+		opts:      opts,
+		last:      make(map[string]time.Time),
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Start evalúa cada muestra del recolector hasta que se llame a Stop
+func (t *Trigger) Start() {
+	samples, unsubscribe := t.collector.Subscribe(4)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case m, ok := <-samples:
+			if !ok {
+				return
+			}
+			t.evaluate(m)
+		}
+	}
+}
+
+// Stop detiene el disparador; un perfil de CPU en curso se descarta
+func (t *Trigger) Stop() {
+	t.cancel()
+}
+
+// evaluate compara la muestra con los umbrales y, si alguna regla fuera de
+// su cooldown se supera, inicia una captura etiquetada con la muestra
+func (t *Trigger) evaluate(m metrics.SystemMetrics) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fired := make(map[string]string)
+	if t.opts.CPUPercent > 0 && m.CPU.Percent >= t.opts.CPUPercent {
+		fired[RuleCPUPercent] = strconv.FormatFloat(m.CPU.Percent, 'f', 1, 64)
+	}
+	if t.opts.Goroutines > 0 && m.Goroutines >= t.opts.Goroutines {
+		fired[RuleGoroutines] = strconv.Itoa(m.Goroutines)
+	}
+	heap := m.Runtime.HeapBytes
+	if t.opts.HeapGrowthPercent > 0 && t.prevHeap > 0 && heap > t.prevHeap {
+		growth := float64(heap-t.prevHeap) / float64(t.prevHeap) * 100
+		if growth >= t.opts.HeapGrowthPercent {
+			fired[RuleHeapGrowth] = strconv.FormatFloat(growth, 'f', 1, 64)
+		}
+	}
+	t.prevHeap = heap
+
+	// Descartar las reglas en cooldown
+	rules := make([]string, 0, len(fired))
+	for _, rule := range []string{RuleCPUPercent, RuleGoroutines, RuleHeapGrowth} {
+		if _, ok := fired[rule]; !ok {
+			continue
+		}
+		if last, ok := t.last[rule]; ok && m.Timestamp.Sub(last) < t.opts.Cooldown {
+			delete(fired, rule)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return
+	}
+	if t.capturing {
+		log.Printf("Disparador %s omitido: ya hay una captura en curso", strings.Join(rules, ","))
+		return
+	}
+
+	labels := map[string]string{
+		"source":  Source,
+		"trigger": strings.Join(rules, ","),
+		"sample":  m.Timestamp.UTC().Format(time.RFC3339),
+	}
+	for rule, value := range fired {
+		labels[rule] = value
+		t.last[rule] = m.Timestamp
+	}
+	t.capturing = true
+	log.Printf("⚡ Disparador %s: capturando perfiles %s", labels["trigger"], strings.Join(t.opts.Types, ","))
+
+	go t.capture(labels)
+}
+
+// capture toma los perfiles configurados: primero las instantáneas, que
+// reflejan el estado en el momento del disparo, y luego el de CPU
+func (t *Trigger) capture(labels map[string]string) {
+	defer func() {
+		t.mu.Lock()
+		t.capturing = false
+		t.mu.Unlock()
+	}()
+
+	cpu := false
+	for _, name := range t.opts.Types {
+		if name == "cpu" {
+			cpu = true
+			continue
+		}
+		if _, err := t.profiler.Capture(t.ctx, name, 0, labels); err != nil {
+			log.Printf("Error en el perfil %s del disparador: %v", name, err)
+		}
+	}
+	if !cpu {
+		return
+	}
+
+	_, err := t.profiler.Capture(t.ctx, "cpu", t.opts.CPUDuration, labels)
+	var busy *profiler.CPUBusyError
+	switch {
+	case errors.As(err, &busy):
+		log.Printf("Perfil de CPU del disparador omitido: %v", err)
+	case err != nil && t.ctx.Err() == nil:
+		log.Printf("Error en el perfil de CPU del disparador: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package trigger
+
+import (
+	"performance-api/internal/metrics"
+	"performance-api/internal/profiler"
+	"testing"
+	"time"
+)
+
+// newTestTrigger crea un disparador que captura solo perfiles de heap
+func newTestTrigger(t *testing.T, opts Options) (*Trigger, *profiler.Profiler) {
+	t.Helper()
+	p := profiler.NewProfiler()
+	opts.Types = []string{"heap"}
+	tr, err := New(nil, p, opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(tr.Stop)
+	return tr, p
+}
+
+// evaluateAndWait evalúa la muestra y espera a que termine la captura que inicie
+func evaluateAndWait(t *testing.T, tr *Trigger, m metrics.SystemMetrics) {
+	t.Helper()
+	tr.evaluate(m)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tr.mu.Lock()
+		capturing := tr.capturing
+		tr.mu.Unlock()
+		if !capturing {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("tiempo agotado esperando la captura")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// captured retorna los perfiles del disparador, del más reciente al más antiguo
+func captured(p *profiler.Profiler) []profiler.ProfileInfo {
+	return p.Archive().List(profiler.ArchiveFilter{Labels: map[string]string{"source": Source}})
+}
+
+func TestEvaluateCooldown(t *testing.T) {
+	tr, p := newTestTrigger(t, Options{CPUPercent: 80, Cooldown: time.Minute})
+	base := time.Now()
+
+	steps := []struct {
+		offset time.Duration
+		cpu    float64
+		total  int // Capturas acumuladas tras la muestra
+	}{
+		{0, 50, 0},
+		{time.Second, 90, 1},
+		{30 * time.Second, 95, 1}, // En cooldown
+		{61 * time.Second, 85, 2}, // Cooldown vencido
+		{62 * time.Second, 99, 2},
+	}
+	for i, step := range steps {
+		evaluateAndWait(t, tr, metrics.SystemMetrics{Timestamp: base.Add(step.offset), CPU: metrics.CPUInfo{Percent: step.cpu}})
+		if got := len(captured(p)); got != step.total {
+			t.Fatalf("muestra %d: %d capturas, se esperaban %d", i, got, step.total)
+		}
+	}
+	if got := captured(p)[0].Labels[RuleCPUPercent]; got != "85.0" {
+		t.Errorf("etiqueta %s = %q, se esperaba 85.0", RuleCPUPercent, got)
+	}
+}
+
+func TestEvaluateHeapGrowth(t *testing.T) {
+	tr, p := newTestTrigger(t, Options{HeapGrowthPercent: 50})
+	base := time.Now()
+
+	steps := []struct {
+		heap   uint64
+		growth string // Vacío = la regla no se dispara
+	}{
+		{1000, ""},      // Sin muestra anterior
+		{1400, ""},      // 40% sobre 1000
+		{2100, "50.0"},  // 50% sobre 1400, no sobre la primera muestra
+		{1000, ""},      // Decrecimiento
+		{3000, "200.0"}, // Contra la muestra anterior, no contra el máximo
+	}
+	fired := 0
+	for i, step := range steps {
+		m := metrics.SystemMetrics{Timestamp: base.Add(time.Duration(i) * time.Second)}
+		m.Runtime.HeapBytes = step.heap
+		evaluateAndWait(t, tr, m)
+
+		list := captured(p)
+		if step.growth == "" {
+			if len(list) != fired {
+				t.Fatalf("muestra %d: %d capturas, se esperaban %d", i, len(list), fired)
+			}
+			continue
+		}
+		fired++
+		if len(list) != fired {
+			t.Fatalf("muestra %d: %d capturas, se esperaban %d", i, len(list), fired)
+		}
+		if got := list[0].Labels[RuleHeapGrowth]; got != step.growth {
+			t.Errorf("muestra %d: crecimiento %q, se esperaba %q", i, got, step.growth)
+		}
+	}
+}
+
+func TestEvaluateSkipsWhileCapturing(t *testing.T) {
+	tr, p := newTestTrigger(t, Options{CPUPercent: 80, Goroutines: 100, Cooldown: time.Minute})
+	base := time.Now()
+
+	evaluateAndWait(t, tr, metrics.SystemMetrics{Timestamp: base, CPU: metrics.CPUInfo{Percent: 95}})
+
+	// Con una captura en curso, la regla de goroutines se omite sin iniciar su cooldown
+	tr.mu.Lock()
+	tr.capturing = true
+	tr.mu.Unlock()
+	tr.evaluate(metrics.SystemMetrics{Timestamp: base.Add(time.Second), CPU: metrics.CPUInfo{Percent: 95}, Goroutines: 150})
+	tr.mu.Lock()
+	_, started := tr.last[RuleGoroutines]
+	tr.capturing = false
+	tr.mu.Unlock()
+	if started {
+		t.Error("la regla omitida inició su cooldown")
+	}
+	if got := len(captured(p)); got != 1 {
+		t.Fatalf("%d capturas con otra en curso, se esperaba 1", got)
+	}
+
+	// Terminada la captura, la regla se dispara; la de CPU sigue en cooldown
+	sample := base.Add(2 * time.Second)
+	evaluateAndWait(t, tr, metrics.SystemMetrics{Timestamp: sample, CPU: metrics.CPUInfo{Percent: 97}, Goroutines: 150})
+	list := captured(p)
+	if len(list) != 2 {
+		t.Fatalf("%d capturas, se esperaban 2", len(list))
+	}
+	want := map[string]string{
+		"source":       Source,
+		"trigger":      RuleGoroutines,
+		"sample":       sample.UTC().Format(time.RFC3339),
+		RuleGoroutines: "150",
+	}
+	for name, value := range want {
+		if got := list[0].Labels[name]; got != value {
+			t.Errorf("etiqueta %s = %q, se esperaba %q", name, got, value)
+		}
+	}
+	if _, ok := list[0].Labels[RuleCPUPercent]; ok {
+		t.Errorf("la regla en cooldown quedó en las etiquetas: %v", list[0].Labels)
+	}
+	if got := list[1].Labels; got["trigger"] != RuleCPUPercent || got[RuleCPUPercent] != "95.0" {
+		t.Errorf("etiquetas de la primera captura = %v, se esperaba %s con 95.0", got, RuleCPUPercent)
+	}
+}
@@ -1,37 +1,257 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"performance-api/internal/alerts"
 	"performance-api/internal/api"
+	"performance-api/internal/config"
 	"performance-api/internal/metrics"
 	"performance-api/internal/profiler"
-	"time"
+	"performance-api/internal/runner"
+	"performance-api/internal/trigger"
+	"syscall"
 )
 
 func main() {
+	configPath := flag.String("config", os.Getenv("PERFORMANCE_API_CONFIG"), "Ruta del archivo de configuración JSON")
+	flag.Parse()
+
+	// Cargar la configuración
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Error al cargar la configuración: %v", err)
+	}
+
+	// Inicializar el almacenamiento del historial de métricas
+	store, err := newMetricsStore(cfg.Storage)
+	if err != nil {
+		log.Fatalf("Error al abrir el historial de métricas: %v", err)
+	}
+
 	// Inicializar el recolector de métricas
-	collector := metrics.NewCollector()
+	collector := metrics.NewCollectorWithStore(store)
+
+	// Inicializar el supervisor de procesos externos
+	watcher := metrics.NewProcessWatcher(cfg.Processes.MaxHistory)
+	
+	// Inicializar el ejecutor de comandos permitidos
+	runs, err := newRunner(cfg.Runs)
+	if err != nil {
+		log.Fatalf("Error en los comandos permitidos: %v", err)
+	}
+
+	// Inicializar el archivo de perfiles y el perfilador
+	archive, err := profiler.OpenArchive(profiler.ArchiveOptions{
+		Dir:       cfg.Profiles.Dir,
+		MaxBytes:  cfg.Profiles.MaxBytes,
+		Retention: cfg.Profiles.Retention.Duration,
+	})
+	if err != nil {
+		log.Fatalf("Error al abrir el archivo de perfiles: %v", err)
+	}
+	rates := profiler.RateUpdate{
+		BlockProfileRate:     &cfg.Profiles.BlockProfileRate,
+		MutexProfileFraction: &cfg.Profiles.MutexProfileFraction,
+		MemProfileRate:       &cfg.Profiles.MemProfileRate,
+	}
+	profiler := profiler.NewProfilerWithArchive(archive)
+	if _, err := profiler.SetRates(rates); err != nil {
+		log.Fatalf("Error en las tasas de perfilamiento: %v", err)
+	}
+	if err := registerTargets(profiler, cfg.Profiles); err != nil {
+		log.Fatalf("Error en los servicios remotos: %v", err)
+	}
 	
-	// Inicializar el perfilador
-	profiler := profiler.NewProfiler()
+	// Inicializar el motor de alertas
+	alertEngine, err := newAlertEngine(collector, cfg.Alerts)
+	if err != nil {
+		log.Fatalf("Error en las reglas de alerta: %v", err)
+	}
 	
 	// Configurar el router de la API
-	router := api.NewRouter(collector, profiler)
+	router := api.NewRouter(collector, profiler, watcher, runs, alertEngine)
 	
 	// Iniciar recolección de métricas en segundo plano
-	go collector.StartCollection(15 * time.Second) // Recolecta cada 5 segundos
+	go collector.StartCollection(cfg.CollectionInterval.Duration)
+	go watcher.StartCollection(cfg.CollectionInterval.Duration)
+	go alertEngine.Start()
+
+	// Iniciar el perfilamiento continuo si está habilitado
+	scheduler, err := newScheduler(profiler, cfg.Profiles.Continuous)
+	if err != nil {
+		log.Fatalf("Error en el perfilamiento continuo: %v", err)
+	}
+	if scheduler != nil {
+		go scheduler.Start()
+		log.Printf("🔁 Perfilamiento continuo cada %v", cfg.Profiles.Continuous.Interval.Duration)
+	}
+
+	// Iniciar la captura de perfiles por umbrales si está habilitada
+	triggers, err := newTrigger(collector, profiler, cfg.Profiles.Triggers)
+	if err != nil {
+		log.Fatalf("Error en los disparadores de perfiles: %v", err)
+	}
+	if triggers != nil {
+		go triggers.Start()
+		log.Printf("⚡ Captura de perfiles por umbrales activada")
+	}
 	
 	// Endpoints de la API
-	port := ":8080"
+	port := cfg.Port
 	log.Printf("🚀 API de Análisis de Rendimiento iniciada en http://localhost%s", port)
 	log.Printf("📊 Métricas disponibles en http://localhost%s/api/metrics", port)
 	log.Printf("🔍 Perfilamiento disponible en http://localhost%s/debug/pprof/", port)
 	
+	// Detener la recolección y cerrar el historial al recibir una señal de término
+	server := &http.Server{Addr: port, Handler: router}
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+		log.Printf("🛑 Deteniendo la API...")
+		alertEngine.Stop()
+		collector.Stop()
+		watcher.Stop()
+		if scheduler != nil {
+			scheduler.Stop()
+		}
+		if triggers != nil {
+			triggers.Stop()
+		}
+		profiler.Targets().Stop()
+		server.Close()
+	}()
+	
 	// Iniciar servidor HTTP
-	if err := http.ListenAndServe(port, router); err != nil {
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Error al iniciar el servidor: %v", err)
 	}
 }
 
+// newMetricsStore crea el backend de historial indicado en la configuración
+func newMetricsStore(cfg config.StorageConfig) (metrics.Store, error) {
+	switch cfg.Backend {
+	case "memory":
+		return metrics.NewMemoryStore(cfg.MaxHistory), nil
+	default:
+		log.Printf("💾 Historial de métricas persistido en %s", cfg.Dir)
+		return metrics.OpenSegmentStore(metrics.SegmentStoreOptions{
+			Dir:             cfg.Dir,
+			MaxSegmentBytes: cfg.MaxSegmentBytes,
+			SegmentDuration: cfg.SegmentDuration.Duration,
+			Retention:       cfg.Retention.Duration,
+		})
+	}
+}
+
+// newRunner crea el ejecutor con la lista de comandos permitidos de la configuración
+func newRunner(cfg config.RunsConfig) (*runner.Runner, error) {
+	commands := make(map[string]runner.Command, len(cfg.Commands))
+	for name, cmd := range cfg.Commands {
+		commands[name] = runner.Command{
+			Path:        cmd.Path,
+			Args:        cmd.Args,
+			Dir:         cmd.Dir,
+			ArgPatterns: cmd.ArgPatterns,
+			MaxArgs:     cmd.MaxArgs,
+		}
+	}
+	return runner.NewRunner(runner.Options{
+		Commands:       commands,
+		SampleInterval: cfg.SampleInterval.Duration,
+		MaxDuration:    cfg.MaxDuration.Duration,
+		MaxRuns:        cfg.MaxRuns,
+		MaxConcurrent:  cfg.MaxConcurrent,
+	})
+}
+
+// newScheduler crea el planificador de perfilamiento continuo, o nil si está desactivado
+func newScheduler(p *profiler.Profiler, cfg config.ContinuousConfig) (*profiler.Scheduler, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return profiler.NewScheduler(p, profiler.ContinuousOptions{
+		Interval:    cfg.Interval.Duration,
+		CPUDuration: cfg.CPUDuration.Duration,
+		Types:       cfg.Types,
+		Retention:   cfg.Retention.Duration,
+	})
+}
+
+// newTrigger crea los disparadores de perfiles por umbrales, o nil si están desactivados
+func newTrigger(c *metrics.Collector, p *profiler.Profiler, cfg config.TriggersConfig) (*trigger.Trigger, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return trigger.New(c, p, trigger.Options{
+		CPUPercent:        cfg.CPUPercent,
+		Goroutines:        cfg.Goroutines,
+		HeapGrowthPercent: cfg.HeapGrowthPercent,
+		Cooldown:          cfg.Cooldown.Duration,
+		CPUDuration:       cfg.CPUDuration.Duration,
+		Types:             cfg.Types,
+	})
+}
+
+// registerTargets registra los servicios remotos de la configuración y los
+// hosts a los que la API puede registrar otros
+func registerTargets(p *profiler.Profiler, cfg config.ProfilesConfig) error {
+	p.Targets().AllowHosts(cfg.TargetHosts)
+	for _, t := range cfg.Targets {
+		info, err := p.Targets().Add(profiler.TargetOptions{
+			Name:        t.Name,
+			URL:         t.URL,
+			Types:       t.Types,
+			Interval:    t.Interval.Duration,
+			CPUDuration: t.CPUDuration.Duration,
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("🎯 Servicio remoto %s en %s", info.Name, info.URL)
+	}
+	return nil
+}
+
+// newAlertEngine crea el motor de alertas con las reglas de la configuración
+func newAlertEngine(c *metrics.Collector, cfg config.AlertsConfig) (*alerts.Engine, error) {
+	rules := make([]alerts.RuleConfig, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		rules[i] = alerts.RuleConfig{
+			Name:        rule.Name,
+			Expr:        rule.Expr,
+			Severity:    rule.Severity,
+			Description: rule.Description,
+		}
+	}
+	notify := cfg.Notifications
+	sinks := make([]alerts.SinkConfig, len(notify.Sinks))
+	for i, sink := range notify.Sinks {
+		sinks[i] = alerts.SinkConfig{
+			Name:    sink.Name,
+			Type:    sink.Type,
+			URL:     sink.URL,
+			Path:    sink.Path,
+			Headers: sink.Headers,
+		}
+	}
+	return alerts.NewEngine(c, alerts.Options{
+		Rules:       rules,
+		HistorySize: cfg.HistorySize,
+		Notify: alerts.NotifyOptions{
+			Sinks:          sinks,
+			GroupWait:      notify.GroupWait.Duration,
+			GroupBy:        notify.GroupBy,
+			MaxRetries:     notify.MaxRetries,
+			InitialBackoff: notify.InitialBackoff.Duration,
+			MaxBackoff:     notify.MaxBackoff.Duration,
+			Timeout:        notify.Timeout.Duration,
+			ResendInterval: notify.ResendInterval.Duration,
+		},
+	})
+}
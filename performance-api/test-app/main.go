@@ -4,8 +4,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log"
 	"math/rand"
+	"net/http"
+	_ "net/http/pprof"
 	"runtime"
 	"time"
 )
@@ -149,6 +153,19 @@ func main() {
 	fmt.Println("Autores: Daniel Agudelo, Paulina Garcia")
 	fmt.Println("")
 
+	// Exponer net/http/pprof para que la API obtenga perfiles remotos
+	pprofAddr := flag.String("pprof", "localhost:6060", "Dirección de net/http/pprof (vacío = desactivado)")
+	flag.Parse()
+	if *pprofAddr != "" {
+		go func() {
+			if err := http.ListenAndServe(*pprofAddr, nil); err != nil {
+				log.Printf("Error en el servidor de pprof: %v", err)
+			}
+		}()
+		fmt.Printf("🔍 Perfiles disponibles en http://%s/debug/pprof/\n", *pprofAddr)
+		fmt.Println("")
+	}
+
 	// Configurar número de CPUs a usar
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	fmt.Printf("💻 CPUs disponibles: %d\n", runtime.NumCPU())